@@ -3,10 +3,15 @@ package main
 import (
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
+	"net/http"
 	"os"
+	"time"
 
 	arg "github.com/alexflint/go-arg"
+	"github.com/che-incubator/configbump/pkg/bumper"
 	"github.com/che-incubator/configbump/pkg/configmaps"
+	"github.com/che-incubator/configbump/pkg/health"
+	"github.com/che-incubator/configbump/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/ready"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -25,12 +30,18 @@ type opts struct {
 	Labels    string `arg:"-l,required,env:CONFIG_BUMP_LABELS" help:"An expression to match the labels against. Consult the Kubernetes documentation for the syntax required. Can also be specified using env var: CONFIG_BUMP_LABELS"`
 	Namespace string `arg:"-n,env:CONFIG_BUMP_NAMESPACE" help:"The namespace in which to look for the config maps to persist. Can also be specified using env var: CONFIG_BUMP_NAMESPACE. If not specified, it is autodetected."`
 
-	// TODO the whole process bumping not implemented yet.
-	//ProcessCommand       string `arg:"--process-command,-c,env:CONFIG_BUMP_PROCESS_COMMAND" help:"The commandline by which to identify the process to send the signal to. This can be a regular expression. Ignored if process pid is specified. Can also be specified using env var: CONFIG_BUMP_PROCESS_COMMAND"`
-	//ProcessPid           int32  `arg:"--process-pid,-p,env:CONFIG_BUMP_PROCESS_PID" help:"The PID of the process to send the signal to, if known. Otherwise process detection can be used. Can also be specified using env var: CONFIG_BUMP_PROCESS_PID"`
-	//ProcessParentCommand string `arg:"--process-parent-command,-a,env:CONFIG_BUMP_PARENT_PROCESS_COMMAND" help:"The commandline by which to identify the parent process of the process to send signal to. This can be a regular expression. Ignored if parent process pid is specified. Can also be specified using env var: CONFIG_BUMP_PARENT_PROCESS_COMMAND"`
-	//ProcessParentPid     int32  `arg:"--process-parent-pid,-i,env:CONFIG_BUMP_PARENT_PROCESS_PID" help:"The PID of the parent process of the process to send the signal to, if known. Otherwise process detection can be used. Can also be specified using env var: CONFIG_BUMP_PARENT_PROCESS_PID"`
-	//Signal               string `arg:"-s,env:CONFIG_BUMP_SIGNAL" help:"The name of the signal to send to the process on the configuration files change. Use 'kill -l' to get a list of possible signals. Can also be specified using env var: CONFIG_BUMP_SIGNAL"`
+	Secrets bool `arg:"--secrets,env:CONFIG_BUMP_SECRETS" help:"Whether to also persist the secrets matching the labels. Requires the permissions to watch and list the secrets. Can also be specified using env var: CONFIG_BUMP_SECRETS"`
+
+	DownwardAPIDir string `arg:"--downward-api-dir,env:CONFIG_BUMP_DOWNWARD_API_DIR" help:"The directory with the downward API volume of the pod. Its values are available to the templates as .Pod. Can also be specified using env var: CONFIG_BUMP_DOWNWARD_API_DIR"`
+
+	ProcessCommand       string `arg:"--process-command,-c,env:CONFIG_BUMP_PROCESS_COMMAND" help:"The commandline by which to identify the process to send the signal to. This can be a regular expression. Ignored if process pid is specified. Can also be specified using env var: CONFIG_BUMP_PROCESS_COMMAND"`
+	ProcessPid           int32  `arg:"--process-pid,-p,env:CONFIG_BUMP_PROCESS_PID" help:"The PID of the process to send the signal to, if known. Otherwise process detection can be used. Can also be specified using env var: CONFIG_BUMP_PROCESS_PID"`
+	ProcessParentCommand string `arg:"--process-parent-command,-a,env:CONFIG_BUMP_PARENT_PROCESS_COMMAND" help:"The commandline by which to identify the parent process of the process to send signal to. This can be a regular expression. Ignored if parent process pid is specified. Can also be specified using env var: CONFIG_BUMP_PARENT_PROCESS_COMMAND"`
+	ProcessParentPid     int32  `arg:"--process-parent-pid,-i,env:CONFIG_BUMP_PARENT_PROCESS_PID" help:"The PID of the parent process of the process to send the signal to, if known. Otherwise process detection can be used. Can also be specified using env var: CONFIG_BUMP_PARENT_PROCESS_PID"`
+	Signal               string `arg:"-s,env:CONFIG_BUMP_SIGNAL" default:"SIGHUP" help:"The name of the signal to send to the process on the configuration files change. Use 'kill -l' to get a list of possible signals. Can also be specified using env var: CONFIG_BUMP_SIGNAL"`
+
+	ListenAddress        string        `arg:"--listen-address,env:CONFIG_BUMP_LISTEN_ADDRESS" help:"The address to serve the health endpoints /healthz, /readyz, /status and the Prometheus metrics on /metrics, e.g. :8081. Not served if not specified. Can also be specified using env var: CONFIG_BUMP_LISTEN_ADDRESS"`
+	SyncFailureThreshold time.Duration `arg:"--sync-failure-threshold,env:CONFIG_BUMP_SYNC_FAILURE_THRESHOLD" default:"5m" help:"The duration for which the syncs may keep failing before the liveness endpoint starts failing. Use 0 to never fail the liveness because of the syncs. Can also be specified using env var: CONFIG_BUMP_SYNC_FAILURE_THRESHOLD"`
 }
 
 // Version returns the version of the program
@@ -52,22 +63,72 @@ func main() {
 	var opts opts
 	arg.MustParse(&opts)
 
-	// process signalling not implemented yet.
-	// d, err := bumper.DetectCommand(opts.ProcessCommand)
-	// if err != nil {
-	// }
-	// var ds []bumper.Detection = []bumper.Detection{d}
-	// b := bumper.New(opts.Signal, ds)
+	onReconcileDone, err := initializeBumper(opts)
+	if err != nil {
+		log.Error(err, "Could not initialize the process signalling")
+		os.Exit(1)
+	}
+
+	status := health.New(opts.SyncFailureThreshold)
+	if opts.ListenAddress != "" {
+		go serveStatus(opts.ListenAddress, status)
+	}
 
-	// once process signalling is implemented, we can call:
-	// initializeConfigMapController(opts.Labels, opts.Dir, b.Bump)
-	if err := initializeConfigMapController(opts.Labels, opts.Dir, opts.Namespace, func() error { return nil }); err != nil {
+	if err := initializeConfigMapController(opts.Labels, opts.Dir, opts.Namespace, opts.Secrets, opts.DownwardAPIDir, onReconcileDone, status); err != nil {
 		log.Error(err, "Could not initialize the config map sync controller")
 		os.Exit(1)
 	}
 }
 
-func initializeConfigMapController(labels string, baseDir string, namespace string, onReconcileDone func() error) error {
+// serveStatus serves the health endpoints and the metrics on the address
+func serveStatus(address string, status *health.Status) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", status.Handler())
+
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Error(err, "Could not serve the health endpoints", "address", address)
+		os.Exit(1)
+	}
+}
+
+// initializeBumper returns the function to signal the configured process once the files are changed. If neither
+// the command nor the PID of the process are configured, nil is returned.
+func initializeBumper(opts opts) (func() error, error) {
+	process, err := detection(opts.ProcessCommand, opts.ProcessPid)
+	if err != nil || process == nil {
+		return nil, err
+	}
+	ds := []bumper.Detection{process}
+
+	parent, err := detection(opts.ProcessParentCommand, opts.ProcessParentPid)
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil {
+		ds = append(ds, parent)
+	}
+
+	b, err := bumper.New(opts.Signal, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bump, nil
+}
+
+// detection returns the detection of the process by PID if it is specified, otherwise by the commandline.
+func detection(command string, pid int32) (bumper.Detection, error) {
+	if pid != 0 {
+		return bumper.DetectPid(pid), nil
+	}
+	if command != "" {
+		return bumper.DetectCommand(command)
+	}
+	return nil, nil
+}
+
+func initializeConfigMapController(labels string, baseDir string, namespace string, secrets bool, downwardAPIDir string, onReconcileDone func() error, status *health.Status) error {
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -101,6 +162,9 @@ func initializeConfigMapController(labels string, baseDir string, namespace stri
 		Labels:          labels,
 		OnReconcileDone: onReconcileDone,
 		Namespace:       namespace,
+		DownwardAPIDir:  downwardAPIDir,
+		Secrets:         secrets,
+		Status:          status,
 	})
 
 	if err != nil {
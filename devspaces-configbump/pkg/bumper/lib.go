@@ -1,6 +1,26 @@
 package bumper
 
-import "regexp"
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/che-incubator/configbump/pkg/metrics"
+	"golang.org/x/sys/unix"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("bumper")
+
+// ErrProcessNotFound is returned by Bump if no process matches the criteria of the Bumper
+var ErrProcessNotFound = errors.New("no process matching the criteria found")
 
 // Detection abstracts a process detection. Use the DetectPid or DetectCommand factory
 // methods to create individual instances.
@@ -29,6 +49,10 @@ func DetectPid(pid int32) Detection {
 type process struct {
 	Commandline string
 	Pid         int32
+	ParentPid   int32
+	// StartTime is the time the process started after the system boot, in clock ticks. Together with the PID,
+	// it identifies the process, because PIDs are reused.
+	StartTime uint64
 }
 
 type regexDetection struct {
@@ -51,16 +75,51 @@ func (d *pidDetection) matches(p *process) bool {
 type Bumper struct {
 	processHierarchy []Detection
 	currentProcess   *process
-	signal           string
+	signal           syscall.Signal
+
+	// procDir is the mount point of the proc filesystem
+	procDir string
+	// kill sends the signal to the process with the PID
+	kill func(pid int, signal syscall.Signal) error
 }
 
 // New constructs a new Bumper instance. The provided detections represent the desired process hierarchy.
 // The first element is the process to detect, the second element is its parent process, etc.
-func New(signal string, ds []Detection) Bumper {
-	return Bumper{processHierarchy: ds, signal: signal}
+// The signal is either the name of the signal, with or without the SIG prefix, or its number.
+func New(signal string, ds []Detection) (*Bumper, error) {
+	if len(ds) == 0 {
+		return nil, fmt.Errorf("at least one process detection must be specified")
+	}
+
+	sig, err := parseSignal(signal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bumper{processHierarchy: ds, signal: sig, procDir: "/proc", kill: unix.Kill}, nil
+}
+
+func parseSignal(signal string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(signal); err == nil {
+		if unix.SignalName(syscall.Signal(num)) == "" {
+			return 0, fmt.Errorf("unknown signal number %d", num)
+		}
+		return syscall.Signal(num), nil
+	}
+
+	name := strings.ToUpper(signal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("unknown signal '%s'", signal)
+	}
+	return sig, nil
 }
 
 // Bump tries to find the process matching the criteria of the Bumper and will send a configured signal to it.
+// ErrProcessNotFound is returned if there is no such process.
 func (b *Bumper) Bump() error {
 	process, err := b.detectProcess()
 	if err != nil {
@@ -68,28 +127,149 @@ func (b *Bumper) Bump() error {
 	}
 
 	if process == nil {
-		return nil
+		return ErrProcessNotFound
 	}
 
-	for _, d := range b.processHierarchy {
-		if d.matches(process) {
-			// TODO signal the process using "kill -signal PID"
-		}
+	log.Info("Sending the signal to the process", "pid", process.Pid, "signal", unix.SignalName(b.signal), "commandline", process.Commandline)
+	if err := b.kill(int(process.Pid), b.signal); err != nil {
+		metrics.Signals.WithLabelValues(metrics.FailureResult).Inc()
+		// the process might have just exited, let's look for it again next time
+		b.currentProcess = nil
+		return fmt.Errorf("failed to send the signal to the process %d: %s", process.Pid, err)
 	}
 
+	metrics.Signals.WithLabelValues(metrics.SuccessResult).Inc()
 	return nil
 }
 
 func (b *Bumper) detectProcess() (*process, error) {
+	if b.currentProcess != nil && !b.checkProcessExists() {
+		log.Info("The process is gone, looking for it again", "pid", b.currentProcess.Pid)
+		b.currentProcess = nil
+	}
+
 	if b.currentProcess == nil {
-		// TODO implement
-	} else {
-		b.checkProcessExists()
+		processes, err := b.listProcesses()
+		if err != nil {
+			return nil, err
+		}
+
+		b.currentProcess = b.findProcess(processes)
 	}
 
 	return b.currentProcess, nil
 }
 
-func (b *Bumper) checkProcessExists() {
-	// TODO check the process still exists and corresponds to the currentProcess struct
+// checkProcessExists checks the process still exists and corresponds to the currentProcess struct. The process
+// is considered gone also if its parent changed, because the process hierarchy might not match anymore.
+func (b *Bumper) checkProcessExists() bool {
+	p, err := b.readProcess(b.currentProcess.Pid)
+	if err != nil {
+		return false
+	}
+
+	return p.StartTime == b.currentProcess.StartTime && p.ParentPid == b.currentProcess.ParentPid
+}
+
+// findProcess returns the process with the lowest PID which, together with its ancestors, matches the process
+// hierarchy. The own process is never matched, even though its commandline contains the detection criteria.
+func (b *Bumper) findProcess(processes map[int32]*process) *process {
+	pids := make([]int, 0, len(processes))
+	for pid := range processes {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+
+	self := int32(os.Getpid())
+
+	for _, pid := range pids {
+		p := processes[int32(pid)]
+		if p.Pid == self {
+			continue
+		}
+
+		current := p
+		matches := true
+		for _, d := range b.processHierarchy {
+			if current == nil || !d.matches(current) {
+				matches = false
+				break
+			}
+			current = processes[current.ParentPid]
+		}
+
+		if matches {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// listProcesses reads all the processes visible in the proc filesystem. Kernel threads, which have no commandline,
+// and the processes which exit while being read are skipped.
+func (b *Bumper) listProcesses() (map[int32]*process, error) {
+	entries, err := ioutil.ReadDir(b.procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make(map[int32]*process, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil || !e.IsDir() {
+			continue
+		}
+
+		p, err := b.readProcess(int32(pid))
+		if err != nil || p.Commandline == "" {
+			continue
+		}
+
+		processes[p.Pid] = p
+	}
+
+	return processes, nil
+}
+
+func (b *Bumper) readProcess(pid int32) (*process, error) {
+	dir := filepath.Join(b.procDir, strconv.Itoa(int(pid)))
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// the command name in the second field is in parentheses and can contain spaces and parentheses itself
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected format of %s", filepath.Join(dir, "stat"))
+	}
+	// fields starting with the 3rd one, the state of the process
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("unexpected format of %s", filepath.Join(dir, "stat"))
+	}
+
+	ppid, err := strconv.ParseInt(fields[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &process{
+		Commandline: strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")),
+		Pid:         pid,
+		ParentPid:   int32(ppid),
+		StartTime:   startTime,
+	}, nil
 }
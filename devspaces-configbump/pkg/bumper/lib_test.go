@@ -0,0 +1,140 @@
+package bumper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+type signalled struct {
+	pid    int
+	signal syscall.Signal
+}
+
+// fakeProc creates the proc filesystem entry of the process in the given dir
+func fakeProc(t *testing.T, dir string, pid int32, ppid int32, startTime uint64, args ...string) {
+	pidDir := filepath.Join(dir, strconv.Itoa(int(pid)))
+	if err := os.MkdirAll(pidDir, 0755); err != nil {
+		t.Fatalf("Failed to create the process dir. %s", err)
+	}
+
+	cmdline := strings.Join(args, "\x00") + "\x00"
+	if err := ioutil.WriteFile(filepath.Join(pidDir, "cmdline"), []byte(cmdline), 0644); err != nil {
+		t.Fatalf("Failed to write the process commandline. %s", err)
+	}
+
+	// the command name contains a space and a parenthesis to check the parsing
+	stat := fmt.Sprintf("%d (my (cmd) S %d 1 1 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 %d 1000 10 18446744073709551615",
+		pid, ppid, startTime)
+	if err := ioutil.WriteFile(filepath.Join(pidDir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatalf("Failed to write the process stat. %s", err)
+	}
+}
+
+func testBumper(t *testing.T, signal string, ds ...Detection) (*Bumper, string, *[]signalled) {
+	dir, err := ioutil.TempDir("", "config-bump-proc")
+	if err != nil {
+		t.Fatalf("Failed to create the proc dir. %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	b, err := New(signal, ds)
+	if err != nil {
+		t.Fatalf("Failed to create the bumper. %s", err)
+	}
+
+	signals := make([]signalled, 0)
+	b.procDir = dir
+	b.kill = func(pid int, signal syscall.Signal) error {
+		signals = append(signals, signalled{pid: pid, signal: signal})
+		return nil
+	}
+
+	return b, dir, &signals
+}
+
+func mustDetectCommand(t *testing.T, command string) Detection {
+	d, err := DetectCommand(command)
+	if err != nil {
+		t.Fatalf("Failed to create the detection. %s", err)
+	}
+	return d
+}
+
+func TestSignalsProcessMatchingHierarchy(t *testing.T) {
+	b, dir, signals := testBumper(t, "HUP", mustDetectCommand(t, "^nginx: worker"), mustDetectCommand(t, "^nginx: master"))
+	fakeProc(t, dir, 1, 0, 10, "/bin/sh", "-c", "nginx")
+	fakeProc(t, dir, 5, 1, 20, "nginx: worker process")
+	fakeProc(t, dir, 10, 1, 30, "nginx: master process")
+	fakeProc(t, dir, 11, 10, 40, "nginx: worker process")
+
+	if err := b.Bump(); err != nil {
+		t.Fatalf("Failed to bump. %s", err)
+	}
+
+	expected := []signalled{{pid: 11, signal: syscall.SIGHUP}}
+	if fmt.Sprint(*signals) != fmt.Sprint(expected) {
+		t.Fatalf("Expected signals %v but were %v.", expected, *signals)
+	}
+}
+
+func TestSignalsProcessByPid(t *testing.T) {
+	b, dir, signals := testBumper(t, "10", DetectPid(5))
+	fakeProc(t, dir, 1, 0, 10, "/bin/sh")
+	fakeProc(t, dir, 5, 1, 20, "traefik")
+
+	if err := b.Bump(); err != nil {
+		t.Fatalf("Failed to bump. %s", err)
+	}
+
+	expected := []signalled{{pid: 5, signal: syscall.SIGUSR1}}
+	if fmt.Sprint(*signals) != fmt.Sprint(expected) {
+		t.Fatalf("Expected signals %v but were %v.", expected, *signals)
+	}
+}
+
+func TestRedetectsRestartedProcess(t *testing.T) {
+	b, dir, signals := testBumper(t, "SIGHUP", mustDetectCommand(t, "traefik"))
+	fakeProc(t, dir, 5, 1, 20, "traefik", "--configfile", "/etc/traefik.yml")
+
+	if err := b.Bump(); err != nil {
+		t.Fatalf("Failed to bump. %s", err)
+	}
+
+	// the process is restarted with a different PID and its PID is reused by an unrelated process
+	fakeProc(t, dir, 5, 1, 50, "sleep", "1000")
+	fakeProc(t, dir, 7, 1, 60, "traefik", "--configfile", "/etc/traefik.yml")
+
+	if err := b.Bump(); err != nil {
+		t.Fatalf("Failed to bump. %s", err)
+	}
+
+	expected := []signalled{{pid: 5, signal: syscall.SIGHUP}, {pid: 7, signal: syscall.SIGHUP}}
+	if fmt.Sprint(*signals) != fmt.Sprint(expected) {
+		t.Fatalf("Expected signals %v but were %v.", expected, *signals)
+	}
+}
+
+func TestDoesNotSignalWhenNoProcessMatches(t *testing.T) {
+	b, dir, signals := testBumper(t, "HUP", mustDetectCommand(t, "haproxy"))
+	fakeProc(t, dir, 5, 1, 20, "traefik")
+
+	if err := b.Bump(); err != ErrProcessNotFound {
+		t.Fatalf("The missing process should have been reported but was %v.", err)
+	}
+
+	if len(*signals) != 0 {
+		t.Fatalf("Expected no signals but were %v.", *signals)
+	}
+}
+
+func TestRejectsUnknownSignal(t *testing.T) {
+	if _, err := New("SIGWHATEVER", []Detection{DetectPid(1)}); err == nil {
+		t.Fatal("Expected the unknown signal to be rejected.")
+	}
+}
@@ -0,0 +1,329 @@
+package configmaps
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// dataDirName is the name of the symlink pointing to the directory with the current generation of the files
+	dataDirName = "..data"
+	// newDataDirName is the name of the symlink which atomically replaces the data dir symlink
+	newDataDirName = "..data_tmp"
+)
+
+// file is the content of the config file together with its attributes
+type file struct {
+	data []byte
+	mode os.FileMode
+	// uid and gid of the owner of the file, -1 keeps the owner configbump runs as
+	uid int
+	gid int
+	// template is true if the data is a Go template to be rendered before the file is written
+	template bool
+	// source is the config map or secret the file comes from
+	source object
+}
+
+// payload is a map where keys are the paths of the files relative to the base dir and values are the files
+type payload = map[string]*file
+
+// changes are the numbers of the files changed by the sync
+type changes struct {
+	// written is the number of the new files and the files with changed contents, modes or owners
+	written int
+	// deleted is the number of the files which are not in the payload anymore
+	deleted int
+}
+
+func (c changes) any() bool {
+	return c.written > 0 || c.deleted > 0
+}
+
+// writePayload materializes the files in the base dir the same way kubelet projects config map volumes. Each
+// generation of the files is written to a fresh timestamped directory and the ..data symlink is atomically flipped
+// to it, so that a watching process never sees partially written files. The files and the top-level directories
+// in the base dir are symlinks into ..data. Stale generations and files that are not in the payload are removed.
+// If any of the files fails to be written, the current generation is kept intact and the error is returned.
+// Returns the changes of the files.
+func writePayload(baseDir string, files payload) (changes, error) {
+	for path := range files {
+		if err := validatePath(path); err != nil {
+			return changes{}, err
+		}
+	}
+
+	diff, err := diffPayload(baseDir, files)
+	if err != nil {
+		return changes{}, err
+	}
+
+	if diff.any() {
+		if err := swapDataDir(baseDir, files); err != nil {
+			return changes{}, err
+		}
+	}
+
+	if err := linkFiles(baseDir, files); err != nil {
+		return diff, err
+	}
+
+	removed, err := removeStaleFiles(baseDir, files)
+	diff.deleted += removed
+	if err != nil {
+		return diff, err
+	}
+
+	return diff, removeStaleGenerations(baseDir)
+}
+
+func validatePath(path string) error {
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("file path '%s' must be relative", path)
+	}
+	for _, name := range strings.Split(path, string(os.PathSeparator)) {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("invalid file path '%s'", path)
+		}
+		if strings.HasPrefix(name, "..") {
+			return fmt.Errorf("file path '%s' must not contain names starting with '..', they are reserved for the generations of the files", path)
+		}
+	}
+	return nil
+}
+
+// topLevelName returns the name of the file or directory in the base dir which contains the file with the path
+func topLevelName(path string) string {
+	return strings.SplitN(path, string(os.PathSeparator), 2)[0]
+}
+
+// diffPayload compares the payload with the current generation of the files. All the files are new if the current
+// generation doesn't exist.
+func diffPayload(baseDir string, files payload) (changes, error) {
+	genName, err := os.Readlink(filepath.Join(baseDir, dataDirName))
+	if os.IsNotExist(err) {
+		return changes{written: len(files)}, nil
+	}
+	if err != nil {
+		return changes{}, err
+	}
+
+	genDir := filepath.Join(baseDir, genName)
+	diff := changes{}
+	unchanged := 0
+	err = filepath.Walk(genDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(genDir, path)
+		if err != nil {
+			return err
+		}
+
+		f, ok := files[rel]
+		if !ok {
+			diff.deleted++
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(f.data, content) && info.Mode().Perm() == f.mode && ownedBy(info, f.uid, f.gid) {
+			unchanged++
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return changes{written: len(files)}, nil
+	}
+	if err != nil {
+		return changes{}, err
+	}
+
+	// the files which are not in the current generation are new
+	diff.written = len(files) - unchanged
+	return diff, nil
+}
+
+func ownedBy(info os.FileInfo, uid int, gid int) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	return (uid < 0 || int(stat.Uid) == uid) && (gid < 0 || int(stat.Gid) == gid)
+}
+
+// swapDataDir writes the files into a new generation directory and atomically points the data dir symlink to it
+func swapDataDir(baseDir string, files payload) error {
+	genDir, err := ioutil.TempDir(baseDir, time.Now().UTC().Format("..2006_01_02_15_04_05."))
+	if err != nil {
+		return err
+	}
+
+	if err := writeGeneration(genDir, files); err != nil {
+		os.RemoveAll(genDir)
+		return err
+	}
+
+	newDataDir := filepath.Join(baseDir, newDataDirName)
+	if err := os.Remove(newDataDir); err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(genDir)
+		return err
+	}
+
+	if err := os.Symlink(filepath.Base(genDir), newDataDir); err != nil {
+		os.RemoveAll(genDir)
+		return err
+	}
+
+	if err := os.Rename(newDataDir, filepath.Join(baseDir, dataDirName)); err != nil {
+		os.Remove(newDataDir)
+		os.RemoveAll(genDir)
+		return err
+	}
+
+	return nil
+}
+
+func writeGeneration(genDir string, files payload) error {
+	// the temp dir is created with 0700 permissions, but the files should be accessible by other processes
+	if err := os.Chmod(genDir, 0755); err != nil {
+		return err
+	}
+
+	for path, f := range files {
+		if err := writeFile(filepath.Join(genDir, path), f); err != nil {
+			return fmt.Errorf("failed to write the file '%s': %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+func writeFile(path string, f *file) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, f.data, f.mode); err != nil {
+		return err
+	}
+
+	// the mode might have been restricted by umask
+	if err := os.Chmod(path, f.mode); err != nil {
+		return err
+	}
+
+	if f.uid >= 0 || f.gid >= 0 {
+		return os.Chown(path, f.uid, f.gid)
+	}
+
+	return nil
+}
+
+// linkFiles makes sure every top-level file or directory in the payload is a symlink into the data dir. Existing
+// files, possibly written by the previous versions of configbump, are atomically replaced by the symlinks.
+func linkFiles(baseDir string, files payload) error {
+	for name := range topLevelNames(files) {
+		path := filepath.Join(baseDir, name)
+		target := filepath.Join(dataDirName, name)
+
+		if link, err := os.Readlink(path); err == nil && link == target {
+			continue
+		}
+
+		tmpPath := filepath.Join(baseDir, "..tmp_"+name)
+		if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := os.Symlink(target, tmpPath); err != nil {
+			return err
+		}
+
+		if err := os.Rename(tmpPath, path); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func topLevelNames(files payload) map[string]bool {
+	names := make(map[string]bool, len(files))
+	for path := range files {
+		names[topLevelName(path)] = true
+	}
+	return names
+}
+
+// removeStaleFiles removes the files in the base dir which are not in the payload. Returns the number of the removed
+// files which are not symlinks into the data dir, because the removed symlinks are counted by diffPayload already.
+func removeStaleFiles(baseDir string, files payload) (int, error) {
+	entries, err := ioutil.ReadDir(baseDir)
+	if err != nil {
+		return 0, err
+	}
+
+	names := topLevelNames(files)
+
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), "..") {
+			continue
+		}
+
+		if names[e.Name()] {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(baseDir, e.Name())); err != nil {
+			return removed, err
+		}
+		if e.Mode()&os.ModeSymlink == 0 {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// removeStaleGenerations removes all the generation directories except the one the data dir points to. There is
+// nothing to remove if no generation was written yet.
+func removeStaleGenerations(baseDir string) error {
+	current, err := os.Readlink(filepath.Join(baseDir, dataDirName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(baseDir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "..") || e.Name() == current {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(baseDir, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package configmaps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ModeAnnotationPrefix followed by the key of the config map or secret is the annotation with the octal file
+	// mode of the file, e.g. "mode.configbump.che.eclipse.org/tls.key: 0640"
+	ModeAnnotationPrefix = "mode.configbump.che.eclipse.org/"
+	// OwnerAnnotationPrefix followed by the key is the annotation with the numeric owner of the file in the form
+	// of "uid" or "uid:gid", e.g. "owner.configbump.che.eclipse.org/tls.key: 101:101"
+	OwnerAnnotationPrefix = "owner.configbump.che.eclipse.org/"
+	// PathAnnotationPrefix followed by the key is the annotation with the subdirectory of the base dir to write
+	// the file to, e.g. "path.configbump.che.eclipse.org/tls.key: certs"
+	PathAnnotationPrefix = "path.configbump.che.eclipse.org/"
+	// TemplateAnnotationPrefix followed by the key is the annotation marking the key as a Go template which is
+	// rendered before the file is written, e.g. "template.configbump.che.eclipse.org/traefik.yml: true"
+	TemplateAnnotationPrefix = "template.configbump.che.eclipse.org/"
+
+	// defaultConfigMapMode is the mode of the files from the config maps
+	defaultConfigMapMode os.FileMode = 0644
+	// defaultSecretMode is the mode of the files from the secrets, only readable by the owner
+	defaultSecretMode os.FileMode = 0600
+)
+
+// object is the config map or secret
+type object interface {
+	metav1.Object
+	runtime.Object
+}
+
+// addFiles adds the data of the object to the payload. The mode, owner, path of the files and whether they are
+// templates are read from the annotations of the object, the default mode is used for the keys without the mode
+// annotation.
+func addFiles(files payload, obj object, data map[string][]byte, defaultMode os.FileMode) error {
+	annotations := obj.GetAnnotations()
+
+	for key, content := range data {
+		f := &file{data: content, mode: defaultMode, uid: -1, gid: -1, source: obj}
+
+		if mode, ok := annotations[ModeAnnotationPrefix+key]; ok {
+			m, err := strconv.ParseUint(mode, 8, 32)
+			if err != nil || m > 0777 {
+				return fmt.Errorf("invalid file mode '%s' of the key '%s' in %s/%s", mode, key, obj.GetNamespace(), obj.GetName())
+			}
+			f.mode = os.FileMode(m)
+		}
+
+		if owner, ok := annotations[OwnerAnnotationPrefix+key]; ok {
+			uid, gid, err := parseOwner(owner)
+			if err != nil {
+				return fmt.Errorf("invalid owner '%s' of the key '%s' in %s/%s: %s", owner, key, obj.GetNamespace(), obj.GetName(), err)
+			}
+			f.uid, f.gid = uid, gid
+		}
+
+		if template, ok := annotations[TemplateAnnotationPrefix+key]; ok {
+			t, err := strconv.ParseBool(template)
+			if err != nil {
+				return fmt.Errorf("invalid template marker '%s' of the key '%s' in %s/%s", template, key, obj.GetNamespace(), obj.GetName())
+			}
+			f.template = t
+		}
+
+		path := key
+		if dir, ok := annotations[PathAnnotationPrefix+key]; ok {
+			path = filepath.Join(filepath.Clean(dir), key)
+		}
+
+		files[path] = f
+	}
+
+	return nil
+}
+
+// parseOwner parses the owner in the form of "uid" or "uid:gid". The gid is -1 if not specified.
+func parseOwner(owner string) (int, int, error) {
+	parts := strings.SplitN(owner, ":", 2)
+
+	uid, err := strconv.ParseUint(parts[0], 10, 31)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	gid := -1
+	if len(parts) == 2 {
+		g, err := strconv.ParseUint(parts[1], 10, 31)
+		if err != nil {
+			return 0, 0, err
+		}
+		gid = int(g)
+	}
+
+	return int(uid), gid, nil
+}
@@ -2,14 +2,13 @@ package configmaps
 
 import (
 	"context"
-	"crypto/md5"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 
+	"github.com/che-incubator/configbump/pkg/health"
+	"github.com/che-incubator/configbump/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -26,23 +25,25 @@ type ConfigMapReconcilerConfig struct {
 	BaseDir         string
 	Labels          string
 	Namespace       string
+	DownwardAPIDir  string
+	Secrets         bool
 	OnReconcileDone func() error
 	NewClient       func(*rest.Config) (client.Client, error)
+	Recorder        record.EventRecorder
+	Status          *health.Status
 }
 
 type configMapReconciler struct {
 	client        client.Client
 	clientConfig  *rest.Config
 	newClientFunc func(*rest.Config) (client.Client, error)
+	recorder      record.EventRecorder
 	config        ConfigMapReconcilerConfig
 	selector      labels.Selector
 	baseDir       string
 	namespace     string
 }
 
-// configFiles is a map where keys are the names of the files and values are digests of their content
-type configFiles = map[string][16]byte
-
 // New creates a config map reconciler with given configuration and configures a controller for it
 func New(mgr manager.Manager, config ConfigMapReconcilerConfig) (controller.Controller, error) {
 	lbls, err := labels.ConvertSelectorToLabelsMap(config.Labels)
@@ -58,10 +59,17 @@ func New(mgr manager.Manager, config ConfigMapReconcilerConfig) (controller.Cont
 		}
 	}
 
+	recorder := config.Recorder
+
+	if recorder == nil {
+		recorder = mgr.GetEventRecorderFor("config-bump")
+	}
+
 	r := &configMapReconciler{
 		client:        mgr.GetClient(),
 		clientConfig:  mgr.GetConfig(),
 		newClientFunc: newClientFn,
+		recorder:      recorder,
 		config:        config,
 		selector:      lbls.AsSelector(),
 	}
@@ -78,106 +86,134 @@ func New(mgr manager.Manager, config ConfigMapReconcilerConfig) (controller.Cont
 		return nil, err
 	}
 
+	// the secrets are only watched if enabled, so that the service account doesn't need to be able to read them
+	if config.Secrets {
+		err = ctrl.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ctrl, nil
 }
 
-// sync performs the sync of the local set of files with the configured config maps
+// sync performs the sync of the local set of files with the configured config maps and secrets. OnReconcileDone
+// is only called if some of the files were actually changed. The results of the sync and of OnReconcileDone are
+// reported as events of the config maps and secrets the files come from.
 func (c *configMapReconciler) sync(managerRunning bool) error {
-	if c.config.OnReconcileDone != nil {
-		defer c.config.OnReconcileDone()
+	diff, sources, err := c.syncFiles(managerRunning)
+	c.config.Status.SyncDone(err)
+
+	metrics.FilesWritten.Add(float64(diff.written))
+	metrics.FilesDeleted.Add(float64(diff.deleted))
+	if err != nil {
+		metrics.SyncErrors.Inc()
+		c.recordEvent(sources, corev1.EventTypeWarning, "SyncFailed", "Failed to sync the files to %s: %s", c.config.BaseDir, err)
+	} else {
+		metrics.LastSuccessfulSync.SetToCurrentTime()
+		if diff.any() {
+			c.recordEvent(sources, corev1.EventTypeNormal, "Synced", "Synced the files to %s: %d written, %d deleted", c.config.BaseDir, diff.written, diff.deleted)
+		}
 	}
 
+	if diff.any() && c.config.OnReconcileDone != nil {
+		if err := c.config.OnReconcileDone(); err != nil {
+			log.Error(err, "Failed to notify about the changed config files")
+			c.recordEvent(sources, corev1.EventTypeWarning, "SignalFailed", "Failed to signal the process about the changed files: %s", err)
+		} else {
+			c.recordEvent(sources, corev1.EventTypeNormal, "Signalled", "Signalled the process about the changed files")
+		}
+	}
+
+	return err
+}
+
+// syncFiles writes the files from the config maps and, if enabled, the secrets. Returns the changes of the files and the config
+// maps and secrets matching the selector, even if the sync failed.
+func (c *configMapReconciler) syncFiles(managerRunning bool) (changes, []object, error) {
 	var cl client.Client
 	if managerRunning {
 		cl = c.client
 	} else {
 		x, err := c.newClientFunc(c.clientConfig)
 		if err != nil {
-			return err
+			return changes{}, nil, err
 		}
 		cl = x
 	}
 
-	list := &corev1.ConfigMapList{}
 	opts := []client.ListOption{
 		client.InNamespace(c.config.Namespace),
 		client.MatchingLabelsSelector{Selector: c.selector},
 	}
 
-	if err := cl.List(context.TODO(), list, opts...); err != nil {
-		return err
+	configMaps := &corev1.ConfigMapList{}
+	if err := cl.List(context.TODO(), configMaps, opts...); err != nil {
+		return changes{}, nil, err
 	}
 
-	processedFiles := make([]string, 0, 8)
+	secrets := &corev1.SecretList{}
+	if c.config.Secrets {
+		if err := cl.List(context.TODO(), secrets, opts...); err != nil {
+			return changes{}, nil, err
+		}
+	}
+
+	files := make(payload)
+	sources := make([]object, 0, len(configMaps.Items)+len(secrets.Items))
 
-	for _, cm := range list.Items {
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
 		if !c.selector.Matches(labels.Set(cm.ObjectMeta.Labels)) {
 			continue
 		}
+		sources = append(sources, cm)
 
-		for name, data := range cm.Data {
-			path := filepath.Join(c.config.BaseDir, name)
-			doWrite := false
-			if _, err := os.Stat(path); err == nil || os.IsExist(err) {
-				// if the file exists
-				if content, err := ioutil.ReadFile(path); err != nil {
-					log.Error(err, "Failed to open the config file to see if it changed", "file", path)
-				} else {
-					dataHash := md5.Sum([]byte(data))
-					contentHash := md5.Sum([]byte(content))
-
-					doWrite = dataHash != contentHash
-				}
-			} else {
-				// the file doesn't exist
-				doWrite = true
-			}
-
-			if doWrite {
-				if f, err := os.Create(path); err != nil {
-					log.Error(err, "Failed to create a file for the configmap",
-						"file", path, "namespace", cm.GetObjectMeta().GetNamespace(), "name", cm.GetObjectMeta().GetName())
-				} else {
-					defer f.Close()
-					f.Write([]byte(data))
-				}
-			}
-
-			processedFiles = append(processedFiles, path)
+		data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for key, value := range cm.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range cm.BinaryData {
+			data[key] = value
+		}
+
+		if err := addFiles(files, cm, data, defaultConfigMapMode); err != nil {
+			log.Error(err, "Failed to read the config map", "namespace", cm.GetNamespace(), "name", cm.GetName())
+			return changes{}, []object{cm}, err
 		}
 	}
 
-	// now go through all the existing files and delete those we have not processed while reading the config maps
-	files, err := ioutil.ReadDir(c.config.BaseDir)
-	if err != nil {
-		return err
-	}
-
-	for _, f := range files {
-		if !f.IsDir() {
-			path := filepath.Join(c.config.BaseDir, f.Name())
-			found := false
-			for _, v := range processedFiles {
-				if v == path {
-					found = true
-					break
-				}
-			}
-
-			if found {
-				continue
-			}
-
-			if err := os.Remove(path); err != nil {
-				return err
-			}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !c.selector.Matches(labels.Set(secret.ObjectMeta.Labels)) {
+			continue
 		}
+		sources = append(sources, secret)
+
+		if err := addFiles(files, secret, secret.Data, defaultSecretMode); err != nil {
+			log.Error(err, "Failed to read the secret", "namespace", secret.GetNamespace(), "name", secret.GetName())
+			return changes{}, []object{secret}, err
+		}
+	}
+
+	renderTemplates(c.config.BaseDir, c.config.DownwardAPIDir, files, c.recorder)
+
+	diff, err := writePayload(c.config.BaseDir, files)
+	if err != nil {
+		log.Error(err, "Failed to write the config files", "dir", c.config.BaseDir)
 	}
 
-	return nil
+	return diff, sources, err
+}
+
+func (c *configMapReconciler) recordEvent(sources []object, eventType string, reason string, messageFmt string, args ...interface{}) {
+	for _, source := range sources {
+		c.recorder.Eventf(source, eventType, reason, messageFmt, args...)
+	}
 }
 
-// Reconcile handles the changes in the configured config maps
+// Reconcile handles the changes in the configured config maps and secrets
 func (c *configMapReconciler) Reconcile(r reconcile.Request) (reconcile.Result, error) {
 	err := c.sync(true)
 	return reconcile.Result{}, err
@@ -1,9 +1,13 @@
 package configmaps
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -12,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -55,7 +60,7 @@ func TestCreatesFiles(t *testing.T) {
 	}
 	ctrl.Reconcile(req)
 
-	files, err := ioutil.ReadDir(state.workDir)
+	files, err := readConfigFiles(state.workDir)
 	if err != nil {
 		t.Fatalf("Failed to read the sync dir. %s", err)
 	}
@@ -116,7 +121,7 @@ func TestUpdatesFiles(t *testing.T) {
 	}
 	ctrl.Reconcile(req)
 
-	files, err := ioutil.ReadDir(state.workDir)
+	files, err := readConfigFiles(state.workDir)
 	if err != nil {
 		t.Fatalf("Failed to read the sync dir. %s", err)
 	}
@@ -176,7 +181,7 @@ func TestDeletesFiles(t *testing.T) {
 	}
 	ctrl.Reconcile(req)
 
-	files, err := ioutil.ReadDir(state.workDir)
+	files, err := readConfigFiles(state.workDir)
 	if err != nil {
 		t.Fatalf("Failed to read the sync dir. %s", err)
 	}
@@ -234,7 +239,7 @@ func TestPicksConfigMapsByLabel(t *testing.T) {
 	}
 	ctrl.Reconcile(req)
 
-	files, err := ioutil.ReadDir(state.workDir)
+	files, err := readConfigFiles(state.workDir)
 	if err != nil {
 		t.Fatalf("Failed to read the sync dir. %s", err)
 	}
@@ -259,7 +264,409 @@ func TestPicksConfigMapsByLabel(t *testing.T) {
 	}
 }
 
+func TestNotifiesOnlyAboutChangedFiles(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = make(map[string]string)
+	cm.Data["created1.txt"] = "data1"
+
+	notifications := 0
+	_, ctrl, err := testWithCallback("", func() error {
+		notifications++
+		return nil
+	}, cm)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	if notifications != 1 {
+		t.Fatalf("There should have been exactly 1 notification after the initial sync but there were %d.", notifications)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cm.ObjectMeta.Name,
+			Namespace: cm.ObjectMeta.Namespace,
+		},
+	}
+	ctrl.Reconcile(req)
+
+	if notifications != 1 {
+		t.Fatalf("There should have been no notification when the files didn't change but there were %d.", notifications-1)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(state.workDir, "created1.txt"), []byte("origdata"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to precreate a file to be updated. %s", err)
+	}
+	ctrl.Reconcile(req)
+
+	if notifications != 2 {
+		t.Fatalf("There should have been a notification when the file changed but there were %d.", notifications-1)
+	}
+}
+
+func TestSwapsGenerationsOfFiles(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = make(map[string]string)
+	cm.Data["created1.txt"] = "data1"
+
+	cl, ctrl, err := testWith("", cm)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	firstGeneration, err := os.Readlink(filepath.Join(state.workDir, "..data"))
+	if err != nil {
+		t.Fatalf("Failed to read the ..data symlink. %s", err)
+	}
+
+	link, err := os.Readlink(filepath.Join(state.workDir, "created1.txt"))
+	if err != nil || link != filepath.Join("..data", "created1.txt") {
+		t.Fatalf("The config file should have been a symlink into ..data but was '%s'. %v", link, err)
+	}
+
+	cm.Data["created1.txt"] = "data2"
+	if err := cl.Update(context.TODO(), cm); err != nil {
+		t.Fatalf("Failed to update the config map. %s", err)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cm.ObjectMeta.Name,
+			Namespace: cm.ObjectMeta.Namespace,
+		},
+	}
+	ctrl.Reconcile(req)
+
+	secondGeneration, err := os.Readlink(filepath.Join(state.workDir, "..data"))
+	if err != nil {
+		t.Fatalf("Failed to read the ..data symlink. %s", err)
+	}
+
+	if secondGeneration == firstGeneration {
+		t.Fatalf("The ..data symlink should have been pointed to a new generation of the files.")
+	}
+
+	if _, err := os.Stat(filepath.Join(state.workDir, firstGeneration)); !os.IsNotExist(err) {
+		t.Errorf("The stale generation %s should have been removed.", firstGeneration)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(state.workDir, "created1.txt"))
+	if err != nil || string(contents) != "data2" {
+		t.Errorf("Failed to find the expected created1.txt with updated contents.")
+	}
+}
+
+func TestWritesEmptyPayloadToFreshDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-bump-fresh")
+	if err != nil {
+		t.Fatalf("Failed to create the base dir. %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	diff, err := writePayload(dir, payload{})
+	if err != nil {
+		t.Fatalf("Failed to write the empty payload. %s", err)
+	}
+
+	if diff.any() {
+		t.Errorf("Expected no changes but were %+v.", diff)
+	}
+}
+
+func TestKeepsFilesWhenWriteFails(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = make(map[string]string)
+	cm.Data["created1.txt"] = "data1"
+
+	cl, ctrl, err := testWith("", cm)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	generation, err := os.Readlink(filepath.Join(state.workDir, "..data"))
+	if err != nil {
+		t.Fatalf("Failed to read the ..data symlink. %s", err)
+	}
+
+	// the file name is too long to be written
+	cm.Data["created1.txt"] = "data2"
+	cm.Data[strings.Repeat("x", 300)] = "data"
+	if err := cl.Update(context.TODO(), cm); err != nil {
+		t.Fatalf("Failed to update the config map. %s", err)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cm.ObjectMeta.Name,
+			Namespace: cm.ObjectMeta.Namespace,
+		},
+	}
+	if _, err := ctrl.Reconcile(req); err == nil {
+		t.Fatalf("The failed write should have been reported.")
+	}
+
+	current, err := os.Readlink(filepath.Join(state.workDir, "..data"))
+	if err != nil || current != generation {
+		t.Fatalf("The ..data symlink should have been kept pointing to %s but was %s. %v", generation, current, err)
+	}
+
+	entries, err := ioutil.ReadDir(state.workDir)
+	if err != nil {
+		t.Fatalf("Failed to read the sync dir. %s", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != generation {
+			t.Errorf("The failed generation %s should have been removed.", e.Name())
+		}
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(state.workDir, "created1.txt"))
+	if err != nil || string(contents) != "data1" {
+		t.Errorf("Failed to find the expected created1.txt with original contents.")
+	}
+}
+
+func TestWritesSecretsAndBinaryDataWithAttributes(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = map[string]string{"created1.txt": "data1"}
+	cm.BinaryData = map[string][]byte{"binary.bin": {0, 1, 2}}
+	cm.ObjectMeta.Annotations = map[string]string{
+		ModeAnnotationPrefix + "binary.bin": "0755",
+	}
+	secret := &corev1.Secret{}
+	secret.ObjectMeta.Name = "test"
+	secret.Data = map[string][]byte{"tls.key": []byte("key"), "tls.crt": []byte("crt")}
+	secret.ObjectMeta.Annotations = map[string]string{
+		PathAnnotationPrefix + "tls.key":  "certs",
+		PathAnnotationPrefix + "tls.crt":  "certs",
+		ModeAnnotationPrefix + "tls.crt":  "0644",
+		OwnerAnnotationPrefix + "tls.key": strconv.Itoa(os.Getuid()),
+	}
+
+	_, _, err := testWithConfig(ConfigMapReconcilerConfig{Secrets: true}, cm, secret)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	expected := map[string]struct {
+		contents string
+		mode     os.FileMode
+	}{
+		"created1.txt":  {"data1", 0644},
+		"binary.bin":    {"\x00\x01\x02", 0755},
+		"certs/tls.key": {"key", 0600},
+		"certs/tls.crt": {"crt", 0644},
+	}
+
+	for path, e := range expected {
+		info, err := os.Stat(filepath.Join(state.workDir, path))
+		if err != nil {
+			t.Errorf("Failed to find the expected %s. %s", path, err)
+			continue
+		}
+
+		if info.Mode().Perm() != e.mode {
+			t.Errorf("The file %s should have had mode %o but had %o.", path, e.mode, info.Mode().Perm())
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(state.workDir, path))
+		if err != nil || string(contents) != e.contents {
+			t.Errorf("Failed to find the expected %s with matching contents.", path)
+		}
+	}
+
+	files, err := readConfigFiles(state.workDir)
+	if err != nil {
+		t.Fatalf("Failed to read the sync dir. %s", err)
+	}
+
+	if len(files) != 3 {
+		t.Fatalf("There should have been exactly 3 entries in the sync dir but there were %d.", len(files))
+	}
+}
+
+func TestIgnoresSecretsUnlessEnabled(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.ObjectMeta.Name = "test"
+	secret.Data = map[string][]byte{"tls.key": []byte("key")}
+
+	_, _, err := testWith("", secret)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	if _, err := os.Lstat(filepath.Join(state.workDir, "tls.key")); !os.IsNotExist(err) {
+		t.Fatalf("The secret should not have been synced. %v", err)
+	}
+}
+
+func TestRejectsInvalidFileMode(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = map[string]string{"created1.txt": "data1"}
+	cm.ObjectMeta.Annotations = map[string]string{
+		ModeAnnotationPrefix + "created1.txt": "rw-r--r--",
+	}
+
+	if _, _, err := testWith("", cm); err == nil {
+		t.Fatalf("The invalid file mode should have been reported.")
+	}
+}
+
+func TestRendersTemplates(t *testing.T) {
+	downwardAPIDir, err := ioutil.TempDir("", "config-bump-podinfo")
+	if err != nil {
+		t.Fatalf("Failed to create the downward API dir. %s", err)
+	}
+	defer os.RemoveAll(downwardAPIDir)
+
+	err = ioutil.WriteFile(filepath.Join(downwardAPIDir, "name"), []byte("gateway-1"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write the downward API value. %s", err)
+	}
+	os.Setenv("CONFIG_BUMP_TEST_PORT", "8080")
+	defer os.Unsetenv("CONFIG_BUMP_TEST_PORT")
+
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = map[string]string{
+		"routes.yml":  "routes: []",
+		"traefik.yml": `{{ .Pod.name }}:{{ .Env.CONFIG_BUMP_TEST_PORT }} {{ index .Files "routes.yml" }}`,
+	}
+	cm.ObjectMeta.Annotations = map[string]string{
+		TemplateAnnotationPrefix + "traefik.yml": "true",
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	cl, ctrl, err := testWithConfig(ConfigMapReconcilerConfig{DownwardAPIDir: downwardAPIDir, Recorder: recorder}, cm)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(state.workDir, "traefik.yml"))
+	if err != nil || string(contents) != "gateway-1:8080 routes: []" {
+		t.Fatalf("Failed to find the expected rendered traefik.yml, was '%s'. %v", contents, err)
+	}
+
+	cm.Data["traefik.yml"] = `{{ .Pod.unknown }}`
+	if err := cl.Update(context.TODO(), cm); err != nil {
+		t.Fatalf("Failed to update the config map. %s", err)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cm.ObjectMeta.Name,
+			Namespace: cm.ObjectMeta.Namespace,
+		},
+	}
+	ctrl.Reconcile(req)
+
+	contents, err = ioutil.ReadFile(filepath.Join(state.workDir, "traefik.yml"))
+	if err != nil || string(contents) != "gateway-1:8080 routes: []" {
+		t.Fatalf("The previous good traefik.yml should have been kept, was '%s'. %v", contents, err)
+	}
+
+	if !receivedEvent(recorder, "Warning TemplateRenderFailed") {
+		t.Errorf("The render failure should have been reported as an event.")
+	}
+}
+
+func TestHidesSecretsFromConfigMapTemplates(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = map[string]string{"config.yml": `key: [{{ index .Files "tls.key" }}]`}
+	cm.ObjectMeta.Annotations = map[string]string{
+		TemplateAnnotationPrefix + "config.yml": "true",
+	}
+	secret := &corev1.Secret{}
+	secret.ObjectMeta.Name = "test"
+	secret.Data = map[string][]byte{
+		"tls.key":    []byte("key"),
+		"secret.yml": []byte(`key: [{{ index .Files "tls.key" }}]`),
+	}
+	secret.ObjectMeta.Annotations = map[string]string{
+		TemplateAnnotationPrefix + "secret.yml": "true",
+	}
+
+	_, _, err := testWithConfig(ConfigMapReconcilerConfig{Secrets: true}, cm, secret)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(state.workDir, "config.yml"))
+	if err != nil || string(contents) != "key: []" {
+		t.Errorf("The secret should not have been rendered into config.yml, was '%s'. %v", contents, err)
+	}
+
+	contents, err = ioutil.ReadFile(filepath.Join(state.workDir, "secret.yml"))
+	if err != nil || string(contents) != "key: [key]" {
+		t.Errorf("The secret should have been rendered into secret.yml, was '%s'. %v", contents, err)
+	}
+}
+
+func TestRecordsSyncAndSignalEvents(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = "test"
+	cm.Data = map[string]string{
+		"created1.txt": "data1",
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	cl, ctrl, err := testWithConfig(ConfigMapReconcilerConfig{
+		Recorder:        recorder,
+		OnReconcileDone: func() error { return errors.New("no process") },
+	}, cm)
+	if err != nil {
+		t.Fatalf("Failed to setup up the test. %s", err)
+	}
+
+	if !receivedEvent(recorder, "Normal Synced") {
+		t.Errorf("The initial sync should have been reported as an event.")
+	}
+
+	cm.Data["created1.txt"] = "data2"
+	if err := cl.Update(context.TODO(), cm); err != nil {
+		t.Fatalf("Failed to update the config map. %s", err)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      cm.ObjectMeta.Name,
+			Namespace: cm.ObjectMeta.Namespace,
+		},
+	}
+	ctrl.Reconcile(req)
+
+	if !receivedEvent(recorder, "Warning SignalFailed") {
+		t.Errorf("The failed signal should have been reported as an event.")
+	}
+
+	// nothing changed, nothing to report
+	ctrl.Reconcile(req)
+
+	if receivedEvent(recorder, "") {
+		t.Errorf("No events should have been reported when no files changed.")
+	}
+}
+
 func testWith(labels string, cms ...runtime.Object) (client.Client, reconcile.Reconciler, error) {
+	return testWithCallback(labels, nil, cms...)
+}
+
+func testWithCallback(labels string, onReconcileDone func() error, cms ...runtime.Object) (client.Client, reconcile.Reconciler, error) {
+	return testWithConfig(ConfigMapReconcilerConfig{
+		Labels:          labels,
+		OnReconcileDone: onReconcileDone,
+	}, cms...)
+}
+
+func testWithConfig(config ConfigMapReconcilerConfig, cms ...runtime.Object) (client.Client, reconcile.Reconciler, error) {
 	cl := fake.NewFakeClient(cms...)
 
 	cfg := rest.Config{}
@@ -283,13 +690,16 @@ func testWith(labels string, cms ...runtime.Object) (client.Client, reconcile.Re
 		return nil, nil, err
 	}
 
-	ctrl, err := New(mgr, ConfigMapReconcilerConfig{
-		BaseDir: state.workDir,
-		NewClient: func(*rest.Config) (client.Client, error) {
-			return cl, nil
-		},
-		Labels: labels,
-	})
+	config.BaseDir = state.workDir
+	if config.Recorder == nil {
+		// the events of the objects from the fake client can't be recorded by the real recorder
+		config.Recorder = record.NewFakeRecorder(100)
+	}
+	config.NewClient = func(*rest.Config) (client.Client, error) {
+		return cl, nil
+	}
+
+	ctrl, err := New(mgr, config)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -297,6 +707,35 @@ func testWith(labels string, cms ...runtime.Object) (client.Client, reconcile.Re
 	return cl, ctrl, nil
 }
 
+// readConfigFiles lists the config files in the dir without the generation directories and the ..data symlink
+// receivedEvent consumes all the events recorded so far and checks any of them starts with the prefix
+func receivedEvent(recorder *record.FakeRecorder, prefix string) bool {
+	found := false
+	for {
+		select {
+		case event := <-recorder.Events:
+			found = found || strings.HasPrefix(event, prefix)
+		default:
+			return found
+		}
+	}
+}
+
+func readConfigFiles(dir string) ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "..") {
+			files = append(files, e)
+		}
+	}
+	return files, nil
+}
+
 func setup() (testSetup, error) {
 	workDir, err := ioutil.TempDir("", "config-bump-test")
 	if err != nil {
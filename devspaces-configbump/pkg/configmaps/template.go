@@ -0,0 +1,132 @@
+package configmaps
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// templateContext is the data the templates are rendered with
+type templateContext struct {
+	// Files are the contents of the synced files, which are not templates, by their paths. The files from
+	// the secrets are only available to the templates from the secrets, so that the secret contents don't end up
+	// in the less protected files from the config maps.
+	Files map[string]string
+	// Env are the environment variables of configbump
+	Env map[string]string
+	// Pod are the downward API values read from the files in the downward API dir by their names
+	Pod map[string]string
+}
+
+// renderTemplates renders the template files of the payload. If a template fails to render, the previous good
+// file is kept, or the file is not written at all if there is none. The failure is reported as an event
+// of the config map or secret the template comes from.
+func renderTemplates(baseDir string, downwardAPIDir string, files payload, recorder record.EventRecorder) {
+	// the contexts of the templates from the config maps and from the secrets
+	contexts := make(map[bool]*templateContext, 2)
+
+	for path, f := range files {
+		if !f.template {
+			continue
+		}
+
+		secret := isSecret(f.source)
+		ctx, ok := contexts[secret]
+		if !ok {
+			ctx = newTemplateContext(downwardAPIDir, files, secret)
+			contexts[secret] = ctx
+		}
+
+		rendered, err := render(path, f.data, ctx)
+		if err == nil {
+			f.data = rendered
+			continue
+		}
+
+		log.Error(err, "Failed to render the template", "file", path, "namespace", f.source.GetNamespace(), "name", f.source.GetName())
+		if recorder != nil {
+			recorder.Eventf(f.source, corev1.EventTypeWarning, "TemplateRenderFailed", "Failed to render the template of the file %s, keeping the previous one: %s", path, err)
+		}
+
+		previous, err := ioutil.ReadFile(filepath.Join(baseDir, dataDirName, path))
+		if err != nil {
+			delete(files, path)
+			continue
+		}
+		f.data = previous
+	}
+}
+
+func render(name string, data []byte, ctx *templateContext) ([]byte, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, ctx); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isSecret(obj object) bool {
+	_, ok := obj.(*corev1.Secret)
+	return ok
+}
+
+// newTemplateContext creates the context of the templates. The files from the secrets are only included
+// if withSecrets is true.
+func newTemplateContext(downwardAPIDir string, files payload, withSecrets bool) *templateContext {
+	ctx := &templateContext{
+		Files: make(map[string]string, len(files)),
+		Env:   make(map[string]string),
+		Pod:   make(map[string]string),
+	}
+
+	for path, f := range files {
+		if !f.template && (withSecrets || !isSecret(f.source)) {
+			ctx.Files[path] = string(f.data)
+		}
+	}
+
+	for _, env := range os.Environ() {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) == 2 {
+			ctx.Env[kv[0]] = kv[1]
+		}
+	}
+
+	if downwardAPIDir == "" {
+		return ctx
+	}
+
+	entries, err := ioutil.ReadDir(downwardAPIDir)
+	if err != nil {
+		log.Error(err, "Failed to read the downward API values", "dir", downwardAPIDir)
+		return ctx
+	}
+
+	for _, e := range entries {
+		// the downward API volume has the same layout as the base dir, the values are the symlinks into ..data
+		if strings.HasPrefix(e.Name(), "..") {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(downwardAPIDir, e.Name()))
+		if err != nil {
+			log.Error(err, "Failed to read the downward API value", "file", e.Name())
+			continue
+		}
+		ctx.Pod[e.Name()] = string(content)
+	}
+
+	return ctx
+}
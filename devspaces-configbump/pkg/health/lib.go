@@ -0,0 +1,107 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status tracks the results of the syncs to tell whether configbump is alive and ready
+type Status struct {
+	mutex              sync.Mutex
+	lastSync           time.Time
+	lastSuccessfulSync time.Time
+	failingSince       time.Time
+	lastError          string
+
+	failureThreshold time.Duration
+	now              func() time.Time
+}
+
+// report is the JSON representation of the status
+type report struct {
+	Live               bool       `json:"live"`
+	Ready              bool       `json:"ready"`
+	LastSync           *time.Time `json:"lastSync,omitempty"`
+	LastSuccessfulSync *time.Time `json:"lastSuccessfulSync,omitempty"`
+	FailingSince       *time.Time `json:"failingSince,omitempty"`
+	LastError          string     `json:"lastError,omitempty"`
+}
+
+// New creates the status. Configbump is not considered alive once the syncs keep failing for longer than
+// the failure threshold. The threshold of 0 or less means the failing syncs never make configbump not alive.
+func New(failureThreshold time.Duration) *Status {
+	return &Status{failureThreshold: failureThreshold, now: time.Now}
+}
+
+// SyncDone records the result of the sync. It does nothing if the status is nil, so that the status is optional.
+func (s *Status) SyncDone(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.lastSync = now
+	if err == nil {
+		s.lastSuccessfulSync = now
+		s.failingSince = time.Time{}
+		s.lastError = ""
+		return
+	}
+
+	if s.failingSince.IsZero() {
+		s.failingSince = now
+	}
+	s.lastError = err.Error()
+}
+
+func (s *Status) report() report {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return report{
+		Live:               s.failureThreshold <= 0 || s.failingSince.IsZero() || s.now().Sub(s.failingSince) <= s.failureThreshold,
+		Ready:              !s.lastSuccessfulSync.IsZero(),
+		LastSync:           timeOrNil(s.lastSync),
+		LastSuccessfulSync: timeOrNil(s.lastSuccessfulSync),
+		FailingSince:       timeOrNil(s.failingSince),
+		LastError:          s.lastError,
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Handler serves the liveness on /healthz, the readiness on /readyz and the whole status on /status. All the
+// endpoints respond with the status in JSON, the liveness and readiness endpoints respond with 503 if not passing.
+func (s *Status) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		report := s.report()
+		writeReport(w, report, report.Live)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := s.report()
+		writeReport(w, report, report.Ready)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, s.report(), true)
+	})
+	return mux
+}
+
+func writeReport(w http.ResponseWriter, r report, passing bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !passing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testStatus(failureThreshold time.Duration) (*Status, *time.Time) {
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := New(failureThreshold)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func get(t *testing.T, s *Status, path string) (int, report) {
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	r := report{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+		t.Fatalf("Failed to parse the status '%s'. %s", rec.Body.String(), err)
+	}
+	return rec.Code, r
+}
+
+func TestNotReadyUntilSuccessfulSync(t *testing.T) {
+	s, _ := testStatus(time.Minute)
+
+	if code, _ := get(t, s, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected not ready before the first sync but was %d.", code)
+	}
+
+	s.SyncDone(errors.New("failed"))
+	if code, _ := get(t, s, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected not ready after the failed sync but was %d.", code)
+	}
+
+	s.SyncDone(nil)
+	if code, _ := get(t, s, "/readyz"); code != http.StatusOK {
+		t.Fatalf("Expected ready after the successful sync but was %d.", code)
+	}
+}
+
+func TestNotLiveWhenFailingLongerThanThreshold(t *testing.T) {
+	s, now := testStatus(time.Minute)
+
+	s.SyncDone(nil)
+	s.SyncDone(errors.New("failed"))
+	*now = now.Add(time.Minute)
+	if code, _ := get(t, s, "/healthz"); code != http.StatusOK {
+		t.Fatalf("Expected live within the threshold but was %d.", code)
+	}
+
+	s.SyncDone(errors.New("failed again"))
+	*now = now.Add(time.Second)
+	code, r := get(t, s, "/healthz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected not live after the threshold but was %d.", code)
+	}
+	if r.LastError != "failed again" || r.FailingSince == nil || !r.FailingSince.Equal(now.Add(-time.Minute-time.Second)) {
+		t.Fatalf("Unexpected status %+v.", r)
+	}
+
+	s.SyncDone(nil)
+	if code, _ := get(t, s, "/healthz"); code != http.StatusOK {
+		t.Fatalf("Expected live after the successful sync but was %d.", code)
+	}
+}
+
+func TestAlwaysLiveWithoutThreshold(t *testing.T) {
+	s, now := testStatus(0)
+
+	s.SyncDone(errors.New("failed"))
+	*now = now.Add(24 * time.Hour)
+	if code, _ := get(t, s, "/healthz"); code != http.StatusOK {
+		t.Fatalf("Expected live without the threshold but was %d.", code)
+	}
+}
+
+func TestReportsSyncTimes(t *testing.T) {
+	s, now := testStatus(time.Minute)
+
+	s.SyncDone(nil)
+	successful := *now
+	*now = now.Add(time.Minute)
+	s.SyncDone(errors.New("failed"))
+
+	code, r := get(t, s, "/status")
+	if code != http.StatusOK {
+		t.Fatalf("Expected the status to be served but was %d.", code)
+	}
+	if r.LastSync == nil || !r.LastSync.Equal(*now) {
+		t.Errorf("Expected the last sync at %s but was %v.", *now, r.LastSync)
+	}
+	if r.LastSuccessfulSync == nil || !r.LastSuccessfulSync.Equal(successful) {
+		t.Errorf("Expected the last successful sync at %s but was %v.", successful, r.LastSuccessfulSync)
+	}
+	if !r.Live || !r.Ready {
+		t.Errorf("Expected live and ready but was %+v.", r)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// SuccessResult is the result label value of the successful signal deliveries
+	SuccessResult = "success"
+	// FailureResult is the result label value of the failed signal deliveries
+	FailureResult = "failure"
+)
+
+var (
+	// FilesWritten counts the new files and the files with changed contents, modes or owners
+	FilesWritten = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configbump_files_written_total",
+		Help: "Number of the config files written because they were new or changed",
+	})
+
+	// FilesDeleted counts the files deleted because they are not in the config maps or secrets anymore
+	FilesDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configbump_files_deleted_total",
+		Help: "Number of the config files deleted because they were removed from the config maps and secrets",
+	})
+
+	// SyncErrors counts the syncs which failed
+	SyncErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configbump_sync_errors_total",
+		Help: "Number of the failed syncs of the config files",
+	})
+
+	// Signals counts the signals sent to the process by their result
+	Signals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "configbump_signals_total",
+		Help: "Number of the signals sent to the process on the config files change by result",
+	}, []string{"result"})
+
+	// LastSuccessfulSync is the time of the last successful sync
+	LastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "configbump_last_successful_sync_timestamp_seconds",
+		Help: "Unix time of the last successful sync of the config files",
+	})
+)
+
+func init() {
+	// the registry of the controller runtime also contains the metrics of the controller itself
+	ctrlmetrics.Registry.MustRegister(FilesWritten, FilesDeleted, SyncErrors, Signals, LastSuccessfulSync)
+}
+
+// Handler serves the metrics in the Prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{})
+}
@@ -30,6 +30,10 @@ import (
 const (
 	// BearerTokenAttr attribute name.
 	BearerTokenAttr = "bearerToken"
+	// UserIDAttr is the attribute with ID of the user the bearer token belongs to.
+	UserIDAttr = "userId"
+	// OwnerAttr is the attribute which is "true" if the user the bearer token belongs to is the workspace owner.
+	OwnerAttr = "owner"
 )
 
 type IdParam struct {
@@ -47,9 +51,19 @@ type ResizeParam struct {
 	Rows uint `json:"rows"`
 }
 
-var (
-	execManager = exec.GetExecManager()
-)
+// RevokeViewerParam defines viewer connection to detach from the exec.
+type RevokeViewerParam struct {
+	Id     int `json:"id"`
+	Viewer int `json:"viewer"`
+}
+
+// DebugParam defines terminal in the ephemeral debug container.
+type DebugParam struct {
+	// Container to debug. The first suitable container is used if omitted.
+	Container string `json:"container"`
+	Cols      int    `json:"cols"`
+	Rows      int    `json:"rows"`
+}
 
 func jsonRpcCreateExec(tunnel *jsonrpc.Tunnel, params interface{}, t jsonrpc.RespTransmitter) {
 	machineExec := params.(*model.MachineExec)
@@ -59,9 +73,9 @@ func jsonRpcCreateExec(tunnel *jsonrpc.Tunnel, params interface{}, t jsonrpc.Res
 		t.SendError(jsonrpc.NewArgsError(err))
 		return
 	}
-	id, err := execManager.Create(machineExec)
+	id, err := exec.GetExecManager().Create(machineExec)
 
-	healthWatcher := exec.NewHealthWatcher(machineExec, events.EventBus, execManager)
+	healthWatcher := exec.NewHealthWatcher(machineExec, events.EventBus, exec.GetExecManager())
 	healthWatcher.CleanUpOnExitOrError()
 
 	if err != nil {
@@ -79,10 +93,32 @@ func jsonRpcCreateExec(tunnel *jsonrpc.Tunnel, params interface{}, t jsonrpc.Res
 	t.Send(id)
 }
 
+func jsonRpcCreateDebugExec(tunnel *jsonrpc.Tunnel, params interface{}, t jsonrpc.RespTransmitter) {
+	debugParam := params.(*DebugParam)
+	machineExec := &model.MachineExec{Cols: debugParam.Cols, Rows: debugParam.Rows}
+	err := setToken(tunnel, machineExec)
+	if err != nil {
+		logrus.Errorf(err.Error())
+		t.SendError(jsonrpc.NewArgsError(err))
+		return
+	}
+	id, err := exec.GetExecManager().CreateDebug(machineExec, debugParam.Container)
+	if err != nil {
+		logrus.Errorf("Unable to initialize terminal in debug container. Cause: %s", err.Error())
+		t.SendError(jsonrpc.NewArgsError(err))
+		return
+	}
+
+	healthWatcher := exec.NewHealthWatcher(machineExec, events.EventBus, exec.GetExecManager())
+	healthWatcher.CleanUpOnExitOrError()
+
+	t.Send(id)
+}
+
 func jsonRpcCheckExec(_ *jsonrpc.Tunnel, params interface{}, t jsonrpc.RespTransmitter) {
 	idParam := params.(*IdParam)
 
-	id, err := execManager.Check(idParam.Id)
+	id, err := exec.GetExecManager().Check(idParam.Id)
 	if err != nil {
 		t.SendError(jsonrpc.NewArgsError(err))
 	}
@@ -93,7 +129,7 @@ func jsonRpcCheckExec(_ *jsonrpc.Tunnel, params interface{}, t jsonrpc.RespTrans
 func jsonRpcResizeExec(_ *jsonrpc.Tunnel, params interface{}) (interface{}, error) {
 	resizeParam := params.(*ResizeParam)
 
-	if err := execManager.Resize(resizeParam.Id, resizeParam.Cols, resizeParam.Rows); err != nil {
+	if err := exec.GetExecManager().Resize(resizeParam.Id, resizeParam.Cols, resizeParam.Rows); err != nil {
 		return nil, jsonrpc.NewArgsError(err)
 	}
 
@@ -102,6 +138,33 @@ func jsonRpcResizeExec(_ *jsonrpc.Tunnel, params interface{}) (interface{}, erro
 	}, nil
 }
 
+func jsonRpcListViewers(_ *jsonrpc.Tunnel, params interface{}) (interface{}, error) {
+	idParam := params.(*IdParam)
+
+	viewers, err := exec.GetExecManager().ListViewers(idParam.Id)
+	if err != nil {
+		return nil, jsonrpc.NewArgsError(err)
+	}
+	return viewers, nil
+}
+
+// jsonRpcRevokeViewer detaches the viewer, only the workspace owner is allowed to revoke viewers.
+func jsonRpcRevokeViewer(tunnel *jsonrpc.Tunnel, params interface{}) (interface{}, error) {
+	revokeParam := params.(*RevokeViewerParam)
+
+	if err := checkOwner(tunnel); err != nil {
+		return nil, jsonrpc.NewArgsError(err)
+	}
+
+	if err := exec.GetExecManager().RevokeViewer(revokeParam.Id, revokeParam.Viewer); err != nil {
+		return nil, jsonrpc.NewArgsError(err)
+	}
+
+	return &OperationResult{
+		Id: revokeParam.Id, Text: "Viewer " + strconv.Itoa(revokeParam.Viewer) + " of exec with id " + strconv.Itoa(revokeParam.Id) + " was revoked",
+	}, nil
+}
+
 func jsonRpcListContainersExec(tunnel *jsonrpc.Tunnel, _ interface{}, t jsonrpc.RespTransmitter) {
 	// use a machine exec object to propagate token
 	machineExec := &model.MachineExec{}
@@ -111,17 +174,40 @@ func jsonRpcListContainersExec(tunnel *jsonrpc.Tunnel, _ interface{}, t jsonrpc.
 		t.SendError(jsonrpc.NewArgsError(err))
 		return
 	}
-	containerList, err := execManager.ListAvailableContainers(machineExec)
+	containerList, err := exec.GetExecManager().ListAvailableContainers(machineExec)
 	if err != nil {
 		t.SendError(jsonrpc.NewArgsError(err))
+		return
 	}
 	t.Send(containerList)
 }
 
+func jsonRpcListSessions(tunnel *jsonrpc.Tunnel, _ interface{}) (interface{}, error) {
+	// use a machine exec object to propagate token
+	caller := &model.MachineExec{}
+	if err := setToken(tunnel, caller); err != nil {
+		return nil, jsonrpc.NewArgsError(err)
+	}
+	return exec.GetExecManager().ListSessions(caller), nil
+}
+
+func jsonRpcExecStats(_ *jsonrpc.Tunnel, _ interface{}) (interface{}, error) {
+	return exec.GetExecManager().Stats(), nil
+}
+
+// checkOwner returns error if the user of the connection is not the workspace owner, see auth.IsOwner.
+func checkOwner(tunnel *jsonrpc.Tunnel) error {
+	if auth.IsEnabled() && tunnel.Attributes[OwnerAttr] != "true" {
+		return errors.New("only the workspace owner can revoke viewers")
+	}
+	return nil
+}
+
 func setToken(tunnel *jsonrpc.Tunnel, machineExec *model.MachineExec) error {
 	if auth.IsEnabled() {
 		if token, ok := tunnel.Attributes[BearerTokenAttr]; ok && len(token) > 0 {
 			machineExec.BearerToken = token
+			machineExec.UserID = tunnel.Attributes[UserIDAttr]
 		} else {
 			err := errors.New("bearer token should not be an empty")
 			return err
@@ -21,9 +21,14 @@ import (
 const (
 	// methods to manage exec life cycle
 	CreateMethod         = "create"
+	CreateDebugMethod    = "createDebug"
 	CheckMethod          = "check"
 	ResizeMethod         = "resize"
 	ListContainersMethod = "listContainers"
+	ListSessionsMethod   = "listSessions"
+	StatsMethod          = "execStats"
+	ListViewersMethod    = "listViewers"
+	RevokeViewerMethod   = "revokeViewer"
 )
 
 // RPCRoutes defines json-rpc exec api. This api uses to manage exec's life cycle.
@@ -35,6 +40,11 @@ var RPCRoutes = jsonrpc.RoutesGroup{
 			Decode: jsonrpc.FactoryDec(func() interface{} { return &model.MachineExec{} }),
 			Handle: jsonRpcCreateExec,
 		},
+		{
+			Method: CreateDebugMethod,
+			Decode: jsonrpc.FactoryDec(func() interface{} { return &DebugParam{} }),
+			Handle: jsonRpcCreateDebugExec,
+		},
 		{
 			Method: CheckMethod,
 			Decode: jsonrpc.FactoryDec(func() interface{} { return &IdParam{} }),
@@ -50,5 +60,25 @@ var RPCRoutes = jsonrpc.RoutesGroup{
 			Decode: jsonrpc.FactoryDec(func() interface{} { return nil }),
 			Handle: jsonRpcListContainersExec,
 		},
+		{
+			Method: ListSessionsMethod,
+			Decode: jsonrpc.FactoryDec(func() interface{} { return nil }),
+			Handle: jsonrpc.HandleRet(jsonRpcListSessions),
+		},
+		{
+			Method: StatsMethod,
+			Decode: jsonrpc.FactoryDec(func() interface{} { return nil }),
+			Handle: jsonrpc.HandleRet(jsonRpcExecStats),
+		},
+		{
+			Method: ListViewersMethod,
+			Decode: jsonrpc.FactoryDec(func() interface{} { return &IdParam{} }),
+			Handle: jsonrpc.HandleRet(jsonRpcListViewers),
+		},
+		{
+			Method: RevokeViewerMethod,
+			Decode: jsonrpc.FactoryDec(func() interface{} { return &RevokeViewerParam{} }),
+			Handle: jsonrpc.HandleRet(jsonRpcRevokeViewer),
+		},
 	},
 }
@@ -13,10 +13,13 @@
 package model
 
 import (
-	line_buffer "github.com/eclipse-che/che-machine-exec/output/line-buffer"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/output/recording"
+	"github.com/eclipse-che/che-machine-exec/output/scrollback"
+	"github.com/eclipse-che/che-machine-exec/process"
 	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
 	"github.com/eclipse/che-go-jsonrpc/event"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 const (
@@ -25,6 +28,19 @@ const (
 	// method names to send events with information about exec to the clients.
 	OnExecExit  = "onExecExit"
 	OnExecError = "onExecError"
+
+	// ArgvExecType runs MachineExec#Cmd as argv without interpreting it by shell.
+	ArgvExecType = "argv"
+
+	// method name to warn the clients that workspace is going to be stopped by idle or run timeout.
+	OnWorkspaceStopWarning = "onWorkspaceStopWarning"
+
+	// method name to report progress of the file transfer to or from the container.
+	OnFileTransferProgress = "onFileTransferProgress"
+
+	// directions of the file transfer.
+	UploadDirection   = "upload"
+	DownloadDirection = "download"
 )
 
 type MachineIdentifier struct {
@@ -36,6 +52,27 @@ type ContainerInfo struct {
 	ContainerName string `json:"container"`
 }
 
+const (
+	// kinds of the workspace pod containers.
+	RegularContainerKind   = "container"
+	InitContainerKind      = "init"
+	EphemeralContainerKind = "ephemeral"
+)
+
+// ContainerMetadata describes the workspace container for the clients to choose where to open terminal.
+type ContainerMetadata struct {
+	ContainerInfo
+	// Kind of the container, one of RegularContainerKind, InitContainerKind, EphemeralContainerKind.
+	Kind  string `json:"kind"`
+	Image string `json:"image,omitempty"`
+	// Component is the name of the devfile component the container is created for. Empty if container is not defined in devfile.
+	Component string `json:"component,omitempty"`
+	// DefaultTerminal is true if devfile marks the container as the default terminal target.
+	DefaultTerminal bool `json:"defaultTerminal,omitempty"`
+	// Attributes of the devfile component.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
 //ResolvedExec holds info client might send to create exec
 type ResolvedExec struct {
 	ContainerInfo
@@ -44,10 +81,20 @@ type ResolvedExec struct {
 
 // Todo code Refactoring: MachineExec should be simple object for exec creation, without any business logic
 type MachineExec struct {
+	// Amount of bytes received from and sent to the exec process.
+	// 64-bit atomic counters are kept first to be aligned on 32-bit platforms.
+	BytesIn  int64 `json:"-"`
+	BytesOut int64 `json:"-"`
+
 	Identifier MachineIdentifier `json:"identifier"`
 	Cmd        []string          `json:"cmd"`
-	// Supported values for now 'shell', "", "process". If type is empty "", then type will resolved like "shell".
+	// Supported values for now 'shell', "", "process", "argv". If type is empty "", then type will resolved like "shell".
 	Type string `json:"type"`
+	// Env variables of the exec process. Supported by "argv" type only.
+	Env map[string]string `json:"env,omitempty"`
+	// Stdin is the content sent to the exec process input, which is closed after it.
+	// Input can't be sent to such exec. Supported by "argv" type only.
+	Stdin *string `json:"stdin,omitempty"`
 
 	Tty  bool   `json:"tty"`
 	Cols int    `json:"cols"`
@@ -56,6 +103,15 @@ type MachineExec struct {
 
 	ExitChan  chan bool
 	ErrorChan chan error
+	// ExitCode of the exec process. It is set before exit is notified with ExitChan.
+	ExitCode int `json:"-"`
+	// ExitSignal is the name of the signal which terminated the exec process, empty if process exited by itself
+	// or if the signal is unknown, which is the case for the processes in the workspace pod containers.
+	ExitSignal string `json:"-"`
+	// ExpiredChan is notified when exec stays without websocket connections longer than the session grace period.
+	ExpiredChan chan bool
+	// StopChan is closed to close exec input.
+	StopChan chan struct{}
 
 	// unique client id, real execId should be hidden from client to prevent serialization
 	ID int `json:"id"`
@@ -64,22 +120,79 @@ type MachineExec struct {
 
 	MsgChan chan []byte
 
-	// Todo Refactoring: this code is kubernetes specific. Create separated code layer and move it.
-	Executor remotecommand.Executor
-	SizeChan chan remotecommand.TerminalSize
+	// Executor is provided by the exec backend to run exec process.
+	Executor process.Executor
+	SizeChan chan process.TerminalSize
 
 	// Todo Refactoring: Create separated code layer and move it.
-	Buffer *line_buffer.LineRingBuffer
+	Buffer *scrollback.Buffer
+	// Recorder of the exec input and output. Is nil if recording is disabled.
+	Recorder *recording.Recorder `json:"-"`
+
+	// Container where exec is created.
+	Container ContainerInfo `json:"-"`
+	// CreatedAt is the time when exec was created.
+	CreatedAt time.Time `json:"-"`
 
 	// BearerToken to have access to the kubernetes api.
 	// For empty value will be created in-cluster config with service account access.
 	BearerToken string
+	// UserID is ID of the user the bearer token belongs to, empty if authentication is disabled.
+	UserID string `json:"-"`
+}
+
+// SessionInfo describes live exec the client is able to reattach to.
+type SessionInfo struct {
+	ID         int               `json:"id"`
+	Identifier MachineIdentifier `json:"identifier"`
+	ContainerInfo
+	Cmd  []string `json:"cmd"`
+	Type string   `json:"type"`
+	Tty  bool     `json:"tty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	// Amount of the websocket connections attached to the exec.
+	Connections int `json:"connections"`
+	// DetachedAt is the time when the last websocket connection was lost. Is not set if exec has connections.
+	DetachedAt *time.Time `json:"detachedAt,omitempty"`
+
+	// Offset of the first output byte which is still available for replay.
+	ScrollbackStart int64 `json:"scrollbackStart"`
+	// Offset of the byte which will be written next.
+	ScrollbackEnd int64 `json:"scrollbackEnd"`
+}
+
+// ExecStats describes resources used by the live execs.
+type ExecStats struct {
+	// Total amount of the live execs.
+	Total int `json:"total"`
+	// Amount of the live execs per container, the key is "pod/container".
+	Containers map[string]int  `json:"containers"`
+	Sessions   []*SessionStats `json:"sessions"`
+}
+
+// SessionStats describes resources used by the single exec.
+type SessionStats struct {
+	ID int `json:"id"`
+	ContainerInfo
+	CreatedAt time.Time `json:"createdAt"`
+	// Age of the exec in seconds.
+	Age int64 `json:"age"`
+	// Amount of the websocket connections attached to the exec.
+	Connections int `json:"connections"`
+	// Amount of bytes sent to the exec input.
+	BytesIn int64 `json:"bytesIn"`
+	// Amount of bytes received from the exec output.
+	BytesOut int64 `json:"bytesOut"`
 }
 
 type ExecExitEvent struct {
 	event.E `json:"-"`
 
-	ExecId int `json:"id"`
+	ExecId   int `json:"id"`
+	ExitCode int `json:"exitCode"`
+	// Signal is reported only for the local processes, see MachineExec.ExitSignal.
+	Signal string `json:"signal,omitempty"`
 }
 
 func (*ExecExitEvent) Type() string {
@@ -97,6 +210,62 @@ func (*ExecErrorEvent) Type() string {
 	return OnExecError
 }
 
+// WorkspaceStopWarningEvent is sent to the clients before workspace is stopped by idle or run timeout.
+type WorkspaceStopWarningEvent struct {
+	event.E `json:"-"`
+
+	// Timeout which stops the workspace, "idle" or "run".
+	Timeout string `json:"timeout"`
+	// StopsIn is the amount of seconds left before workspace is stopped.
+	StopsIn int `json:"stopsIn"`
+	// StopAt is the time when workspace is stopped.
+	StopAt time.Time `json:"stopAt"`
+	// Postponable is true if the user is able to postpone the stop.
+	Postponable bool `json:"postponable"`
+}
+
+func (*WorkspaceStopWarningEvent) Type() string {
+	return OnWorkspaceStopWarning
+}
+
+// PostponeStopParams defines for how long workspace stop by idle or run timeout is postponed.
+type PostponeStopParams struct {
+	// Timeout which stop is postponed, "idle" or "run".
+	Timeout string `json:"timeout"`
+	// Duration of the postpone, for example "15m". The maximum allowed duration is used if omitted.
+	Duration string `json:"duration,omitempty"`
+}
+
+// PostponeStopResult describes when workspace is stopped after the postpone.
+type PostponeStopResult struct {
+	Timeout string    `json:"timeout"`
+	StopAt  time.Time `json:"stopAt"`
+}
+
+// FileTransferProgressEvent is sent to the clients while file or archive is transferred to or from the container.
+type FileTransferProgressEvent struct {
+	event.E `json:"-"`
+
+	// ID of the transfer, unique within machine exec lifetime.
+	ID int `json:"id"`
+	// Direction of the transfer, "upload" or "download".
+	Direction string `json:"direction"`
+	Container string `json:"container"`
+	Path      string `json:"path"`
+	// Amount of bytes transferred so far.
+	Bytes int64 `json:"bytes"`
+	// Total amount of bytes to transfer, 0 if it is unknown.
+	Total int64 `json:"total,omitempty"`
+	// Done is true for the last event of the transfer.
+	Done bool `json:"done"`
+	// Error is set if transfer is failed.
+	Error string `json:"error,omitempty"`
+}
+
+func (*FileTransferProgressEvent) Type() string {
+	return OnFileTransferProgress
+}
+
 type InitConfigParams struct {
 	ContainerName    string `json:"container"` // optional, Will be first suitable container in pod if not set
 	KubeConfigParams `json:"kubeconfig"`
@@ -106,4 +275,8 @@ type KubeConfigParams struct {
 	Namespace   string `json:"namespace"`   //optional, Is not set into kubeconfig file if is not set or empty
 	Username    string `json:"username"`    //optional, Developer in kubeconfig if empty
 	BearerToken string `json:"bearertoken"` //evaluated from header
+	// ID of the user the token belongs to, evaluated from header
+	UserID string `json:"-"`
+	// optional, additional namespaces to create kubeconfig contexts for
+	Namespaces []string `json:"namespaces,omitempty"`
 }
@@ -0,0 +1,263 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+)
+
+const (
+	// InitialWindow is amount of bytes the server is allowed to send over the new channel.
+	InitialWindow = 256 * 1024
+
+	// FlowControlTimeout is a period the server waits for the window to be granted
+	// before channel is detached.
+	FlowControlTimeout = 60 * time.Second
+
+	// Amount of stdin frames queued for the channel before it is detached as stalled.
+	inputQueueSize = 16
+
+	// Amount of output frames queued for the channel before it is detached as too slow.
+	outputQueueSize = 256
+)
+
+var (
+	errChannelClosed     = errors.New("channel is closed")
+	errOutputQueueIsFull = errors.New("client does not keep up with the exec output")
+	errInputQueueIsFull  = errors.New("exec does not keep up with the input")
+)
+
+// channelOwner sends channel frames to the client and tracks channels.
+type channelOwner interface {
+	send(frame *Frame) error
+	// release is called when channel is not able to transfer output anymore.
+	release(ch *channel, cause error)
+}
+
+// channel transfers input/output of the single exec over multiplexed connection.
+// It implements ws_conn.Connection to receive exec output.
+// Viewer channel implements ws_conn.ResizeListener and ws_conn.Revocable as well.
+// Output is queued and sent to the client by the channel's own goroutine, so waiting for the window
+// of one channel never blocks the exec and the other connections attached to it.
+type channel struct {
+	id    uint32
+	owner channelOwner
+	// viewer channel does not forward input
+	viewer bool
+
+	lock    *sync.Mutex
+	window  int64
+	granted chan struct{}
+
+	input  chan []byte
+	output chan *outgoing
+
+	closeOnce *sync.Once
+	closed    chan struct{}
+}
+
+func newChannel(id uint32, owner channelOwner) *channel {
+	ch := &channel{
+		id:        id,
+		owner:     owner,
+		lock:      &sync.Mutex{},
+		window:    InitialWindow,
+		granted:   make(chan struct{}, 1),
+		input:     make(chan []byte, inputQueueSize),
+		output:    make(chan *outgoing, outputQueueSize),
+		closeOnce: &sync.Once{},
+		closed:    make(chan struct{}),
+	}
+	go ch.sendOutput()
+	return ch
+}
+
+// outgoing is the frame queued to be sent to the client.
+type outgoing struct {
+	frame *Frame
+	// channel is closed once the last frame is sent
+	last bool
+}
+
+// Write queues exec output to be sent to the client within granted window.
+func (ch *channel) Write(data []byte) error {
+	return ch.queueOutput(&outgoing{frame: &Frame{Type: StdoutFrame, Channel: ch.id, Payload: append([]byte(nil), data...)}})
+}
+
+// WriteStderr queues exec error output to be sent to the client within granted window.
+func (ch *channel) WriteStderr(data []byte) error {
+	return ch.queueOutput(&outgoing{frame: &Frame{Type: StderrFrame, Channel: ch.id, Payload: append([]byte(nil), data...)}})
+}
+
+// finish queues the last frame to be sent after the pending output and closes the channel once it is sent.
+func (ch *channel) finish(frame *Frame) error {
+	return ch.queueOutput(&outgoing{frame: frame, last: true})
+}
+
+// queueOutput queues the frame without blocking. Channel is detached when the queue is full.
+func (ch *channel) queueOutput(frame *outgoing) error {
+	select {
+	case <-ch.closed:
+		return errChannelClosed
+	default:
+	}
+
+	select {
+	case ch.output <- frame:
+		return nil
+	default:
+		// caller might hold the locks of the exec, so channel is released asynchronously
+		go ch.owner.release(ch, errOutputQueueIsFull)
+		return errOutputQueueIsFull
+	}
+}
+
+// sendOutput sends queued frames to the client until channel is closed.
+func (ch *channel) sendOutput() {
+	for {
+		select {
+		case queued := <-ch.output:
+			if err := ch.send(queued.frame); err != nil {
+				if err != errChannelClosed {
+					ch.owner.release(ch, err)
+				}
+				return
+			}
+			if queued.last {
+				ch.Close()
+				return
+			}
+		case <-ch.closed:
+			return
+		}
+	}
+}
+
+// send sends the frame to the client. Output frames are split to fit the granted window.
+func (ch *channel) send(frame *Frame) error {
+	if frame.Type != StdoutFrame && frame.Type != StderrFrame {
+		return ch.owner.send(frame)
+	}
+
+	data := frame.Payload
+	for len(data) > 0 {
+		n, err := ch.acquireWindow(len(data))
+		if err != nil {
+			return err
+		}
+		if err := ch.owner.send(&Frame{Type: frame.Type, Channel: ch.id, Payload: data[:n]}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// acquireWindow waits until window is granted and takes up to size bytes of it.
+func (ch *channel) acquireWindow(size int) (int, error) {
+	timeout := time.NewTimer(FlowControlTimeout)
+	defer timeout.Stop()
+
+	for {
+		ch.lock.Lock()
+		if ch.window > 0 {
+			acquired := int64(size)
+			if acquired > ch.window {
+				acquired = ch.window
+			}
+			ch.window -= acquired
+			ch.lock.Unlock()
+			return int(acquired), nil
+		}
+		ch.lock.Unlock()
+
+		select {
+		case <-ch.granted:
+		case <-ch.closed:
+			return 0, errChannelClosed
+		case <-timeout.C:
+			return 0, errors.New("client has not granted window in time")
+		}
+	}
+}
+
+// WriteResize queues new terminal size of the exec to be sent to the client after the preceding output.
+func (ch *channel) WriteResize(cols uint, rows uint) error {
+	payload, err := json.Marshal(&ControlEvent{Event: OnTerminalResized, Channel: int(ch.id), Cols: cols, Rows: rows})
+	if err != nil {
+		return err
+	}
+	return ch.queueOutput(&outgoing{frame: &Frame{Type: ControlFrame, Payload: payload}})
+}
+
+// Revoke detaches viewer channel.
+func (ch *channel) Revoke() {
+	ch.owner.release(ch, ws_conn.ErrViewerRevoked)
+}
+
+// grant allows to send size more bytes over the channel.
+func (ch *channel) grant(size uint32) {
+	ch.lock.Lock()
+	ch.window += int64(size)
+	ch.lock.Unlock()
+
+	select {
+	case ch.granted <- struct{}{}:
+	default:
+	}
+}
+
+// queueInput queues exec input received from the client without blocking, so that the exec which doesn't read
+// its input never blocks the other channels of the connection. Channel is detached when the queue is full.
+func (ch *channel) queueInput(data []byte) error {
+	select {
+	case <-ch.closed:
+		return errChannelClosed
+	default:
+	}
+
+	select {
+	case ch.input <- data:
+		return nil
+	default:
+		ch.owner.release(ch, errInputQueueIsFull)
+		return errInputQueueIsFull
+	}
+}
+
+// forwardInput sends queued input to the exec until channel is closed.
+func (ch *channel) forwardInput(send func(data []byte) error) {
+	for {
+		select {
+		case data := <-ch.input:
+			if err := send(data); err != nil {
+				return
+			}
+		case <-ch.closed:
+			return
+		}
+	}
+}
+
+// Close stops channel, pending writes are interrupted.
+func (ch *channel) Close() error {
+	ch.closeOnce.Do(func() {
+		close(ch.closed)
+	})
+	return nil
+}
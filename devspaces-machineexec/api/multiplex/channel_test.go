@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOwner struct {
+	lock     sync.Mutex
+	frames   []*Frame
+	released error
+}
+
+func (owner *fakeOwner) send(frame *Frame) error {
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	owner.frames = append(owner.frames, frame)
+	return nil
+}
+
+func (owner *fakeOwner) release(ch *channel, cause error) {
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	owner.released = cause
+}
+
+func (owner *fakeOwner) sent() int {
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	sent := 0
+	for _, frame := range owner.frames {
+		sent += len(frame.Payload)
+	}
+	return sent
+}
+
+func TestShouldSendOutputWithinWindow(t *testing.T) {
+	owner := &fakeOwner{}
+	ch := newChannel(1, owner)
+	ch.window = 4
+
+	go func() {
+		_ = ch.Write([]byte("0123456789"))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, 4, owner.sent())
+
+	ch.grant(6)
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, 10, owner.sent())
+	assert.Equal(t, StdoutFrame, owner.frames[0].Type)
+	assert.Equal(t, uint32(1), owner.frames[0].Channel)
+	assert.Nil(t, owner.released)
+}
+
+func TestShouldNotBlockWriteWithoutWindow(t *testing.T) {
+	owner := &fakeOwner{}
+	ch := newChannel(1, owner)
+	ch.window = 0
+
+	result := make(chan error)
+	go func() {
+		result <- ch.Write([]byte("data"))
+	}()
+
+	select {
+	case err := <-result:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("write is blocked")
+	}
+
+	_ = ch.Close()
+	assert.Equal(t, errChannelClosed, ch.Write([]byte("data")))
+	assert.Equal(t, 0, owner.sent())
+	assert.Nil(t, owner.released)
+}
+
+func TestShouldReleaseChannelWhenOutputQueueIsFull(t *testing.T) {
+	owner := &fakeOwner{}
+	ch := newChannel(1, owner)
+	ch.window = 0
+
+	var err error
+	for i := 0; i <= outputQueueSize+1 && err == nil; i++ {
+		err = ch.Write([]byte("data"))
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, errOutputQueueIsFull, err)
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	assert.Equal(t, errOutputQueueIsFull, owner.released)
+}
+
+func TestShouldSendLastFrameAfterOutput(t *testing.T) {
+	owner := &fakeOwner{}
+	ch := newChannel(1, owner)
+	ch.window = 0
+
+	_ = ch.Write([]byte("data"))
+	_ = ch.finish(&Frame{Type: ControlFrame, Payload: []byte("{}")})
+	ch.grant(4)
+
+	select {
+	case <-ch.closed:
+	case <-time.After(time.Second):
+		t.Fatal("channel is not closed after the last frame")
+	}
+
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	assert.Len(t, owner.frames, 2)
+	assert.Equal(t, StdoutFrame, owner.frames[0].Type)
+	assert.Equal(t, ControlFrame, owner.frames[1].Type)
+}
+
+func TestShouldForwardQueuedInput(t *testing.T) {
+	ch := newChannel(1, &fakeOwner{})
+	received := make(chan []byte, 1)
+	go ch.forwardInput(func(data []byte) error {
+		received <- data
+		return nil
+	})
+
+	ch.queueInput([]byte("ls\n"))
+
+	select {
+	case data := <-received:
+		assert.Equal(t, "ls\n", string(data))
+	case <-time.After(time.Second):
+		t.Fatal("input is not forwarded")
+	}
+	_ = ch.Close()
+}
+
+func TestShouldReleaseChannelWhenInputQueueIsFull(t *testing.T) {
+	owner := &fakeOwner{}
+	ch := newChannel(1, owner)
+
+	var err error
+	for i := 0; i <= inputQueueSize && err == nil; i++ {
+		err = ch.queueInput([]byte("data"))
+	}
+
+	assert.Equal(t, errInputQueueIsFull, err)
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	assert.Equal(t, errInputQueueIsFull, owner.released)
+}
@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"github.com/eclipse-che/che-machine-exec/api/model"
+)
+
+// Control operations the client is able to request.
+const (
+	// Create new exec and attach channel to it.
+	CreateOp = "create"
+	// Attach channel to the existing exec.
+	AttachOp = "attach"
+	// Detach channel from exec, exec keeps running.
+	DetachOp = "detach"
+	// Resize exec terminal.
+	ResizeOp = "resize"
+	// Kill exec.
+	KillOp = "kill"
+)
+
+// ControlRequest is sent by the client in control frames.
+type ControlRequest struct {
+	// Request id chosen by the client, it is returned in the response.
+	ID int    `json:"id"`
+	Op string `json:"op"`
+
+	// Exec to create. Is used by create operation.
+	Exec *model.MachineExec `json:"exec,omitempty"`
+
+	// Channel to operate on. Is used by all operations except create.
+	Channel int `json:"channel,omitempty"`
+
+	// Offset of the exec output to replay from. Is used by attach operation.
+	ReplayFrom int64 `json:"replayFrom,omitempty"`
+	// Attach channel as viewer, which receives output and terminal size, but can't send input.
+	// Is used by attach operation. Channels of the session with read-only access are always attached as viewers.
+	Viewer bool `json:"viewer,omitempty"`
+
+	// New terminal size. Is used by resize operation.
+	Cols uint `json:"cols,omitempty"`
+	Rows uint `json:"rows,omitempty"`
+}
+
+// ControlResponse is sent by the server in control frames as the result of the control request.
+type ControlResponse struct {
+	ID      int    `json:"id"`
+	Channel int    `json:"channel,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Event sent when channel is detached by the server because it is not able to transfer output anymore
+// or because viewer access is revoked.
+const OnChannelDetached = "onChannelDetached"
+
+// Event sent to the viewer channels when exec terminal is resized.
+const OnTerminalResized = "onTerminalResized"
+
+// ControlEvent is sent by the server in control frames when exec or channel state is changed.
+type ControlEvent struct {
+	// One of model.OnExecExit, model.OnExecError, OnChannelDetached, OnTerminalResized
+	Event   string `json:"event"`
+	Channel int    `json:"channel"`
+	Stack   string `json:"stack,omitempty"`
+
+	// New terminal size. Is sent with OnTerminalResized event.
+	Cols uint `json:"cols,omitempty"`
+	Rows uint `json:"rows,omitempty"`
+}
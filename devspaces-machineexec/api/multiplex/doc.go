@@ -0,0 +1,36 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+// Package multiplex implements protocol to manage execs and transfer
+// their input/output over the single websocket connection.
+//
+// Every websocket message is a binary frame:
+//   - 1 byte frame type;
+//   - 4 bytes channel id, big endian. Channel id is the exec id, 0 for control frames;
+//   - payload.
+//
+// Frame types:
+//   - control: JSON encoded ControlRequest sent by the client,
+//     ControlResponse or ControlEvent sent by the server;
+//   - stdin: exec input sent by the client;
+//   - stdout, stderr: exec output sent by the server. Stderr frames are sent only
+//     for execs without tty, output replayed on attach is always sent as stdout;
+//   - window: 4 bytes big endian amount of bytes the client is ready to receive
+//     over the channel in addition to the previously granted ones.
+//
+// Server sends output over the channel only while the client has granted window for it.
+// Each channel starts with InitialWindow bytes granted. If the client does not grant
+// more window within FlowControlTimeout, the channel is detached. Output waiting for the window
+// is queued per channel, the channel is detached as well if the client does not keep up with it.
+// Input is queued per channel too, the channel is detached if the exec does not keep up with it.
@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Version of the multiplexed protocol.
+const Version = 1
+
+// FrameType defines how frame payload should be handled.
+type FrameType byte
+
+const (
+	ControlFrame FrameType = iota
+	StdinFrame
+	StdoutFrame
+	StderrFrame
+	WindowFrame
+)
+
+const headerSize = 5
+
+// Frame is a single message of the multiplexed protocol.
+type Frame struct {
+	Type    FrameType
+	Channel uint32
+	Payload []byte
+}
+
+// Marshal returns binary representation of the frame.
+func (frame *Frame) Marshal() []byte {
+	data := make([]byte, headerSize+len(frame.Payload))
+	data[0] = byte(frame.Type)
+	binary.BigEndian.PutUint32(data[1:headerSize], frame.Channel)
+	copy(data[headerSize:], frame.Payload)
+	return data
+}
+
+// ParseFrame parses frame from its binary representation.
+func ParseFrame(data []byte) (*Frame, error) {
+	if len(data) < headerSize {
+		return nil, errors.New("frame is too short")
+	}
+
+	frameType := FrameType(data[0])
+	if frameType > WindowFrame {
+		return nil, fmt.Errorf("unknown frame type %d", frameType)
+	}
+
+	return &Frame{
+		Type:    frameType,
+		Channel: binary.BigEndian.Uint32(data[1:headerSize]),
+		Payload: data[headerSize:],
+	}, nil
+}
+
+// NewWindowFrame creates frame which grants window of the given size for the channel.
+func NewWindowFrame(channel uint32, size uint32) *Frame {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, size)
+	return &Frame{Type: WindowFrame, Channel: channel, Payload: payload}
+}
+
+// WindowSize returns window size granted by the window frame.
+func (frame *Frame) WindowSize() (uint32, error) {
+	if frame.Type != WindowFrame || len(frame.Payload) != 4 {
+		return 0, errors.New("invalid window frame")
+	}
+	return binary.BigEndian.Uint32(frame.Payload), nil
+}
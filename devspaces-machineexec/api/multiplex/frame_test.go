@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldMarshalAndParseFrame(t *testing.T) {
+	frame := &Frame{Type: StdoutFrame, Channel: 258, Payload: []byte("output")}
+
+	data := frame.Marshal()
+	parsed, err := ParseFrame(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{byte(StdoutFrame), 0, 0, 1, 2}, data[:headerSize])
+	assert.Equal(t, frame, parsed)
+}
+
+func TestShouldNotParseTooShortFrame(t *testing.T) {
+	_, err := ParseFrame([]byte{byte(StdinFrame), 0, 0})
+
+	assert.NotNil(t, err)
+}
+
+func TestShouldNotParseFrameOfUnknownType(t *testing.T) {
+	_, err := ParseFrame([]byte{42, 0, 0, 0, 1})
+
+	assert.NotNil(t, err)
+}
+
+func TestShouldReadWindowSize(t *testing.T) {
+	parsed, _ := ParseFrame(NewWindowFrame(1, 65536).Marshal())
+
+	size, err := parsed.WindowSize()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(65536), size)
+}
@@ -0,0 +1,366 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/events"
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/eclipse/che-go-jsonrpc/event"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+var errSessionClosed = errors.New("session is closed")
+
+// Session serves multiplexed protocol over the single websocket connection.
+type Session struct {
+	conn    *websocket.Conn
+	token   string
+	client  ws_conn.Client
+	manager exec.ExecManager
+
+	writeLock *sync.Mutex
+
+	channelsLock *sync.Mutex
+	channels     map[uint32]*channel
+
+	closeOnce *sync.Once
+	closed    chan struct{}
+}
+
+// NewSession creates new session for the websocket connection.
+// Token is used to create execs if authentication is enabled.
+// Session of the client with read-only access is only able to attach to the execs as viewer and to detach from them.
+func NewSession(conn *websocket.Conn, token string, client ws_conn.Client, manager exec.ExecManager) *Session {
+	return &Session{
+		conn:         conn,
+		token:        token,
+		client:       client,
+		manager:      manager,
+		writeLock:    &sync.Mutex{},
+		channelsLock: &sync.Mutex{},
+		channels:     make(map[uint32]*channel),
+		closeOnce:    &sync.Once{},
+		closed:       make(chan struct{}),
+	}
+}
+
+// Serve handles frames received from the client until the connection is closed.
+// Execs attached to the session keep running after it is closed.
+func (s *Session) Serve() {
+	events.EventBus.SubAny(s, model.OnExecExit, model.OnExecError)
+	metrics.WebsocketConnected(metrics.MultiplexEndpoint)
+	defer metrics.WebsocketDisconnected(metrics.MultiplexEndpoint)
+	defer s.close()
+
+	go s.sendPingMessages()
+
+	s.conn.SetPongHandler(func(string) error { return s.conn.SetReadDeadline(time.Now().Add(ws_conn.PongWait)) })
+	for {
+		msgType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if ws_conn.IsPongTimeoutError(err) {
+				metrics.KeepAliveFailed(metrics.PongFailure)
+			}
+			if !ws_conn.IsClosedByClientError(err) {
+				logrus.Errorf("failed to read multiplexed connection message. Cause: %v", err)
+			}
+			return
+		}
+
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+
+		frame, err := ParseFrame(data)
+		if err != nil {
+			logrus.Debugf("Skipping invalid frame. Cause: %s", err.Error())
+			continue
+		}
+		s.handleFrame(frame)
+	}
+}
+
+// Accept sends exec events to the client if exec is attached to the session.
+func (s *Session) Accept(e event.E) {
+	var controlEvent *ControlEvent
+	switch execEvent := e.(type) {
+	case *model.ExecExitEvent:
+		controlEvent = &ControlEvent{Event: model.OnExecExit, Channel: execEvent.ExecId}
+	case *model.ExecErrorEvent:
+		controlEvent = &ControlEvent{Event: model.OnExecError, Channel: execEvent.ExecId, Stack: execEvent.Stack}
+	default:
+		return
+	}
+
+	ch := s.removeChannel(uint32(controlEvent.Channel))
+	if ch == nil {
+		return
+	}
+
+	payload, err := json.Marshal(controlEvent)
+	if err != nil {
+		ch.Close()
+		logrus.Errorf("Unable to send event to the multiplexed connection. Cause: %s", err.Error())
+		return
+	}
+	// event is sent after the output which is still queued in the channel
+	if err := ch.finish(&Frame{Type: ControlFrame, Payload: payload}); err != nil {
+		ch.Close()
+		if err := s.send(&Frame{Type: ControlFrame, Payload: payload}); err != nil && err != errSessionClosed {
+			logrus.Errorf("Unable to send event to the multiplexed connection. Cause: %s", err.Error())
+		}
+	}
+}
+
+func (s *Session) handleFrame(frame *Frame) {
+	switch frame.Type {
+	case ControlFrame:
+		request := &ControlRequest{}
+		if err := json.Unmarshal(frame.Payload, request); err != nil {
+			logrus.Debugf("Skipping invalid control frame. Cause: %s", err.Error())
+			return
+		}
+		// control operations might take a while, so they should not block channels
+		go s.handleControl(request)
+	case StdinFrame:
+		if err := ws_conn.AllowControl(s.client.Mode); err != nil {
+			logrus.Debugf("Dropping input of the channel %d. Cause: %s", frame.Channel, err.Error())
+			return
+		}
+		if ch := s.getChannel(frame.Channel); ch != nil && !ch.viewer {
+			if err := ch.queueInput(frame.Payload); err != nil {
+				logrus.Debugf("Dropping input of the channel %d. Cause: %s", frame.Channel, err.Error())
+			}
+		}
+	case WindowFrame:
+		size, err := frame.WindowSize()
+		if err != nil {
+			logrus.Debugf("Skipping invalid window frame. Cause: %s", err.Error())
+			return
+		}
+		if ch := s.getChannel(frame.Channel); ch != nil {
+			ch.grant(size)
+		}
+	default:
+		logrus.Debugf("Skipping frame of unexpected type %d", frame.Type)
+	}
+}
+
+func (s *Session) handleControl(request *ControlRequest) {
+	response := &ControlResponse{ID: request.ID, Channel: request.Channel}
+
+	var err error
+	switch request.Op {
+	case CreateOp:
+		if err = ws_conn.AllowControl(s.client.Mode); err == nil {
+			response.Channel, err = s.create(request.Exec)
+		}
+	case AttachOp:
+		err = s.attach(request.Channel, request.ReplayFrom, request.Viewer)
+	case DetachOp:
+		err = s.detach(request.Channel)
+	case ResizeOp:
+		if err = ws_conn.AllowControl(s.client.Mode); err == nil {
+			err = s.manager.Resize(request.Channel, request.Cols, request.Rows)
+		}
+	case KillOp:
+		if err = ws_conn.AllowControl(s.client.Mode); err == nil {
+			err = s.manager.Kill(request.Channel)
+		}
+	default:
+		err = fmt.Errorf("unknown operation '%s'", request.Op)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if err := s.sendControl(response); err != nil && err != errSessionClosed {
+		logrus.Errorf("Unable to send control response to the multiplexed connection. Cause: %s", err.Error())
+	}
+}
+
+func (s *Session) create(machineExec *model.MachineExec) (int, error) {
+	if machineExec == nil {
+		return -1, errors.New("exec to create is not specified")
+	}
+	if auth.IsEnabled() {
+		machineExec.BearerToken = s.token
+		machineExec.UserID = s.client.UserID
+	}
+
+	id, err := s.manager.Create(machineExec)
+	if err != nil {
+		logrus.Errorf("Unable to initialize terminal. Cause: %s", err.Error())
+		return -1, err
+	}
+
+	healthWatcher := exec.NewHealthWatcher(machineExec, events.EventBus, s.manager)
+	healthWatcher.CleanUpOnExitOrError()
+
+	return id, s.attach(id, 0, false)
+}
+
+func (s *Session) attach(id int, replayFrom int64, viewer bool) error {
+	if id <= 0 {
+		return errors.New("channel must be a positive exec id")
+	}
+
+	s.channelsLock.Lock()
+	if _, ok := s.channels[uint32(id)]; ok {
+		s.channelsLock.Unlock()
+		return fmt.Errorf("channel %d is already attached", id)
+	}
+	client := s.client
+	if viewer {
+		client.Mode = ws_conn.ReadOnlyAccess
+	}
+	ch := newChannel(uint32(id), s)
+	ch.viewer = client.Mode != ws_conn.FullAccess
+	s.channels[ch.id] = ch
+	s.channelsLock.Unlock()
+
+	if err := s.manager.AttachConnection(id, ch, replayFrom, client); err != nil {
+		s.removeChannel(ch.id)
+		ch.Close()
+		return err
+	}
+
+	if !ch.viewer {
+		go ch.forwardInput(func(data []byte) error {
+			return s.manager.Input(id, data)
+		})
+	}
+	return nil
+}
+
+func (s *Session) detach(id int) error {
+	ch := s.removeChannel(uint32(id))
+	if ch == nil {
+		return fmt.Errorf("channel %d is not attached", id)
+	}
+
+	ch.Close()
+	s.manager.DetachConnection(id, ch)
+	return nil
+}
+
+// release detaches channel which is not able to transfer output anymore.
+func (s *Session) release(ch *channel, cause error) {
+	if s.removeChannel(ch.id) != ch {
+		return
+	}
+	ch.Close()
+	logrus.Debugf("Channel %d is detached. Cause: %s", ch.id, cause.Error())
+
+	// connection handler removes channel from exec itself when write fails
+	if err := s.sendControl(&ControlEvent{Event: OnChannelDetached, Channel: int(ch.id), Stack: cause.Error()}); err != nil && err != errSessionClosed {
+		logrus.Errorf("Unable to send event to the multiplexed connection. Cause: %s", err.Error())
+	}
+}
+
+func (s *Session) getChannel(id uint32) *channel {
+	defer s.channelsLock.Unlock()
+	s.channelsLock.Lock()
+
+	return s.channels[id]
+}
+
+func (s *Session) removeChannel(id uint32) *channel {
+	defer s.channelsLock.Unlock()
+	s.channelsLock.Lock()
+
+	ch, ok := s.channels[id]
+	if !ok {
+		return nil
+	}
+	delete(s.channels, id)
+	return ch
+}
+
+func (s *Session) sendControl(message interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return s.send(&Frame{Type: ControlFrame, Payload: payload})
+}
+
+func (s *Session) send(frame *Frame) error {
+	defer s.writeLock.Unlock()
+	s.writeLock.Lock()
+
+	select {
+	case <-s.closed:
+		return errSessionClosed
+	default:
+	}
+
+	return s.conn.WriteMessage(websocket.BinaryMessage, frame.Marshal())
+}
+
+func (s *Session) sendPingMessages() {
+	ticker := time.NewTicker(ws_conn.PingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.writeLock.Lock()
+			err := s.conn.WriteMessage(websocket.PingMessage, []byte{})
+			s.writeLock.Unlock()
+			if err != nil {
+				metrics.KeepAliveFailed(metrics.PingFailure)
+				// stop sending ping messages if one failed
+				return
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// close detaches all the channels, execs keep running.
+func (s *Session) close() {
+	s.closeOnce.Do(func() {
+		events.EventBus.Rm(s)
+
+		s.writeLock.Lock()
+		close(s.closed)
+		s.writeLock.Unlock()
+
+		s.channelsLock.Lock()
+		channels := s.channels
+		s.channels = make(map[uint32]*channel)
+		s.channelsLock.Unlock()
+
+		for _, ch := range channels {
+			ch.Close()
+			s.manager.DetachConnection(int(ch.id), ch)
+		}
+
+		if err := s.conn.Close(); err != nil {
+			logrus.Debugf("Failed to close multiplexed connection. Cause: %s", err.Error())
+		}
+	})
+}
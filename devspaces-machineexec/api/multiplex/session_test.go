@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package multiplex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/mocks"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func serveSession(t *testing.T, mode ws_conn.AccessMode, manager *mocks.ExecManager) *websocket.Conn {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		NewSession(conn, "", ws_conn.Client{Mode: mode}, manager).Serve()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func requestControl(t *testing.T, conn *websocket.Conn, request *ControlRequest) *ControlResponse {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, (&Frame{Type: ControlFrame, Payload: payload}).Marshal()); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := ParseFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := &ControlResponse{}
+	if err := json.Unmarshal(frame.Payload, response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestShouldRejectControlOfReadOnlySession(t *testing.T) {
+	manager := &mocks.ExecManager{}
+	conn := serveSession(t, ws_conn.ReadOnlyAccess, manager)
+
+	for i, op := range []string{CreateOp, ResizeOp, KillOp} {
+		response := requestControl(t, conn, &ControlRequest{ID: i + 1, Op: op, Channel: 3})
+
+		assert.Equal(t, i+1, response.ID)
+		assert.Equal(t, ws_conn.ErrReadOnlyAccess.Error(), response.Error)
+	}
+	manager.AssertExpectations(t)
+}
+
+func TestShouldKillExecOfFullAccessSession(t *testing.T) {
+	manager := &mocks.ExecManager{}
+	manager.On("Kill", 3).Return(nil)
+	conn := serveSession(t, ws_conn.FullAccess, manager)
+
+	response := requestControl(t, conn, &ControlRequest{ID: 1, Op: KillOp, Channel: 3})
+
+	assert.Empty(t, response.Error)
+	manager.AssertExpectations(t)
+}
+
+func readControlEvent(t *testing.T, conn *websocket.Conn) *ControlEvent {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := ParseFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlEvent := &ControlEvent{}
+	if err := json.Unmarshal(frame.Payload, controlEvent); err != nil {
+		t.Fatal(err)
+	}
+	return controlEvent
+}
+
+func TestShouldNotBlockChannelsByExecWhichDoesNotReadInput(t *testing.T) {
+	stalled := make(chan struct{})
+	defer close(stalled)
+	received := make(chan []byte, 1)
+	manager := &mocks.ExecManager{}
+	manager.On("AttachConnection", mock.Anything, mock.Anything, int64(0), mock.Anything).Return(nil)
+	manager.On("DetachConnection", mock.Anything, mock.Anything).Return()
+	manager.On("Input", 1, mock.Anything).Run(func(mock.Arguments) { <-stalled }).Return(nil)
+	manager.On("Input", 2, mock.Anything).Run(func(args mock.Arguments) { received <- args.Get(1).([]byte) }).Return(nil)
+	conn := serveSession(t, ws_conn.FullAccess, manager)
+
+	for _, id := range []int{1, 2} {
+		response := requestControl(t, conn, &ControlRequest{ID: id, Op: AttachOp, Channel: id})
+		assert.Empty(t, response.Error)
+	}
+
+	// one frame is taken by the stalled input, the next ones fill the queue
+	for i := 0; i <= inputQueueSize+1; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, (&Frame{Type: StdinFrame, Channel: 1, Payload: []byte("data")}).Marshal()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, (&Frame{Type: StdinFrame, Channel: 2, Payload: []byte("ls\n")}).Marshal()); err != nil {
+		t.Fatal(err)
+	}
+
+	controlEvent := readControlEvent(t, conn)
+	assert.Equal(t, OnChannelDetached, controlEvent.Event)
+	assert.Equal(t, 1, controlEvent.Channel)
+	assert.Equal(t, errInputQueueIsFull.Error(), controlEvent.Stack)
+	select {
+	case data := <-received:
+		assert.Equal(t, "ls\n", string(data))
+	case <-time.After(time.Second):
+		t.Fatal("input of the other channel is blocked")
+	}
+}
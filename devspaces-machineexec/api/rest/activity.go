@@ -14,9 +14,13 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/eclipse-che/che-machine-exec/api/model"
 	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/cfg"
 	restUtil "github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	"github.com/eclipse-che/che-machine-exec/timeout"
 	"github.com/gin-gonic/gin"
 )
@@ -34,3 +38,52 @@ func HandleActivityTick(c *gin.Context, manager timeout.InactivityIdleManager) {
 	c.Writer.WriteHeader(http.StatusNoContent)
 	return
 }
+
+func HandleStopPostpone(c *gin.Context, activityManager timeout.InactivityIdleManager, runtimeManager timeout.RunIdleManager) {
+	if auth.IsEnabled() {
+		_, err := auth.Authenticate(c)
+		if err != nil {
+			restUtil.WriteErrorResponse(c, err)
+			return
+		}
+	}
+
+	var params model.PostponeStopParams
+	if c.BindJSON(&params) != nil {
+		restUtil.WriteResponse(c, http.StatusBadRequest, "Failed to convert body args into internal structure")
+		return
+	}
+
+	var duration time.Duration
+	if params.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(params.Duration)
+		if err != nil || duration <= 0 {
+			restUtil.WriteResponse(c, http.StatusBadRequest, "Duration must be positive, for example 15m")
+			return
+		}
+		if cfg.MaxStopPostpone > 0 && duration > cfg.MaxStopPostpone {
+			restUtil.WriteResponse(c, http.StatusBadRequest, "Workspace stop can't be postponed for more than "+cfg.MaxStopPostpone.String())
+			return
+		}
+	}
+
+	var postpone func(time.Duration) (time.Time, error)
+	switch params.Timeout {
+	case metrics.IdleTimeout:
+		postpone = activityManager.Postpone
+	case metrics.RunTimeout:
+		postpone = runtimeManager.Postpone
+	default:
+		restUtil.WriteResponse(c, http.StatusBadRequest, "Timeout must be one of: "+metrics.IdleTimeout+", "+metrics.RunTimeout)
+		return
+	}
+
+	stopAt, err := postpone(duration)
+	if err != nil {
+		restUtil.WriteResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.PostponeStopResult{Timeout: params.Timeout, StopAt: stopAt})
+}
@@ -14,6 +14,7 @@ package rest
 
 import (
 	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/exec"
 )
 
 func HandleKubeConfigCreation(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error {
@@ -21,6 +22,6 @@ func HandleKubeConfigCreation(kubeConfigParams *model.KubeConfigParams, containe
 		kubeConfigParams.Username = "Developer"
 	}
 
-	err := execManager.CreateKubeConfig(kubeConfigParams, containerInfo)
+	err := exec.GetExecManager().CreateKubeConfig(kubeConfigParams, containerInfo)
 	return err
 }
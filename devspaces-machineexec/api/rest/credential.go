@@ -0,0 +1,34 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/kubeconfig"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleCredential returns the current token of the user to the credential plugin of the kubeconfig.
+// Plugin is authenticated with the key it was created with instead of the bearer token,
+// since it is used to get the token.
+func HandleCredential(c *gin.Context) {
+	credential, err := kubeconfig.GetCredential(c.GetHeader(kubeconfig.CredentialKeyHeader))
+	if err != nil {
+		rest.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
@@ -26,19 +26,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	execManager = exec.GetExecManager()
-)
-
 func HandleInit(c *gin.Context) {
-	var token string
+	var token, userID string
 	if auth.IsEnabled() {
-		var err error
-		token, err = auth.Authenticate(c)
+		user, err := auth.AuthenticateFullAccess(c)
 		if err != nil {
 			rest.WriteErrorResponse(c, err)
 			return
 		}
+		token, userID = user.Token, user.ID
 	}
 
 	var initConfigParams model.InitConfigParams
@@ -49,6 +45,7 @@ func HandleInit(c *gin.Context) {
 
 	kubeConfigParams := initConfigParams.KubeConfigParams
 	kubeConfigParams.BearerToken = token
+	kubeConfigParams.UserID = userID
 
 	execRequest := handleContainerResolve(c, token, initConfigParams.ContainerName)
 	if execRequest == nil {
@@ -75,7 +72,7 @@ func HandleInit(c *gin.Context) {
 }
 
 func handleContainerResolve(c *gin.Context, token, container string) *model.ResolvedExec {
-	resolvedExec, err := execManager.Resolve(container, token)
+	resolvedExec, err := exec.GetExecManager().Resolve(container, token)
 
 	if err != nil {
 		rest.WriteResponse(c, http.StatusInternalServerError, fmt.Sprintf("Unable to resolve exec. Cause: %s", err.Error()))
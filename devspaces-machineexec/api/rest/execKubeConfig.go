@@ -24,14 +24,14 @@ import (
 )
 
 func HandleKubeConfig(c *gin.Context) {
-	var token string
+	var token, userID string
 	if auth.IsEnabled() {
-		var err error
-		token, err = auth.Authenticate(c)
+		user, err := auth.AuthenticateFullAccess(c)
 		if err != nil {
 			rest.WriteErrorResponse(c, err)
 			return
 		}
+		token, userID = user.Token, user.ID
 	}
 
 	var initConfigParams model.InitConfigParams
@@ -42,6 +42,7 @@ func HandleKubeConfig(c *gin.Context) {
 
 	kubeConfigParams := initConfigParams.KubeConfigParams
 	kubeConfigParams.BearerToken = token
+	kubeConfigParams.UserID = userID
 
 	if initConfigParams.ContainerName == "" {
 		c.Writer.WriteHeader(http.StatusBadRequest)
@@ -0,0 +1,110 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/transfer"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HandleDownloadArchive streams tar archive of the file or directory at the path query parameter
+// in the container query parameter. The first available container is used if container is omitted.
+func HandleDownloadArchive(c *gin.Context) {
+	token, ok := authenticateFilesAccess(c)
+	if !ok {
+		return
+	}
+
+	filePath := c.Query("path")
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(filePath)+".tar"))
+	err := transfer.DownloadArchive(exec.GetExecManager(), token, c.Query("container"), filePath, c.Writer)
+	writeTransferResult(c, err)
+}
+
+// HandleUploadArchive extracts tar archive from the request body into the directory at the path query parameter.
+func HandleUploadArchive(c *gin.Context) {
+	token, ok := authenticateFilesAccess(c)
+	if !ok {
+		return
+	}
+
+	err := transfer.UploadArchive(exec.GetExecManager(), token, c.Query("container"), c.Query("path"), c.Request.Body, c.Request.ContentLength)
+	writeTransferResult(c, err)
+}
+
+// HandleDownloadFile streams content of the regular file at the path query parameter.
+func HandleDownloadFile(c *gin.Context) {
+	token, ok := authenticateFilesAccess(c)
+	if !ok {
+		return
+	}
+
+	filePath := c.Query("path")
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(filePath)))
+	err := transfer.DownloadFile(exec.GetExecManager(), token, c.Query("container"), filePath, c.Writer)
+	writeTransferResult(c, err)
+}
+
+// HandleUploadFile writes the request body into the file at the path query parameter.
+// Content-Length of the request is required.
+func HandleUploadFile(c *gin.Context) {
+	token, ok := authenticateFilesAccess(c)
+	if !ok {
+		return
+	}
+
+	err := transfer.UploadFile(exec.GetExecManager(), token, c.Query("container"), c.Query("path"), c.Request.Body, c.Request.ContentLength)
+	writeTransferResult(c, err)
+}
+
+// writeTransferResult responds with error if response is not started yet.
+// Otherwise download is interrupted and client gets incomplete content.
+func writeTransferResult(c *gin.Context, err error) {
+	if err == nil {
+		if !c.Writer.Written() {
+			c.Status(http.StatusNoContent)
+		}
+		return
+	}
+
+	logrus.Debugf("File transfer is failed. Cause: %s", err.Error())
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Disposition")
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	rest.WriteErrorResponse(c, err)
+}
+
+func authenticateFilesAccess(c *gin.Context) (token string, ok bool) {
+	if auth.IsEnabled() {
+		var err error
+		token, err = auth.Authenticate(c)
+		if err != nil {
+			rest.WriteErrorResponse(c, err)
+			return "", false
+		}
+	}
+	return token, true
+}
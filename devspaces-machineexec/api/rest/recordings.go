@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package rest
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/output/recording"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListRecordings lists the recordings of the execs created by the authenticated user.
+func HandleListRecordings(c *gin.Context) {
+	userID, ok := authenticateRecordingsAccess(c)
+	if !ok {
+		return
+	}
+
+	recordings, err := recording.List(cfg.RecordingsPath)
+	if err != nil {
+		rest.WriteErrorResponse(c, err)
+		return
+	}
+
+	if auth.IsEnabled() {
+		owned := make([]recording.Info, 0, len(recordings))
+		for _, info := range recordings {
+			if userID != "" && info.User == userID {
+				owned = append(owned, info)
+			}
+		}
+		recordings = owned
+	}
+	c.JSON(http.StatusOK, recordings)
+}
+
+// HandleDownloadRecording sends the recording of the exec created by the authenticated user.
+func HandleDownloadRecording(c *gin.Context) {
+	userID, ok := authenticateRecordingsAccess(c)
+	if !ok {
+		return
+	}
+
+	name := c.Param("name")
+	path, err := recording.Path(cfg.RecordingsPath, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			rest.WriteErrorResponse(c, rest.NewError(http.StatusNotFound, "Recording '"+name+"' was not found"))
+		} else {
+			rest.WriteErrorResponse(c, rest.NewError(http.StatusBadRequest, err.Error()))
+		}
+		return
+	}
+
+	if auth.IsEnabled() {
+		// recordings of the other users are not revealed
+		if header, err := recording.ReadHeader(path); err != nil || userID == "" || header.User != userID {
+			rest.WriteErrorResponse(c, rest.NewError(http.StatusNotFound, "Recording '"+name+"' was not found"))
+			return
+		}
+	}
+
+	c.FileAttachment(path, name)
+}
+
+// authenticateRecordingsAccess returns ID of the authenticated user, it is empty if authentication is disabled.
+func authenticateRecordingsAccess(c *gin.Context) (string, bool) {
+	var userID string
+	if auth.IsEnabled() {
+		user, err := auth.AuthenticateFullAccess(c)
+		if err != nil {
+			rest.WriteErrorResponse(c, err)
+			return "", false
+		}
+		userID = user.ID
+	}
+
+	if cfg.RecordingsPath == "" {
+		rest.WriteErrorResponse(c, rest.NewError(http.StatusNotFound, "Exec sessions recording is disabled"))
+		return "", false
+	}
+	return userID, true
+}
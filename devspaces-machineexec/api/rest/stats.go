@@ -0,0 +1,33 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/gin-gonic/gin"
+)
+
+func HandleExecStats(c *gin.Context) {
+	if auth.IsEnabled() {
+		if _, err := auth.Authenticate(c); err != nil {
+			rest.WriteErrorResponse(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, exec.GetExecManager().Stats())
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/eclipse-che/che-machine-exec/auth"
 	"github.com/eclipse-che/che-machine-exec/common/rest"
 	"github.com/eclipse-che/che-machine-exec/exec"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
@@ -33,33 +34,49 @@ var (
 	}
 )
 
+// HandleAttach attaches websocket connection to the exec. Users with read-only access
+// and users who request viewer=true are attached as viewers: they receive exec output, but their input is dropped.
 func HandleAttach(c *gin.Context) {
+	client := ws_conn.Client{Mode: ws_conn.FullAccess}
 	if auth.IsEnabled() {
-		_, err := auth.Authenticate(c)
+		user, err := auth.AuthenticateUser(c)
 		if err != nil {
 			rest.WriteErrorResponse(c, err)
 			return
 		}
+		client = ws_conn.Client{User: user.Name, UserID: user.ID, Mode: user.Mode}
+	}
+	if c.Query("viewer") == "true" {
+		client.Mode = ws_conn.ReadOnlyAccess
 	}
 
-	if err := doAttach(c.Writer, c.Request, c.Param("id")); err != nil {
+	if err := doAttach(c.Writer, c.Request, c.Param("id"), c.Query("replayFrom"), client); err != nil {
 		c.JSON(c.Writer.Status(), err.Error())
 	}
 }
 
-func doAttach(w http.ResponseWriter, r *http.Request, idParam string) error {
+func doAttach(w http.ResponseWriter, r *http.Request, idParam string, replayFromParam string, client ws_conn.Client) error {
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
 		return errors.New("failed to parse id")
 	}
 
+	// by default all the retained output is replayed
+	var replayFrom int64
+	if replayFromParam != "" {
+		replayFrom, err = strconv.ParseInt(replayFromParam, 10, 64)
+		if err != nil || replayFrom < 0 {
+			return errors.New("failed to parse replayFrom offset")
+		}
+	}
+
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logrus.Errorln("Unable to upgrade connection to ws-conn " + err.Error())
 		return err
 	}
 
-	if err = exec.GetExecManager().Attach(id, wsConn); err != nil {
+	if err = exec.GetExecManager().Attach(id, wsConn, replayFrom, client); err != nil {
 		logrus.Errorln("Attach to exec", strconv.Itoa(id), " failed. Cause:  ", err.Error())
 		return err
 	}
@@ -13,11 +13,14 @@
 package websocket
 
 import (
+	"sync"
+
 	"github.com/eclipse-che/che-machine-exec/api/events"
 	execRpc "github.com/eclipse-che/che-machine-exec/api/jsonrpc"
 	"github.com/eclipse-che/che-machine-exec/api/model"
 	"github.com/eclipse-che/che-machine-exec/auth"
 	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	jsonrpc "github.com/eclipse/che-go-jsonrpc"
 	"github.com/eclipse/che-go-jsonrpc/jsonrpcws"
 	"github.com/gin-gonic/gin"
@@ -25,14 +28,15 @@ import (
 )
 
 func HandleConnect(c *gin.Context) {
-	var token string
+	var token, userID string
+	owner := false
 	if auth.IsEnabled() {
-		var err error
-		token, err = auth.Authenticate(c)
+		user, err := auth.AuthenticateFullAccess(c)
 		if err != nil {
 			rest.WriteErrorResponse(c, err)
 			return
 		}
+		token, userID, owner = user.Token, user.ID, user.Owner
 	}
 
 	conn, err := jsonrpcws.Upgrade(c.Writer, c.Request)
@@ -42,14 +46,34 @@ func HandleConnect(c *gin.Context) {
 	}
 
 	logrus.Debug("Create json-rpc channel for new websocket connection")
-	tunnel := jsonrpc.NewManagedTunnel(conn)
+	metrics.WebsocketConnected(metrics.ConnectEndpoint)
+	tunnel := jsonrpc.NewManagedTunnel(&countedConn{NativeConn: conn, closeOnce: &sync.Once{}})
 
 	if len(token) > 0 {
 		tunnel.Attributes[execRpc.BearerTokenAttr] = token
 	}
+	if len(userID) > 0 {
+		tunnel.Attributes[execRpc.UserIDAttr] = userID
+	}
+	if owner {
+		tunnel.Attributes[execRpc.OwnerAttr] = "true"
+	}
 
 	execConsumer := &events.ExecEventConsumer{Tunnel: tunnel}
-	events.EventBus.SubAny(execConsumer, model.OnExecError, model.OnExecExit)
+	events.EventBus.SubAny(execConsumer, model.OnExecError, model.OnExecExit, model.OnWorkspaceStopWarning, model.OnFileTransferProgress)
 
 	tunnel.SayHello()
 }
+
+// countedConn counts closed json-rpc connection in metrics.
+type countedConn struct {
+	jsonrpc.NativeConn
+	closeOnce *sync.Once
+}
+
+func (conn *countedConn) Close() error {
+	conn.closeOnce.Do(func() {
+		metrics.WebsocketDisconnected(metrics.ConnectEndpoint)
+	})
+	return conn.NativeConn.Close()
+}
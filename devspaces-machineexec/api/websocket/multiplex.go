@@ -0,0 +1,47 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package websocket
+
+import (
+	"github.com/eclipse-che/che-machine-exec/api/multiplex"
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HandleMultiplex serves multiplexed protocol. Users with read-only access are only able
+// to attach to the execs as viewers and to detach from them.
+func HandleMultiplex(c *gin.Context) {
+	var token string
+	client := ws_conn.Client{Mode: ws_conn.FullAccess}
+	if auth.IsEnabled() {
+		user, err := auth.AuthenticateUser(c)
+		if err != nil {
+			rest.WriteErrorResponse(c, err)
+			return
+		}
+		token, client = user.Token, ws_conn.Client{User: user.Name, UserID: user.ID, Mode: user.Mode}
+	}
+
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logrus.Errorln("Unable to upgrade connection to ws-conn " + err.Error())
+		c.JSON(c.Writer.Status(), err.Error())
+		return
+	}
+
+	multiplex.NewSession(wsConn, token, client, exec.GetExecManager()).Serve()
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package websocket
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/auth"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// user which is reported in the audit log when authentication is disabled
+	anonymousUser = "anonymous"
+	// max length of the close message reason allowed by websocket protocol
+	maxCloseReasonLength = 123
+	// time to send close message to the client
+	closeTimeout = time.Second
+)
+
+// HandlePortForward tunnels binary websocket messages to the port of the workspace container and back.
+// Container is optional, the first available one is used if it is omitted.
+func HandlePortForward(c *gin.Context) {
+	var token string
+	user := anonymousUser
+	if auth.IsEnabled() {
+		authenticated, err := auth.AuthenticateUser(c)
+		if err != nil {
+			rest.WriteErrorResponse(c, err)
+			return
+		}
+		if err := ws_conn.AllowControl(authenticated.Mode); err != nil {
+			rest.WriteErrorResponse(c, rest.NewError(http.StatusForbidden, err.Error()))
+			return
+		}
+		token, user = authenticated.Token, authenticated.Name+" ("+authenticated.ID+")"
+	}
+
+	container := c.Query("container")
+	port, err := strconv.Atoi(c.Query("port"))
+	if err != nil || port < 1 || port > 65535 {
+		rest.WriteErrorResponse(c, rest.NewError(http.StatusBadRequest, "port must be a number from 1 to 65535"))
+		return
+	}
+
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logrus.Errorln("Unable to upgrade connection to ws-conn " + err.Error())
+		c.JSON(c.Writer.Status(), err.Error())
+		return
+	}
+	defer wsConn.Close()
+
+	metrics.WebsocketConnected(metrics.PortForwardEndpoint)
+	defer metrics.WebsocketDisconnected(metrics.PortForwardEndpoint)
+
+	logrus.Infof("Port forward to port %d of container '%s' is opened by user %s", port, container, user)
+	stream := &wsStream{conn: wsConn}
+	err = exec.GetExecManager().PortForward(token, container, port, stream)
+	logrus.Infof("Port forward to port %d of container '%s' by user %s is closed. Received %d bytes, sent %d bytes",
+		port, container, user, atomic.LoadInt64(&stream.received), atomic.LoadInt64(&stream.sent))
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err != nil {
+		logrus.Errorf("Port forward to port %d of container '%s' failed. Cause: %s", port, container, err.Error())
+		reason := err.Error()
+		if len(reason) > maxCloseReasonLength {
+			reason = reason[:maxCloseReasonLength]
+		}
+		closeMessage = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, reason)
+	}
+	_ = wsConn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(closeTimeout))
+}
+
+// wsStream reads and writes bytes as binary websocket messages.
+// Normal closure of the connection by the client is the end of the stream.
+type wsStream struct {
+	conn   *websocket.Conn
+	reader io.Reader
+
+	received int64
+	sent     int64
+}
+
+func (stream *wsStream) Read(p []byte) (int, error) {
+	for {
+		if stream.reader == nil {
+			msgType, reader, err := stream.conn.NextReader()
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return 0, io.EOF
+			}
+			if err != nil {
+				return 0, err
+			}
+			if msgType != websocket.BinaryMessage {
+				continue
+			}
+			stream.reader = reader
+		}
+
+		n, err := stream.reader.Read(p)
+		atomic.AddInt64(&stream.received, int64(n))
+		if err == io.EOF {
+			stream.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (stream *wsStream) Write(p []byte) (int, error) {
+	if err := stream.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	atomic.AddInt64(&stream.sent, int64(len(p)))
+	return len(p), nil
+}
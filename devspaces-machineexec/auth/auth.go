@@ -17,6 +17,7 @@ import (
 
 	"github.com/eclipse-che/che-machine-exec/cfg"
 	restUtil "github.com/eclipse-che/che-machine-exec/common/rest"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -25,21 +26,66 @@ func IsEnabled() bool {
 	return cfg.UseBearerToken
 }
 
+// User is the authenticated user and the access the user is granted.
+type User struct {
+	Token string
+	ID    string
+	Name  string
+	Mode  ws_conn.AccessMode
+	// Owner tells whether the user is the workspace owner, see IsOwner.
+	Owner bool
+}
+
+// IsOwner tells whether the user is the workspace owner: the user with the authenticated user ID
+// or, with the kubernetes authenticator, the user allowed to exec into the workspace pod.
+func IsOwner(userID string, canExec bool) bool {
+	return (userID != "" && userID == cfg.AuthenticatedUserID) || canExec
+}
+
+// Authenticate checks that the user the token belongs to has full access and returns the token.
 func Authenticate(c *gin.Context) (string, error) {
-	token, err := extractToken(c)
+	user, err := AuthenticateFullAccess(c)
 	if err != nil {
 		return "", err
 	}
+	return user.Token, nil
+}
+
+// AuthenticateFullAccess checks that the user the token belongs to has full access and returns the user.
+func AuthenticateFullAccess(c *gin.Context) (*User, error) {
+	user, err := AuthenticateUser(c)
+	if err != nil {
+		return nil, err
+	}
 
-	userID, err := getCurrentUserID(token)
+	if user.Mode != ws_conn.FullAccess {
+		return nil, restUtil.NewError(http.StatusForbidden, "the current user has read-only access to API")
+	}
+
+	return user, nil
+}
+
+// AuthenticateUser checks that the user the token belongs to is the workspace owner,
+// is allowed to exec into the workspace pod or is granted access by the access policy. See AccessMode.
+func AuthenticateUser(c *gin.Context) (*User, error) {
+	token, err := extractToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := currentAuthenticator.getUser(token)
+	if err == errNotAuthenticated {
+		return nil, restUtil.NewError(http.StatusUnauthorized, "the token is not valid")
+	}
 	if err != nil {
 		logrus.Error("Failed to verify user. Cause: ", err.Error())
-		return "", restUtil.NewError(http.StatusInternalServerError, "unable to verify user with provided token")
+		return nil, restUtil.NewError(http.StatusInternalServerError, "unable to verify user with provided token")
 	}
 
-	if userID != cfg.AuthenticatedUserID {
-		return "", restUtil.NewError(http.StatusForbidden, "the current user is not authorized to use API")
+	mode := accessModeOf(user)
+	if mode == "" {
+		return nil, restUtil.NewError(http.StatusForbidden, "the current user is not authorized to use API")
 	}
 
-	return token, nil
+	return &User{Token: token, ID: user.ID, Name: user.Name, Mode: mode, Owner: IsOwner(user.ID, user.CanExec)}, nil
 }
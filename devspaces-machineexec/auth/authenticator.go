@@ -0,0 +1,158 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sRest "k8s.io/client-go/rest"
+)
+
+// errNotAuthenticated is returned when the token is rejected by the cluster.
+var errNotAuthenticated = errors.New("token is not authenticated")
+
+// period the name of the workspace pod is reused for the reviews, pod is replaced when workspace is restarted
+const podNameTTL = 30 * time.Second
+
+// authenticator identifies the user the token belongs to.
+type authenticator interface {
+	getUser(token string) (*userInfo, error)
+}
+
+var currentAuthenticator authenticator = &openShiftAuthenticator{}
+
+// UseKubernetesAuthenticator makes users to be identified with TokenReview instead of the OpenShift users API.
+// See cfg.KubernetesAuthenticator. Namespace is the namespace of the workspace pod.
+func UseKubernetesAuthenticator(namespace string) error {
+	config, err := k8sRest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	currentAuthenticator = newKubernetesAuthenticator(client, namespace)
+	return nil
+}
+
+// openShiftAuthenticator identifies users with the OpenShift users API on behalf of the token owner.
+type openShiftAuthenticator struct{}
+
+func (*openShiftAuthenticator) getUser(token string) (*userInfo, error) {
+	return getCurrentUser(token)
+}
+
+// kubernetesAuthenticator identifies users with TokenReview and checks with SubjectAccessReview
+// whether they are allowed to exec into the workspace pod. Both reviews are created with
+// the service account of the machine exec, which needs the permissions of system:auth-delegator role.
+type kubernetesAuthenticator struct {
+	client    kubernetes.Interface
+	namespace string
+
+	// name of the running workspace pod, it is resolved again once podNameTTL passes
+	podMutex      sync.Mutex
+	podName       string
+	podResolvedAt time.Time
+	podNameTTL    time.Duration
+}
+
+func newKubernetesAuthenticator(client kubernetes.Interface, namespace string) *kubernetesAuthenticator {
+	return &kubernetesAuthenticator{client: client, namespace: namespace, podNameTTL: podNameTTL}
+}
+
+func (a *kubernetesAuthenticator) getUser(token string) (*userInfo, error) {
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(context.TODO(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.New("Failed to review the token. Cause: " + err.Error())
+	}
+	if !review.Status.Authenticated {
+		return nil, errNotAuthenticated
+	}
+
+	user := review.Status.User
+	canExec, err := a.canExec(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userInfo{ID: user.UID, Name: user.Username, Groups: user.Groups, CanExec: canExec}, nil
+}
+
+// canExec checks whether the user is allowed to exec into the workspace pod.
+func (a *kubernetesAuthenticator) canExec(user authenticationv1.UserInfo) (bool, error) {
+	podName, err := a.getPodName()
+	if err != nil {
+		return false, err
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   a.namespace,
+				Verb:        "create",
+				Resource:    "pods",
+				Subresource: "exec",
+				Name:        podName,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, errors.New("Failed to review access of the user to the workspace pod. Cause: " + err.Error())
+	}
+	return review.Status.Allowed, nil
+}
+
+// getPodName returns the name of the running workspace pod. Name is reused for podNameTTL,
+// so that the review is not made against the pod of the previous workspace start for long.
+func (a *kubernetesAuthenticator) getPodName() (string, error) {
+	a.podMutex.Lock()
+	defer a.podMutex.Unlock()
+
+	if a.podName != "" && time.Since(a.podResolvedAt) < a.podNameTTL {
+		return a.podName, nil
+	}
+
+	pods, err := a.client.CoreV1().Pods(a.namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: cfg.PodSelector,
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return "", errors.New("Failed to find the workspace pod. Cause: " + err.Error())
+	}
+	if len(pods.Items) == 0 {
+		return "", errors.New("Running workspace pod is not found by selector " + cfg.PodSelector)
+	}
+
+	a.podName = pods.Items[0].Name
+	a.podResolvedAt = time.Now()
+	return a.podName, nil
+}
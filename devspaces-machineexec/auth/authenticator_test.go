@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sRest "k8s.io/client-go/rest"
+)
+
+// name of the running workspace pod served by the review server
+var workspacePodName = "workspace-pod"
+
+// newReviewServer serves token and access reviews, tokens are the names of the users
+// and only alice is allowed to exec into the workspace pod.
+func newReviewServer(t *testing.T) *kubernetesAuthenticator {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/user-che/pods":
+			assert.Equal(t, "controller.devfile.io/devworkspace_id=workspace", r.URL.Query().Get("labelSelector"))
+			assert.Equal(t, "status.phase=Running", r.URL.Query().Get("fieldSelector"))
+			response = &corev1.PodList{Items: []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: workspacePodName}}}}
+		case "/apis/authentication.k8s.io/v1/tokenreviews":
+			review := &authenticationv1.TokenReview{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(review))
+			if review.Spec.Token != "" && review.Spec.Token != "expired" {
+				review.Status.Authenticated = true
+				review.Status.User = authenticationv1.UserInfo{
+					Username: review.Spec.Token, UID: review.Spec.Token + "-uid", Groups: []string{"developers"},
+				}
+			}
+			response = review
+		case "/apis/authorization.k8s.io/v1/subjectaccessreviews":
+			review := &authorizationv1.SubjectAccessReview{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(review))
+			assert.Equal(t, authorizationv1.ResourceAttributes{
+				Namespace: "user-che", Verb: "create", Resource: "pods", Subresource: "exec", Name: workspacePodName,
+			}, *review.Spec.ResourceAttributes)
+			review.Status.Allowed = review.Spec.User == "alice"
+			response = review
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.Nil(t, json.NewEncoder(w).Encode(response))
+	}))
+	t.Cleanup(server.Close)
+
+	previousSelector := cfg.PodSelector
+	cfg.PodSelector = "controller.devfile.io/devworkspace_id=workspace"
+	t.Cleanup(func() {
+		cfg.PodSelector = previousSelector
+	})
+
+	client, err := kubernetes.NewForConfig(&k8sRest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newKubernetesAuthenticator(client, "user-che")
+}
+
+func TestShouldGrantFullAccessToUserAllowedToExec(t *testing.T) {
+	setUpPolicies(t)
+	authenticator := newReviewServer(t)
+
+	user, err := authenticator.getUser("alice")
+
+	assert.Nil(t, err)
+	assert.Equal(t, &userInfo{ID: "alice-uid", Name: "alice", Groups: []string{"developers"}, CanExec: true}, user)
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(user))
+}
+
+func TestShouldGrantAccessByPolicyToUserNotAllowedToExec(t *testing.T) {
+	setUpPolicies(t)
+	authenticator := newReviewServer(t)
+
+	user, err := authenticator.getUser("bob")
+	assert.Nil(t, err)
+	assert.False(t, user.CanExec)
+	assert.Equal(t, ws_conn.AccessMode(""), accessModeOf(user))
+
+	policies.apply("test", []byte(policy))
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(user))
+}
+
+func TestShouldRejectNotAuthenticatedToken(t *testing.T) {
+	authenticator := newReviewServer(t)
+
+	_, err := authenticator.getUser("expired")
+
+	assert.Equal(t, errNotAuthenticated, err)
+}
+
+func TestShouldNotTreatUserWithoutIDAsOwner(t *testing.T) {
+	setUpPolicies(t)
+	cfg.AuthenticatedUserID = ""
+
+	assert.Equal(t, ws_conn.AccessMode(""), accessModeOf(&userInfo{Name: "system:anonymous"}))
+}
+
+func TestShouldResolveWorkspacePodAgainAfterTTL(t *testing.T) {
+	authenticator := newReviewServer(t)
+	t.Cleanup(func() { workspacePodName = "workspace-pod" })
+
+	podName, err := authenticator.getPodName()
+	assert.Nil(t, err)
+	assert.Equal(t, "workspace-pod", podName)
+
+	// workspace is restarted with the new pod
+	workspacePodName = "restarted-workspace-pod"
+	podName, err = authenticator.getPodName()
+	assert.Nil(t, err)
+	assert.Equal(t, "workspace-pod", podName)
+
+	authenticator.podNameTTL = 0
+	podName, err = authenticator.getPodName()
+	assert.Nil(t, err)
+	assert.Equal(t, "restarted-workspace-pod", podName)
+}
+
+func TestShouldTreatUserAllowedToExecAsOwnerWithoutAuthenticatedUserID(t *testing.T) {
+	setUpPolicies(t)
+	cfg.AuthenticatedUserID = ""
+	policies.apply("test", []byte(policy))
+	previousAuthenticator := currentAuthenticator
+	currentAuthenticator = newReviewServer(t)
+	t.Cleanup(func() { currentAuthenticator = previousAuthenticator })
+
+	authenticate := func(token string) *User {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/connect", nil)
+		c.Request.Header.Set(AccessTokenHeader, token)
+		user, err := AuthenticateUser(c)
+		assert.Nil(t, err)
+		return user
+	}
+
+	assert.True(t, authenticate("alice").Owner)
+	bob := authenticate("bob")
+	assert.False(t, bob.Owner)
+	assert.Equal(t, ws_conn.ReadOnlyAccess, bob.Mode)
+}
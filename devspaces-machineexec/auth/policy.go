@@ -0,0 +1,230 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	k8sRest "k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// AccessPolicyAnnotation is the annotation of the DevWorkspace the access policy is read from.
+const AccessPolicyAnnotation = "che.eclipse.org/access-policy"
+
+const (
+	// period to check whether access policy file is changed
+	accessPolicyPollPeriod = 10 * time.Second
+	// period to retry DevWorkspace watch after failure
+	accessPolicyRetryPeriod = 10 * time.Second
+
+	// source of the access policy from DevWorkspace annotation
+	annotationSource = "annotation"
+)
+
+var DevWorkspaceResource = schema.GroupVersionResource{
+	Group:    "workspace.devfile.io",
+	Version:  "v1alpha2",
+	Resource: "devworkspaces",
+}
+
+// AccessPolicy grants access to the workspace to the users and groups besides the owner,
+// who always has full access. Users are identified either by name or by UID.
+// Example:
+//
+//	users:
+//	  alice: full
+//	  bob: read-only
+//	groups:
+//	  team-leads: read-only
+type AccessPolicy struct {
+	Users  map[string]ws_conn.AccessMode `json:"users,omitempty"`
+	Groups map[string]ws_conn.AccessMode `json:"groups,omitempty"`
+}
+
+// ParseAccessPolicy parses access policy in YAML or JSON format.
+func ParseAccessPolicy(data []byte) (*AccessPolicy, error) {
+	policy := &AccessPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, err
+	}
+
+	for user, mode := range policy.Users {
+		if !mode.IsValid() {
+			return nil, fmt.Errorf("access mode '%s' of user '%s' is not one of %s, %s", mode, user, ws_conn.FullAccess, ws_conn.ReadOnlyAccess)
+		}
+	}
+	for group, mode := range policy.Groups {
+		if !mode.IsValid() {
+			return nil, fmt.Errorf("access mode '%s' of group '%s' is not one of %s, %s", mode, group, ws_conn.FullAccess, ws_conn.ReadOnlyAccess)
+		}
+	}
+	return policy, nil
+}
+
+// modeOf returns the widest access the policy grants to the user, empty if access is not granted.
+func (policy *AccessPolicy) modeOf(user *userInfo) ws_conn.AccessMode {
+	mode := widest(policy.Users[user.ID], policy.Users[user.Name])
+	for _, group := range user.Groups {
+		mode = widest(mode, policy.Groups[group])
+	}
+	return mode
+}
+
+func widest(mode, other ws_conn.AccessMode) ws_conn.AccessMode {
+	switch {
+	case mode == ws_conn.FullAccess || other == ws_conn.FullAccess:
+		return ws_conn.FullAccess
+	case mode == ws_conn.ReadOnlyAccess || other == ws_conn.ReadOnlyAccess:
+		return ws_conn.ReadOnlyAccess
+	default:
+		return ""
+	}
+}
+
+// accessPolicies keeps the latest access policies read from the sources.
+type accessPolicies struct {
+	mutex    sync.RWMutex
+	bySource map[string]*AccessPolicy
+}
+
+var policies = &accessPolicies{bySource: make(map[string]*AccessPolicy)}
+
+// apply replaces access policy of the source. Empty data removes the policy.
+// Previous policy is kept if data is not valid.
+func (p *accessPolicies) apply(source string, data []byte) {
+	var policy *AccessPolicy
+	if len(bytes.TrimSpace(data)) > 0 {
+		var err error
+		if policy, err = ParseAccessPolicy(data); err != nil {
+			logrus.Errorf("Access policy from %s is not valid, previous one is kept. Cause: %s", source, err.Error())
+			return
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if policy == nil {
+		if _, ok := p.bySource[source]; ok {
+			logrus.Infof("Access policy from %s is removed", source)
+			delete(p.bySource, source)
+		}
+		return
+	}
+	logrus.Infof("Access policy from %s is updated: %d users, %d groups", source, len(policy.Users), len(policy.Groups))
+	p.bySource[source] = policy
+}
+
+// accessModeOf returns the widest access granted to the user by the access policies.
+// Workspace owner and users allowed to exec into the workspace pod always have full access.
+// Empty mode is returned if access is not granted.
+func accessModeOf(user *userInfo) ws_conn.AccessMode {
+	if IsOwner(user.ID, user.CanExec) {
+		return ws_conn.FullAccess
+	}
+
+	policies.mutex.RLock()
+	defer policies.mutex.RUnlock()
+
+	var mode ws_conn.AccessMode
+	for _, policy := range policies.bySource {
+		mode = widest(mode, policy.modeOf(user))
+	}
+	return mode
+}
+
+// WatchAccessPolicyFile reads access policy from the file, for example mounted ConfigMap key,
+// and rereads it when the file is changed. Policy is removed when the file is removed.
+func WatchAccessPolicyFile(path string) {
+	source := "file " + path
+	var previous []byte
+	read := func() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			logrus.Errorf("Unable to read access policy from %s. Cause: %s", path, err.Error())
+			return
+		}
+		if previous != nil && bytes.Equal(previous, data) {
+			return
+		}
+		previous = data
+		policies.apply(source, data)
+	}
+
+	read()
+	go func() {
+		ticker := time.NewTicker(accessPolicyPollPeriod)
+		defer ticker.Stop()
+		for range ticker.C {
+			read()
+		}
+	}()
+}
+
+// WatchAccessPolicyAnnotation watches the DevWorkspace with the service account of machine exec
+// and reads access policy from its AccessPolicyAnnotation annotation.
+func WatchAccessPolicyAnnotation(namespace, workspaceName string) error {
+	config, err := k8sRest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	resource := client.Resource(DevWorkspaceResource).Namespace(namespace)
+	options := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", workspaceName).String()}
+	go func() {
+		for {
+			watcher, err := resource.Watch(context.TODO(), options)
+			if err != nil {
+				logrus.Warnf("Unable to watch access policy of the workspace %s. Cause: %s", workspaceName, err.Error())
+				time.Sleep(accessPolicyRetryPeriod)
+				continue
+			}
+			for event := range watcher.ResultChan() {
+				handleWorkspaceEvent(event)
+			}
+			watcher.Stop()
+		}
+	}()
+	return nil
+}
+
+// handleWorkspaceEvent applies access policy from the annotation of the changed DevWorkspace.
+func handleWorkspaceEvent(event watch.Event) {
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		if workspace, ok := event.Object.(*unstructured.Unstructured); ok {
+			policies.apply(annotationSource, []byte(workspace.GetAnnotations()[AccessPolicyAnnotation]))
+		}
+	case watch.Deleted:
+		policies.apply(annotationSource, nil)
+	}
+}
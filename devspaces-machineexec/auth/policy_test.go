@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const policy = `
+users:
+  alice: full
+  bob: read-only
+  8d4b5d2e-uid: read-only
+groups:
+  team-leads: read-only
+  pair-programmers: full
+`
+
+func setUpPolicies(t *testing.T) {
+	policies = &accessPolicies{bySource: make(map[string]*AccessPolicy)}
+	previousUserID := cfg.AuthenticatedUserID
+	cfg.AuthenticatedUserID = "owner-uid"
+	t.Cleanup(func() {
+		cfg.AuthenticatedUserID = previousUserID
+	})
+}
+
+func TestShouldGrantAccessByPolicy(t *testing.T) {
+	setUpPolicies(t)
+	policies.apply("test", []byte(policy))
+
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(&userInfo{ID: "owner-uid", Name: "owner"}))
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(&userInfo{ID: "alice-uid", Name: "alice"}))
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{ID: "bob-uid", Name: "bob"}))
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{ID: "8d4b5d2e-uid", Name: "carol"}))
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{ID: "dave-uid", Name: "dave", Groups: []string{"team-leads"}}))
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(&userInfo{ID: "bob-uid", Name: "bob", Groups: []string{"team-leads", "pair-programmers"}}))
+	assert.Equal(t, ws_conn.AccessMode(""), accessModeOf(&userInfo{ID: "eve-uid", Name: "eve", Groups: []string{"developers"}}))
+}
+
+func TestShouldMergePoliciesOfSources(t *testing.T) {
+	setUpPolicies(t)
+	policies.apply("file", []byte(`{"users": {"bob": "read-only"}}`))
+	policies.apply(annotationSource, []byte(`{"users": {"bob": "full"}, "groups": {"qa": "read-only"}}`))
+
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(&userInfo{Name: "bob"}))
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{Name: "frank", Groups: []string{"qa"}}))
+
+	policies.apply(annotationSource, nil)
+
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{Name: "bob"}))
+	assert.Equal(t, ws_conn.AccessMode(""), accessModeOf(&userInfo{Name: "frank", Groups: []string{"qa"}}))
+}
+
+func TestShouldKeepPreviousPolicyIfNewOneIsNotValid(t *testing.T) {
+	setUpPolicies(t)
+	policies.apply("test", []byte(policy))
+
+	policies.apply("test", []byte(`{"users": {"alice": "admin"}}`))
+
+	assert.Equal(t, ws_conn.FullAccess, accessModeOf(&userInfo{Name: "alice"}))
+	_, err := ParseAccessPolicy([]byte(`{"users": {"alice": "admin"}}`))
+	assert.EqualError(t, err, "access mode 'admin' of user 'alice' is not one of full, read-only")
+	_, err = ParseAccessPolicy([]byte(`{"user": {"alice": "full"}}`))
+	assert.Error(t, err)
+}
+
+func TestShouldReadPolicyFromFile(t *testing.T) {
+	setUpPolicies(t)
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	WatchAccessPolicyFile(path)
+
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{Name: "bob"}))
+	_ = os.Remove(path)
+}
+
+func TestShouldReadPolicyFromWorkspaceAnnotation(t *testing.T) {
+	setUpPolicies(t)
+	workspace := &unstructured.Unstructured{}
+	workspace.SetAnnotations(map[string]string{AccessPolicyAnnotation: `{"groups": {"team-leads": "read-only"}}`})
+
+	handleWorkspaceEvent(watch.Event{Type: watch.Added, Object: workspace})
+	assert.Equal(t, ws_conn.ReadOnlyAccess, accessModeOf(&userInfo{Name: "dave", Groups: []string{"team-leads"}}))
+
+	workspace.SetAnnotations(nil)
+	handleWorkspaceEvent(watch.Event{Type: watch.Modified, Object: workspace})
+	assert.Equal(t, ws_conn.AccessMode(""), accessModeOf(&userInfo{Name: "dave", Groups: []string{"team-leads"}}))
+}
@@ -17,6 +17,7 @@ import (
 	"errors"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	k8sRest "k8s.io/client-go/rest"
@@ -40,18 +41,34 @@ var (
 	}
 )
 
-func getCurrentUserID(token string) (string, error) {
+// userInfo is the user the token belongs to.
+type userInfo struct {
+	ID     string
+	Name   string
+	Groups []string
+	// CanExec is true if the user is allowed to exec into the workspace pod,
+	// so granting full access to API gives the user no more permissions than the user has.
+	CanExec bool
+}
+
+// getCurrentUser returns the OpenShift user the token belongs to.
+func getCurrentUser(token string) (*userInfo, error) {
 	client, err := newDynamicForUsersWithToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	user, err := client.Resource(UserGroupResource.WithVersion("v1")).Namespace("").Get(context.TODO(), "~", metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.New("Failed to retrieve the current user info. Cause: " + err.Error())
 	}
 
-	userInfo, err := client.Resource(UserGroupResource.WithVersion("v1")).Namespace("").Get(context.TODO(), "~", metav1.GetOptions{})
+	groups, _, err := unstructured.NestedStringSlice(user.Object, "groups")
 	if err != nil {
-		return "", errors.New("Failed to retrieve the current user info. Cause: " + err.Error())
+		return nil, errors.New("Failed to read groups of the current user. Cause: " + err.Error())
 	}
 
-	return string(userInfo.GetUID()), nil
+	return &userInfo{ID: string(user.GetUID()), Name: user.GetName(), Groups: groups}, nil
 }
 
 func newDynamicForUsersWithToken(token string) (dynamic.Interface, error) {
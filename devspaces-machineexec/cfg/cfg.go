@@ -13,15 +13,46 @@
 package cfg
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/eclipse-che/che-machine-exec/output/scrollback"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// KubernetesExecBackend creates execs in the containers of the workspace pod.
+	KubernetesExecBackend = "kubernetes"
+	// LocalExecBackend spawns execs as local processes of the machine exec.
+	LocalExecBackend = "local"
+
+	// AutoExecTransport streams kubernetes execs over websocket and falls back to SPDY if websocket is not supported.
+	AutoExecTransport = "auto"
+	// WebsocketExecTransport streams kubernetes execs over websocket only.
+	WebsocketExecTransport = "websocket"
+	// SPDYExecTransport streams kubernetes execs over SPDY only.
+	SPDYExecTransport = "spdy"
+
+	// OpenShiftAuthenticator identifies users with the OpenShift users API.
+	OpenShiftAuthenticator = "openshift"
+	// KubernetesAuthenticator identifies users with TokenReview and checks that they are allowed
+	// to exec into the workspace pod with SubjectAccessReview.
+	KubernetesAuthenticator = "kubernetes"
+)
+
+// PreStopCommand is a command which is run in the container before workspace is stopped by idle or run timeout.
+type PreStopCommand struct {
+	// Container to run command in. For the local exec backend it might be omitted.
+	Container string `json:"container"`
+	// Command with arguments, it is not run in shell. For example: ["sh", "-c", "git stash"]
+	Command []string `json:"command"`
+}
+
 var (
 	// URL che-machine-exec api server url
 	URL string
@@ -32,6 +63,16 @@ var (
 	UseBearerToken bool
 	// AuthenticatedUserID is a user's ID who is authenticated to use API. Is ignored if useBearerToken is disabled
 	AuthenticatedUserID string
+	// Authenticator defines how users are identified by bearer token, one of OpenShiftAuthenticator, KubernetesAuthenticator.
+	// Default value is openshift
+	Authenticator string
+	// AccessPolicyPath is the path to the access policy file, for example mounted ConfigMap key,
+	// which grants access to API to the users and groups besides the authenticated user.
+	// Default value is empty, which means - access policy file is not used
+	AccessPolicyPath string
+	// AccessPolicyAnnotation flag to enable/disable watching access policy in the DevWorkspace annotation.
+	// Default value is false
+	AccessPolicyAnnotation bool
 
 	// IdleTimeout is a inactivity period after which workspace should be stopped
 	// Default value is 30 minutes
@@ -42,13 +83,103 @@ var (
 	// RunTimeout is the maximum duration a workspace can be running before it is stopped
 	// Default value is -1, which means - no maximium duration
 	RunTimeout time.Duration
+	// StopWarnings are periods before workspace is stopped by idle or run timeout when clients are warned about it.
+	// Default value is 5m,1m
+	StopWarnings []time.Duration
+	// PreStopTimeout is a period for which the live execs and pre-stop commands are waited to complete
+	// before workspace is stopped by idle or run timeout.
+	// Default value is -1, which means - pre-stop phase is disabled
+	PreStopTimeout time.Duration
+	// PreStopCommands are run in the containers before workspace is stopped by idle or run timeout.
+	PreStopCommands []PreStopCommand
+	// MaxStopPostpone is the maximum period for which user is able to postpone workspace stop once.
+	// Default value is 30 minutes, -1 means - postponing is disabled
+	MaxStopPostpone time.Duration
 
 	// UseTLS flag to enable/disable serving TLS
 	UseTLS bool
 
+	// EnableMetrics flag to enable/disable serving prometheus metrics on /metrics endpoint
+	EnableMetrics bool
+
+	// ExecBackend defines where execs are created, one of KubernetesExecBackend, LocalExecBackend.
+	// Default value is kubernetes
+	ExecBackend string
+
+	// ExecTransport defines protocol to stream kubernetes execs, one of AutoExecTransport, WebsocketExecTransport, SPDYExecTransport.
+	// Default value is auto
+	ExecTransport string
+
 	// PodSelector set of labels to be used as selector for getting workspace pod.
 	// Default value is che.workspace_id=${CHE_WORKSPACE_ID}
 	PodSelector string
+
+	// ContainerCache flag to enable/disable watching the workspace pods to find containers and to cache the detected shells.
+	// Default value is true
+	ContainerCache bool
+
+	// DevfilePath is the path to the flattened devworkspace with the attributes of the devfile components.
+	// Default value is ${DEVWORKSPACE_FLATTENED_DEVFILE}
+	DevfilePath string
+
+	// DebugImage is the image of the ephemeral debug containers which are added to the workspace pod
+	// to open terminal in the containers without shell.
+	// Default value is empty, which means - debug containers are disabled
+	DebugImage string
+
+	// KubeConfigCredentialPlugin flag to enable/disable the exec credential plugin in the kubeconfigs created in the containers.
+	// Plugin gets the current token of the user from machine exec, otherwise the token is written into kubeconfig.
+	// Default value is true
+	KubeConfigCredentialPlugin bool
+
+	// ScrollbackSize is the amount of exec output in bytes which is kept to be replayed for reattached clients.
+	// Default value is 1 MiB
+	ScrollbackSize int
+	// SessionGracePeriod is a period for which exec is kept alive after its last websocket connection is lost.
+	// Default value is -1, which means - exec is kept until its process exits
+	SessionGracePeriod time.Duration
+
+	// RecordingsPath is a directory where exec sessions are recorded in asciicast v2 format.
+	// Default value is empty, which means - recording is disabled
+	RecordingsPath string
+	// RecordingMaxSize is the maximum size in bytes of the single exec session recording.
+	// Default value is 10 MiB
+	RecordingMaxSize int64
+	// RecordingRetention is a period after which recordings are removed
+	// Default value is 30 days, -1 means - recordings are kept forever
+	RecordingRetention time.Duration
+	// RecordingsQuota is the maximum total size in bytes of the recordings, the oldest ones are removed to fit it.
+	// Default value is 100 MiB, -1 means - no quota
+	RecordingsQuota int64
+
+	// MaxExecsPerToken is the maximum amount of the live execs created with the same bearer token.
+	// If bearer token is not used, all the execs are counted together.
+	// Default value is -1, which means - no limit
+	MaxExecsPerToken int
+	// MaxExecsPerContainer is the maximum amount of the live execs in the same container.
+	// Default value is -1, which means - no limit
+	MaxExecsPerContainer int
+	// ExecCreateRate is the maximum amount of execs created per second.
+	// Default value is -1, which means - no limit
+	ExecCreateRate float64
+	// ExecCreateBurst is the amount of execs which may be created at once exceeding ExecCreateRate.
+	// Default value is 20
+	ExecCreateBurst int
+	// InfoExecRate is the maximum amount of info execs, which detect shell or resolve command, spawned per second.
+	// Default value is -1, which means - no limit
+	InfoExecRate float64
+	// InfoExecBurst is the amount of info execs which may be spawned at once exceeding InfoExecRate.
+	// Default value is 50
+	InfoExecBurst int
+
+	// MaxTransferSize is the maximum size in bytes of the file or archive uploaded to or downloaded from the container.
+	// Default value is 100MiB, -1 means - no limit
+	MaxTransferSize int64
+
+	// raw value of the StopWarnings which is parsed when arguments are parsed
+	stopWarnings string
+	// raw value of the PreStopCommands which is parsed when arguments are parsed
+	preStopCommands string
 )
 
 func init() {
@@ -85,6 +216,22 @@ func init() {
 	}
 	flag.StringVar(&AuthenticatedUserID, "authenticated-user-id", defaultAuthenticatedUserID, "OpenShift user's ID that should has access to API. Is used only if useBearerToken is configured")
 
+	defaultAuthenticator := OpenShiftAuthenticator
+	authenticatorEnvValue, isFound := os.LookupEnv("AUTHENTICATOR")
+	if isFound && len(authenticatorEnvValue) > 0 {
+		defaultAuthenticator = authenticatorEnvValue
+	}
+	flag.StringVar(&Authenticator, "authenticator", defaultAuthenticator, "Authenticator defines how users are identified by bearer token. Supported values: openshift - with OpenShift users API, kubernetes - with TokenReview, users allowed to exec into the workspace pod are checked with SubjectAccessReview. Is used only if useBearerToken is configured")
+
+	flag.StringVar(&AccessPolicyPath, "access-policy-path", os.Getenv("ACCESS_POLICY_PATH"), "Path to the access policy file, for example mounted ConfigMap key, which grants full or read-only access to API to the users and groups besides the authenticated user. File is reread when it is changed. Is used only if useBearerToken is configured")
+
+	defaultAccessPolicyAnnotation := false
+	accessPolicyAnnotationEnvValue, isFound := os.LookupEnv("ACCESS_POLICY_ANNOTATION")
+	if isFound && len(accessPolicyAnnotationEnvValue) > 0 {
+		defaultAccessPolicyAnnotation = accessPolicyAnnotationEnvValue == "true"
+	}
+	flag.BoolVar(&AccessPolicyAnnotation, "access-policy-annotation", defaultAccessPolicyAnnotation, "Watch the DevWorkspace and read access policy from its che.eclipse.org/access-policy annotation. Is used only if useBearerToken is configured")
+
 	const defaultIdleTimeout = 1800 * time.Second
 	idleTimeout := defaultIdleTimeout
 	idleTimeoutEnv := "SECONDS_OF_DW_INACTIVITY_BEFORE_IDLING"
@@ -111,10 +258,44 @@ func init() {
 	}
 	flag.DurationVar(&RunTimeout, "run-timeout", runtimeout, "RunTimeout is the maximum duration a workspace can run. After this period, the workspace will be stopped. Examples: -1, 30s, 15m, 1h")
 
+	flag.StringVar(&stopWarnings, "stop-warnings", "5m,1m", "StopWarnings are comma separated periods before workspace is stopped by idle or run timeout when clients are warned about it. To disable, set to empty value. Examples: 5m,1m")
+
+	flag.DurationVar(&MaxStopPostpone, "max-stop-postpone", 30*time.Minute, "MaxStopPostpone is the maximum period for which user is able to postpone workspace stop by idle or run timeout once. To disable postponing, set to -1. Examples: -1, 15m, 1h")
+
+	flag.DurationVar(&PreStopTimeout, "pre-stop-timeout", -1*time.Nanosecond, "PreStopTimeout is a period for which shells of the live execs and pre-stop commands are waited to complete before workspace is stopped by idle or run timeout. To disable pre-stop phase, set to -1. Examples: -1, 30s, 1m")
+
+	defaultPreStopCommands := ""
+	preStopCommandsEnvValue, isFound := os.LookupEnv("PRE_STOP_COMMANDS")
+	if isFound && len(preStopCommandsEnvValue) > 0 {
+		defaultPreStopCommands = preStopCommandsEnvValue
+	}
+	flag.StringVar(&preStopCommands, "pre-stop-commands", defaultPreStopCommands, `PreStopCommands is JSON array of commands which are run in the containers before workspace is stopped by idle or run timeout. Example: [{"container": "tools", "command": ["sh", "-c", "git stash"]}]`)
+
 	flag.DurationVar(&StopRetryPeriod, "stop-retry-period", 10*time.Second, "StopRetryPeriod is a period after which workspace should be tried to stop if the previous try failed. Examples: 30s")
 
 	flag.BoolVar(&UseTLS, "use-tls", false, "Serve content via TLS")
 
+	defaultEnableMetrics := false
+	enableMetricsEnvValue, isFound := os.LookupEnv("ENABLE_METRICS")
+	if isFound && len(enableMetricsEnvValue) > 0 {
+		defaultEnableMetrics = enableMetricsEnvValue == "true"
+	}
+	flag.BoolVar(&EnableMetrics, "enable-metrics", defaultEnableMetrics, "Serve prometheus metrics on /metrics endpoint")
+
+	defaultExecBackend := KubernetesExecBackend
+	execBackendEnvValue, isFound := os.LookupEnv("EXEC_BACKEND")
+	if isFound && len(execBackendEnvValue) > 0 {
+		defaultExecBackend = execBackendEnvValue
+	}
+	flag.StringVar(&ExecBackend, "exec-backend", defaultExecBackend, "ExecBackend defines where execs are created. Supported values: kubernetes - in the containers of the workspace pod, local - as local processes of the machine exec.")
+
+	defaultExecTransport := AutoExecTransport
+	execTransportEnvValue, isFound := os.LookupEnv("EXEC_TRANSPORT")
+	if isFound && len(execTransportEnvValue) > 0 {
+		defaultExecTransport = execTransportEnvValue
+	}
+	flag.StringVar(&ExecTransport, "exec-transport", defaultExecTransport, "ExecTransport defines protocol to stream kubernetes execs. Supported values: auto - websocket with fallback to SPDY, websocket - websocket only, spdy - SPDY only.")
+
 	defaultPodSelector, isFound := os.LookupEnv("POD_SELECTOR")
 	if !isFound {
 		workspaceID := os.Getenv("DEVWORKSPACE_ID")
@@ -129,6 +310,55 @@ func init() {
 	}
 	flag.StringVar(&PodSelector, "pod-selector", defaultPodSelector, "Selector that is used to find workspace pod. Default value is `che.workspace_id=${CHE_WORKSPACE_ID}` or controller.devfile.io/devworkspace_id={DEVWORKSPACE_ID} if che env var is not defined")
 
+	defaultContainerCache := true
+	containerCacheEnvValue, isFound := os.LookupEnv("CONTAINER_CACHE")
+	if isFound && len(containerCacheEnvValue) > 0 {
+		defaultContainerCache = containerCacheEnvValue == "true"
+	}
+	flag.BoolVar(&ContainerCache, "container-cache", defaultContainerCache, "Watch the workspace pods to find containers without listing the pods on each exec creation and cache the shells detected in the containers until they are restarted")
+
+	flag.StringVar(&DevfilePath, "devfile-path", os.Getenv("DEVWORKSPACE_FLATTENED_DEVFILE"), "DevfilePath is the path to the flattened devworkspace. Attributes of the devfile components are used to choose the containers where terminals are opened. Default value is ${DEVWORKSPACE_FLATTENED_DEVFILE}")
+
+	flag.StringVar(&DebugImage, "debug-image", os.Getenv("DEBUG_IMAGE"), "DebugImage is the image of the ephemeral debug containers which are added to the workspace pod to open terminal in the containers without shell. To disable debug containers, set to empty value. Example: registry.access.redhat.com/ubi8/ubi-minimal")
+
+	defaultKubeConfigCredentialPlugin := true
+	kubeConfigCredentialPluginEnvValue, isFound := os.LookupEnv("KUBECONFIG_CREDENTIAL_PLUGIN")
+	if isFound && len(kubeConfigCredentialPluginEnvValue) > 0 {
+		defaultKubeConfigCredentialPlugin = kubeConfigCredentialPluginEnvValue == "true"
+	}
+	flag.BoolVar(&KubeConfigCredentialPlugin, "kubeconfig-credential-plugin", defaultKubeConfigCredentialPlugin, "Use exec credential plugin in the kubeconfigs created in the containers, so kubectl and oc get the current token of the user from machine exec. Otherwise the token is written into kubeconfig")
+
+	flag.IntVar(&ScrollbackSize, "scrollback-size", scrollback.DefaultSize, "ScrollbackSize is the amount of exec output in bytes which is kept to be replayed for reattached clients.")
+
+	flag.DurationVar(&SessionGracePeriod, "session-grace-period", -1*time.Nanosecond, "SessionGracePeriod is a period for which exec is kept alive after its last websocket connection is lost. To keep exec until its process exits, set to -1. Examples: -1, 30s, 15m, 1h")
+
+	defaultRecordingsPath := ""
+	recordingsPathEnvValue, isFound := os.LookupEnv("RECORDINGS_PATH")
+	if isFound && len(recordingsPathEnvValue) > 0 {
+		defaultRecordingsPath = recordingsPathEnvValue
+	}
+	flag.StringVar(&RecordingsPath, "recordings-path", defaultRecordingsPath, "/projects/.recordings - absolute path to folder where exec sessions are recorded. Recording is disabled if not set.")
+
+	flag.Int64Var(&RecordingMaxSize, "recording-max-size", 10*1024*1024, "RecordingMaxSize is the maximum size in bytes of the single exec session recording. The rest of the session is not recorded.")
+
+	flag.DurationVar(&RecordingRetention, "recording-retention", 720*time.Hour, "RecordingRetention is a period after which recordings are removed. To keep recordings forever, set to -1. Examples: -1, 24h, 720h")
+
+	flag.Int64Var(&RecordingsQuota, "recordings-quota", 100*1024*1024, "RecordingsQuota is the maximum total size in bytes of the recordings. The oldest recordings are removed when a new recording is started. To disable, set to -1.")
+
+	flag.IntVar(&MaxExecsPerToken, "max-execs-per-token", -1, "MaxExecsPerToken is the maximum amount of the live execs created with the same bearer token. If bearer token is not used, all the execs are counted together. To disable, set to -1.")
+
+	flag.IntVar(&MaxExecsPerContainer, "max-execs-per-container", -1, "MaxExecsPerContainer is the maximum amount of the live execs in the same container. To disable, set to -1.")
+
+	flag.Float64Var(&ExecCreateRate, "exec-create-rate", -1, "ExecCreateRate is the maximum amount of execs created per second. Is disabled by default, set to a positive value to limit it.")
+
+	flag.IntVar(&ExecCreateBurst, "exec-create-burst", 20, "ExecCreateBurst is the amount of execs which may be created at once exceeding exec-create-rate.")
+
+	flag.Float64Var(&InfoExecRate, "info-exec-rate", -1, "InfoExecRate is the maximum amount of info execs, which detect shell or resolve command, spawned per second. Is disabled by default, set to a positive value to limit it.")
+
+	flag.IntVar(&InfoExecBurst, "info-exec-burst", 50, "InfoExecBurst is the amount of info execs which may be spawned at once exceeding info-exec-rate.")
+
+	flag.Int64Var(&MaxTransferSize, "max-transfer-size", 100*1024*1024, "MaxTransferSize is the maximum size in bytes of the file or archive uploaded to or downloaded from the container. To disable, set to -1.")
+
 	setLogLevel()
 }
 
@@ -153,13 +383,86 @@ func setLogLevel() {
 func Parse() {
 	flag.Parse()
 
-	if PodSelector == "" {
-		logrus.Fatal("pod selector is required. Configure custom pod selector or che workspace/devworkspace id env var to activate defaults")
+	switch ExecBackend {
+	case KubernetesExecBackend:
+		if PodSelector == "" {
+			logrus.Fatal("pod selector is required. Configure custom pod selector or che workspace/devworkspace id env var to activate defaults")
+		}
+	case LocalExecBackend:
+		// there is no workspace to stop
+		IdleTimeout = -1
+		RunTimeout = -1
+	default:
+		logrus.Fatalf("exec-backend '%s' is not supported. Supported values: %s, %s", ExecBackend, KubernetesExecBackend, LocalExecBackend)
+	}
+
+	switch ExecTransport {
+	case AutoExecTransport, WebsocketExecTransport, SPDYExecTransport:
+	default:
+		logrus.Fatalf("exec-transport '%s' is not supported. Supported values: %s, %s, %s", ExecTransport, AutoExecTransport, WebsocketExecTransport, SPDYExecTransport)
+	}
+
+	switch Authenticator {
+	case OpenShiftAuthenticator, KubernetesAuthenticator:
+	default:
+		logrus.Fatalf("authenticator '%s' is not supported. Supported values: %s, %s", Authenticator, OpenShiftAuthenticator, KubernetesAuthenticator)
 	}
 
 	if StopRetryPeriod <= 0 {
 		logrus.Fatalf("stop-retry-period must be greater than 0")
 	}
+
+	PreStopCommands = nil
+	if preStopCommands != "" {
+		if err := json.Unmarshal([]byte(preStopCommands), &PreStopCommands); err != nil {
+			logrus.Fatalf("pre-stop-commands must be JSON array of commands. Cause: %s", err.Error())
+		}
+		for _, command := range PreStopCommands {
+			if len(command.Command) == 0 {
+				logrus.Fatalf("pre-stop-commands must not contain empty commands")
+			}
+		}
+	}
+
+	StopWarnings = nil
+	for _, warning := range strings.Split(stopWarnings, ",") {
+		if warning = strings.TrimSpace(warning); warning == "" {
+			continue
+		}
+		period, err := time.ParseDuration(warning)
+		if err != nil || period <= 0 {
+			logrus.Fatalf("stop-warnings must contain positive durations, got '%s'", warning)
+		}
+		StopWarnings = append(StopWarnings, period)
+	}
+
+	if ScrollbackSize <= 0 {
+		logrus.Fatalf("scrollback-size must be greater than 0")
+	}
+
+	if RecordingsPath != "" && RecordingMaxSize <= 0 {
+		logrus.Fatalf("recording-max-size must be greater than 0")
+	}
+
+	if RecordingsPath != "" && RecordingsQuota >= 0 && RecordingsQuota < RecordingMaxSize {
+		logrus.Fatalf("recordings-quota must not be less than recording-max-size or -1 to disable the quota")
+	}
+
+	if MaxExecsPerToken == 0 || MaxExecsPerContainer == 0 {
+		logrus.Fatalf("max-execs-per-token and max-execs-per-container must be greater than 0 or -1 to disable the limit")
+	}
+
+	if ExecCreateRate > 0 && ExecCreateBurst <= 0 {
+		logrus.Fatalf("exec-create-burst must be greater than 0")
+	}
+
+	if InfoExecRate > 0 && InfoExecBurst <= 0 {
+		logrus.Fatalf("info-exec-burst must be greater than 0")
+	}
+
+	if MaxTransferSize == 0 {
+		logrus.Fatalf("max-transfer-size must be greater than 0 or -1 to disable the limit")
+	}
 }
 
 // Print configuration information
@@ -170,9 +473,25 @@ func Print() {
 	logrus.Infof("==> Application url %s", URL)
 	logrus.Infof("==> Absolute path to folder with static resources %s", StaticPath)
 	logrus.Infof("==> Use bearer token: %t", UseBearerToken)
-	logrus.Infof("==> Pod selector: %s", PodSelector)
+	logrus.Infof("==> Exec backend: %s", ExecBackend)
+	logrus.Infof("==> Enable metrics: %t", EnableMetrics)
+	if ExecBackend == KubernetesExecBackend {
+		logrus.Infof("==> Pod selector: %s", PodSelector)
+		logrus.Infof("==> Exec transport: %s", ExecTransport)
+		logrus.Infof("==> Container cache: %t", ContainerCache)
+		logrus.Infof("==> Devfile path: %s", DevfilePath)
+		if DebugImage != "" {
+			logrus.Infof("==> Debug image: %s", DebugImage)
+		}
+		logrus.Infof("==> Kubeconfig credential plugin: %t", KubeConfigCredentialPlugin)
+	}
 	if UseBearerToken {
+		logrus.Infof("==> Authenticator: %s", Authenticator)
 		logrus.Infof("==> Authenticated user ID: %s", AuthenticatedUserID)
+		if AccessPolicyPath != "" {
+			logrus.Infof("==> Access policy path: %s", AccessPolicyPath)
+		}
+		logrus.Infof("==> Access policy annotation: %t", AccessPolicyAnnotation)
 	}
 	if IdleTimeout > 0 {
 		logrus.Infof("==> Idle timeout: %s", IdleTimeout)
@@ -182,5 +501,44 @@ func Print() {
 	}
 	if IdleTimeout > 0 || RunTimeout > 0 {
 		logrus.Infof("==> Stop retry period: %s", StopRetryPeriod)
+		logrus.Infof("==> Stop warnings: %v", StopWarnings)
+		if PreStopTimeout > 0 {
+			logrus.Infof("==> Pre-stop timeout: %s", PreStopTimeout)
+			for _, command := range PreStopCommands {
+				logrus.Infof("==> Pre-stop command in '%s': %s", command.Container, strings.Join(command.Command, " "))
+			}
+		}
+		if MaxStopPostpone > 0 {
+			logrus.Infof("==> Max stop postpone: %s", MaxStopPostpone)
+		}
+	}
+	logrus.Infof("==> Scrollback size: %d bytes", ScrollbackSize)
+	if SessionGracePeriod > 0 {
+		logrus.Infof("==> Session grace period: %s", SessionGracePeriod)
+	}
+	if RecordingsPath != "" {
+		logrus.Infof("==> Recordings path: %s", RecordingsPath)
+		logrus.Infof("==> Recording max size: %d bytes", RecordingMaxSize)
+		if RecordingRetention > 0 {
+			logrus.Infof("==> Recording retention: %s", RecordingRetention)
+		}
+		if RecordingsQuota > 0 {
+			logrus.Infof("==> Recordings quota: %d bytes", RecordingsQuota)
+		}
+	}
+	if MaxExecsPerToken > 0 {
+		logrus.Infof("==> Max execs per token: %d", MaxExecsPerToken)
+	}
+	if MaxExecsPerContainer > 0 {
+		logrus.Infof("==> Max execs per container: %d", MaxExecsPerContainer)
+	}
+	if ExecCreateRate > 0 {
+		logrus.Infof("==> Exec create rate: %g/s, burst %d", ExecCreateRate, ExecCreateBurst)
+	}
+	if InfoExecRate > 0 {
+		logrus.Infof("==> Info exec rate: %g/s, burst %d", InfoExecRate, InfoExecBurst)
+	}
+	if MaxTransferSize > 0 {
+		logrus.Infof("==> Max transfer size: %d bytes", MaxTransferSize)
 	}
 }
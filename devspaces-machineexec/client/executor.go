@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// NewExecutor creates executor to stream exec with the url over the configured transport.
+// See cfg.ExecTransport. Executor implements io.Closer to stop streaming.
+func NewExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+	switch cfg.ExecTransport {
+	case cfg.SPDYExecTransport:
+		return newSPDYExecutor(config, method, url)
+	case cfg.WebsocketExecTransport:
+		return newWebsocketExecutor(config, url), nil
+	default:
+		return &fallbackExecutor{
+			closer:     newStreamCloser(),
+			negotiated: apiServerTransport,
+			primary:    newWebsocketExecutor(config, url),
+			fallback: func() (remotecommand.Executor, error) {
+				return newSPDYExecutor(config, method, url)
+			},
+		}, nil
+	}
+}
+
+// errStreamClosed is returned when exec is started after the executor is closed.
+var errStreamClosed = errors.New("exec streaming is aborted")
+
+// streamCloser closes connection of the streamed exec on request, so that streaming returns.
+// Executors created by NewExecutor implement io.Closer with it, since remote command streaming can't be cancelled.
+type streamCloser struct {
+	lock   *sync.Mutex
+	closed bool
+	conn   io.Closer
+}
+
+func newStreamCloser() *streamCloser {
+	return &streamCloser{lock: &sync.Mutex{}}
+}
+
+// track remembers connection of the exec to be closed. If closing is already requested,
+// connection is closed at once and errStreamClosed is returned.
+func (closer *streamCloser) track(conn io.Closer) error {
+	defer closer.lock.Unlock()
+	closer.lock.Lock()
+
+	if closer.closed {
+		_ = conn.Close()
+		return errStreamClosed
+	}
+	closer.conn = conn
+	return nil
+}
+
+// Close closes connection of the streamed exec and the ones established later.
+func (closer *streamCloser) Close() error {
+	defer closer.lock.Unlock()
+	closer.lock.Lock()
+
+	closer.closed = true
+	if closer.conn == nil {
+		return nil
+	}
+	return closer.conn.Close()
+}
+
+// apiServerTransport is the transport negotiated with the API server by the first exec.
+var apiServerTransport = &negotiatedTransport{lock: &sync.Mutex{}}
+
+// negotiatedTransport remembers the transport supported by the server, so that it is negotiated only once.
+type negotiatedTransport struct {
+	lock      *sync.Mutex
+	transport string
+}
+
+func (negotiated *negotiatedTransport) get() string {
+	defer negotiated.lock.Unlock()
+	negotiated.lock.Lock()
+
+	return negotiated.transport
+}
+
+func (negotiated *negotiatedTransport) set(transport string) {
+	defer negotiated.lock.Unlock()
+	negotiated.lock.Lock()
+
+	negotiated.transport = transport
+}
+
+// fallbackExecutor streams exec over websocket if server supports it, otherwise over SPDY.
+type fallbackExecutor struct {
+	// closer closes the fallback executor
+	closer     *streamCloser
+	negotiated *negotiatedTransport
+	primary    *websocketExecutor
+	fallback   func() (remotecommand.Executor, error)
+}
+
+func (executor *fallbackExecutor) Stream(options remotecommand.StreamOptions) error {
+	if executor.negotiated.get() == cfg.SPDYExecTransport {
+		return executor.streamFallback(options)
+	}
+
+	conn, err := executor.primary.dial()
+	if err != nil {
+		// other errors, e.g. unauthorized ones, would fail over SPDY as well
+		if _, ok := err.(*handshakeError); !ok {
+			return err
+		}
+		logrus.Warnf("Unable to stream exec over websocket, falling back to SPDY for all the execs. Cause: %s", err.Error())
+		executor.negotiated.set(cfg.SPDYExecTransport)
+		return executor.streamFallback(options)
+	}
+	executor.negotiated.set(cfg.WebsocketExecTransport)
+	return executor.primary.stream(conn, options)
+}
+
+func (executor *fallbackExecutor) streamFallback(options remotecommand.StreamOptions) error {
+	spdyExecutor, err := executor.fallback()
+	if err != nil {
+		return err
+	}
+	if closer, ok := spdyExecutor.(io.Closer); ok {
+		if err := executor.closer.track(closer); err != nil {
+			return err
+		}
+	}
+	return spdyExecutor.Stream(options)
+}
+
+// Close closes connection of the exec streamed over either transport.
+func (executor *fallbackExecutor) Close() error {
+	if err := executor.primary.Close(); err != nil {
+		return err
+	}
+	return executor.closer.Close()
+}
+
+// spdyExecutor streams exec over SPDY and measures how long it takes to upgrade connection.
+type spdyExecutor struct {
+	executor remotecommand.Executor
+	upgrader *timedUpgrader
+}
+
+func newSPDYExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	timedUpgrader := &timedUpgrader{Upgrader: upgrader, closer: newStreamCloser()}
+	executor, err := remotecommand.NewSPDYExecutorForTransports(transport, timedUpgrader, method, url)
+	if err != nil {
+		return nil, err
+	}
+	return &spdyExecutor{executor: executor, upgrader: timedUpgrader}, nil
+}
+
+func (executor *spdyExecutor) Stream(options remotecommand.StreamOptions) error {
+	executor.upgrader.start = time.Now()
+	return executor.executor.Stream(options)
+}
+
+// Close closes SPDY connection of the exec.
+func (executor *spdyExecutor) Close() error {
+	return executor.upgrader.closer.Close()
+}
+
+// timedUpgrader records SPDY setup duration when upgrade response is received
+// and tracks the connection to be closed.
+type timedUpgrader struct {
+	spdy.Upgrader
+	start  time.Time
+	closer *streamCloser
+}
+
+func (upgrader *timedUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := upgrader.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveCreatePhase(metrics.SPDYSetupPhase, time.Since(upgrader.start))
+	if err := upgrader.closer.track(conn); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
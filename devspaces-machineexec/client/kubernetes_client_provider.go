@@ -14,12 +14,14 @@ package client
 
 import (
 	"errors"
+	"net/url"
 
 	"github.com/eclipse-che/che-machine-exec/api/model"
 	"github.com/eclipse-che/che-machine-exec/auth"
 	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
 // K8sAPI object to access k8s api.
@@ -43,6 +45,11 @@ func (api *K8sAPI) GetConfig() *rest.Config {
 	return api.config
 }
 
+// NewExecutor creates executor to stream exec with the url over the configured transport.
+func (api *K8sAPI) NewExecutor(method string, url *url.URL) (remotecommand.Executor, error) {
+	return NewExecutor(api.config, method, url)
+}
+
 // K8sAPIProvider for creation new K8sAPI.
 type K8sAPIProvider struct {
 	k8sAPI *K8sAPI
@@ -93,6 +100,12 @@ func (clientProvider *K8sAPIProvider) getK8sAPIWithBearerToken(token string) (*K
 	return NewK8sAPI(config, client), nil
 }
 
+// GetServiceAccountK8sAPI returns k8s api object with service account permissions
+// regardless of authentication, to be used by the machine exec on its own behalf.
+func (clientProvider *K8sAPIProvider) GetServiceAccountK8sAPI() (*K8sAPI, error) {
+	return clientProvider.getK8sAPIWithSA()
+}
+
 // GetK8sAPI return k8s api object.
 func (clientProvider *K8sAPIProvider) GetK8sAPI(machineExec *model.MachineExec) (*K8sAPI, error) {
 	if auth.IsEnabled() {
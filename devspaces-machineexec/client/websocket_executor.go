@@ -0,0 +1,285 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/rest"
+	clientremotecommand "k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// StreamProtocolV5 is kubernetes websocket streaming protocol
+// which allows to close stdin of the exec.
+const StreamProtocolV5 = "v5.channel.k8s.io"
+
+// Channels of the websocket streaming protocol, each message starts with the channel byte.
+const (
+	stdinChannel  byte = 0
+	stdoutChannel byte = 1
+	stderrChannel byte = 2
+	errorChannel  byte = 3
+	resizeChannel byte = 4
+	// closeChannel message contains channel which should be closed.
+	closeChannel byte = 255
+)
+
+const (
+	handshakeTimeout = 30 * time.Second
+	bufferSize       = 32 * 1024
+)
+
+// handshakeError means that server is not able to stream exec over websocket streaming protocol.
+type handshakeError struct {
+	message string
+}
+
+func (err *handshakeError) Error() string {
+	return err.message
+}
+
+// websocketExecutor streams exec over kubernetes websocket streaming protocol.
+type websocketExecutor struct {
+	config *rest.Config
+	url    *url.URL
+	closer *streamCloser
+}
+
+func newWebsocketExecutor(config *rest.Config, url *url.URL) *websocketExecutor {
+	return &websocketExecutor{config: config, url: url, closer: newStreamCloser()}
+}
+
+func (executor *websocketExecutor) Stream(options clientremotecommand.StreamOptions) error {
+	conn, err := executor.dial()
+	if err != nil {
+		return err
+	}
+	return executor.stream(conn, options)
+}
+
+// dial upgrades connection to the websocket one. Error means that exec is not started,
+// handshakeError means that exec might be streamed over other protocol.
+func (executor *websocketExecutor) dial() (*websocket.Conn, error) {
+	tlsConfig, err := rest.TLSConfigFor(executor.config)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	token, err := bearerToken(executor.config)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	execURL := *executor.url
+	switch execURL.Scheme {
+	case "https":
+		execURL.Scheme = "wss"
+	case "http":
+		execURL.Scheme = "ws"
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: handshakeTimeout,
+		ReadBufferSize:   bufferSize,
+		WriteBufferSize:  bufferSize,
+		Subprotocols:     []string{StreamProtocolV5},
+	}
+	dialStart := time.Now()
+	conn, resp, err := dialer.Dial(execURL.String(), header)
+	if err != nil {
+		if err != websocket.ErrBadHandshake || resp == nil {
+			return nil, err
+		}
+		message := fmt.Sprintf("%s, response status: %s", err.Error(), resp.Status)
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, errors.New(message)
+		}
+		return nil, &handshakeError{message: message}
+	}
+	if conn.Subprotocol() != StreamProtocolV5 {
+		_ = conn.Close()
+		return nil, &handshakeError{message: fmt.Sprintf("server does not support %s protocol", StreamProtocolV5)}
+	}
+
+	metrics.ObserveCreatePhase(metrics.WebsocketSetupPhase, time.Since(dialStart))
+	logrus.Debugf("Exec is streamed over websocket %s protocol", StreamProtocolV5)
+	return conn, nil
+}
+
+// Close closes websocket connection of the exec.
+func (executor *websocketExecutor) Close() error {
+	return executor.closer.Close()
+}
+
+// stream copies exec streams until server closes connection or executor is closed.
+func (executor *websocketExecutor) stream(conn *websocket.Conn, options clientremotecommand.StreamOptions) error {
+	if err := executor.closer.track(conn); err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	writeLock := &sync.Mutex{}
+	write := func(channel byte, data []byte) error {
+		defer writeLock.Unlock()
+		writeLock.Lock()
+
+		return conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, data...))
+	}
+
+	if options.Stdin != nil {
+		go copyStdin(options.Stdin, write)
+	}
+	if options.Tty && options.TerminalSizeQueue != nil {
+		go sendResizes(options.TerminalSizeQueue, write)
+	}
+
+	var status error
+	statusReceived := false
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if statusReceived {
+				return status
+			}
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case stdoutChannel:
+			if options.Stdout != nil {
+				if _, err := options.Stdout.Write(data[1:]); err != nil {
+					return err
+				}
+			}
+		case stderrChannel:
+			if options.Stderr != nil {
+				if _, err := options.Stderr.Write(data[1:]); err != nil {
+					return err
+				}
+			}
+		case errorChannel:
+			// output might be still in flight, so status is returned when server closes connection
+			status = decodeStatus(data[1:])
+			statusReceived = true
+		}
+	}
+}
+
+func copyStdin(stdin io.Reader, write func(channel byte, data []byte) error) {
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if err := write(stdinChannel, buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err := write(closeChannel, []byte{stdinChannel}); err != nil {
+				logrus.Debugf("Unable to close exec stdin. Cause: %s", err.Error())
+			}
+			return
+		}
+	}
+}
+
+func sendResizes(queue clientremotecommand.TerminalSizeQueue, write func(channel byte, data []byte) error) {
+	for size := queue.Next(); size != nil; size = queue.Next() {
+		data, err := json.Marshal(size)
+		if err != nil {
+			logrus.Errorf("Unable to encode terminal size. Cause: %s", err.Error())
+			return
+		}
+		if err := write(resizeChannel, data); err != nil {
+			return
+		}
+	}
+}
+
+// decodeStatus converts exec status to error the same way as SPDY executor does.
+func decodeStatus(message []byte) error {
+	status := metav1.Status{}
+	if err := json.Unmarshal(message, &status); err != nil {
+		return fmt.Errorf("error stream protocol error: %v in %q", err, string(message))
+	}
+
+	switch status.Status {
+	case metav1.StatusSuccess:
+		return nil
+	case metav1.StatusFailure:
+		if status.Reason == remotecommand.NonZeroExitCodeReason {
+			if status.Details == nil {
+				return errors.New("error stream protocol error: details must be set")
+			}
+			for _, cause := range status.Details.Causes {
+				if cause.Type != remotecommand.ExitCodeCauseType {
+					continue
+				}
+				var code int
+				if _, err := fmt.Sscanf(cause.Message, "%d", &code); err != nil {
+					return fmt.Errorf("error stream protocol error: invalid exit code value %q", cause.Message)
+				}
+				return exec.CodeExitError{
+					Err:  fmt.Errorf("command terminated with exit code %d", code),
+					Code: code,
+				}
+			}
+			return fmt.Errorf("error stream protocol error: no %s cause given", remotecommand.ExitCodeCauseType)
+		}
+	default:
+		return errors.New("error stream protocol error: unknown error")
+	}
+
+	return errors.New(status.Message)
+}
+
+func bearerToken(config *rest.Config) (string, error) {
+	if config.BearerToken != "" {
+		return config.BearerToken, nil
+	}
+	if config.BearerTokenFile == "" {
+		return "", nil
+	}
+	token, err := ioutil.ReadFile(config.BearerTokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
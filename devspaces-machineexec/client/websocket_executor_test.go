@@ -0,0 +1,195 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/rest"
+	clientremotecommand "k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// echoExecHandler echoes stdin to stdout and stderr until stdin is closed and exits with code 3.
+func echoExecHandler(t *testing.T) http.HandlerFunc {
+	upgrader := websocket.Upgrader{Subprotocols: []string{StreamProtocolV5}}
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch data[0] {
+			case stdinChannel:
+				_ = conn.WriteMessage(websocket.BinaryMessage, append([]byte{stdoutChannel}, data[1:]...))
+				_ = conn.WriteMessage(websocket.BinaryMessage, append([]byte{stderrChannel}, data[1:]...))
+			case closeChannel:
+				status, _ := json.Marshal(metav1.Status{
+					Status: metav1.StatusFailure,
+					Reason: remotecommand.NonZeroExitCodeReason,
+					Details: &metav1.StatusDetails{
+						Causes: []metav1.StatusCause{{Type: remotecommand.ExitCodeCauseType, Message: "3"}},
+					},
+				})
+				_ = conn.WriteMessage(websocket.BinaryMessage, append([]byte{errorChannel}, status...))
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	}
+}
+
+func TestShouldStreamExecOverWebsocket(t *testing.T) {
+	server := httptest.NewServer(echoExecHandler(t))
+	defer server.Close()
+
+	execURL, _ := url.Parse(server.URL + "/api/v1/namespaces/ns/pods/pod/exec")
+	executor := newWebsocketExecutor(&rest.Config{Host: server.URL, BearerToken: "token"}, execURL)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := executor.Stream(clientremotecommand.StreamOptions{
+		Stdin:  strings.NewReader("hello"),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+
+	exitErr, ok := err.(exec.CodeExitError)
+	assert.True(t, ok, "exit error is expected, got %v", err)
+	assert.Equal(t, 3, exitErr.Code)
+	assert.Equal(t, "hello", stdout.String())
+	assert.Equal(t, "hello", stderr.String())
+}
+
+func TestShouldFallbackWhenWebsocketIsNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	execURL, _ := url.Parse(server.URL + "/api/v1/namespaces/ns/pods/pod/exec")
+	fallbackErr := errors.New("streamed over fallback")
+	executor := &fallbackExecutor{
+		closer:     newStreamCloser(),
+		negotiated: &negotiatedTransport{lock: &sync.Mutex{}},
+		primary:    newWebsocketExecutor(&rest.Config{Host: server.URL}, execURL),
+		fallback: func() (clientremotecommand.Executor, error) {
+			return &failingExecutor{err: fallbackErr}, nil
+		},
+	}
+
+	err := executor.Stream(clientremotecommand.StreamOptions{Stdout: &bytes.Buffer{}})
+
+	assert.Equal(t, fallbackErr, err)
+	assert.Equal(t, cfg.SPDYExecTransport, executor.negotiated.get())
+
+	// fallback is remembered, so websocket is not dialed anymore
+	server.Close()
+	err = executor.Stream(clientremotecommand.StreamOptions{Stdout: &bytes.Buffer{}})
+
+	assert.Equal(t, fallbackErr, err)
+}
+
+func TestShouldNotFallbackWhenUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	execURL, _ := url.Parse(server.URL + "/api/v1/namespaces/ns/pods/pod/exec")
+	executor := &fallbackExecutor{
+		closer:     newStreamCloser(),
+		negotiated: &negotiatedTransport{lock: &sync.Mutex{}},
+		primary:    newWebsocketExecutor(&rest.Config{Host: server.URL}, execURL),
+		fallback: func() (clientremotecommand.Executor, error) {
+			return &failingExecutor{err: errors.New("streamed over fallback")}, nil
+		},
+	}
+
+	err := executor.Stream(clientremotecommand.StreamOptions{Stdout: &bytes.Buffer{}})
+
+	assert.Contains(t, err.Error(), "403")
+	assert.Equal(t, "", executor.negotiated.get())
+}
+
+func TestShouldStopStreamingWhenClosed(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{StreamProtocolV5}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// exec never completes
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	execURL, _ := url.Parse(server.URL + "/api/v1/namespaces/ns/pods/pod/exec")
+	executor := newWebsocketExecutor(&rest.Config{Host: server.URL}, execURL)
+
+	streamEnd := make(chan error, 1)
+	go func() {
+		streamEnd <- executor.Stream(clientremotecommand.StreamOptions{Stdout: &bytes.Buffer{}})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	_ = executor.Close()
+
+	select {
+	case err := <-streamEnd:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("streaming is not stopped")
+	}
+	assert.Equal(t, errStreamClosed, executor.Stream(clientremotecommand.StreamOptions{Stdout: &bytes.Buffer{}}))
+}
+
+func TestShouldDecodeSuccessStatus(t *testing.T) {
+	status, _ := json.Marshal(metav1.Status{Status: metav1.StatusSuccess})
+
+	assert.Nil(t, decodeStatus(status))
+}
+
+type failingExecutor struct {
+	err error
+}
+
+func (executor *failingExecutor) Stream(options clientremotecommand.StreamOptions) error {
+	return executor.err
+}
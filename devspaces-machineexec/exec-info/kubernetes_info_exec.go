@@ -16,6 +16,7 @@ import (
 	"bytes"
 	"time"
 
+	"github.com/eclipse-che/che-machine-exec/client"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
@@ -79,6 +80,10 @@ func NewKubernetesInfoExec(
 
 // Start new kubernetes info exec.
 func (exec *KubernetesInfoExec) Start() (err error) {
+	if err := allowInfoExec(); err != nil {
+		return err
+	}
+
 	req := exec.core.RESTClient().
 		Post().
 		Namespace(exec.namespace).
@@ -97,7 +102,7 @@ func (exec *KubernetesInfoExec) Start() (err error) {
 			TTY: false,
 		}, scheme.ParameterCodec)
 
-	executor, err := remotecommand.NewSPDYExecutor(exec.config, Post, req.URL())
+	executor, err := client.NewExecutor(exec.config, Post, req.URL())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec_info
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Info exec which is spawned as a local process of the machine exec.
+// Like kubernetes info exec it is not "tty", doesn't provide input
+// and is considered to be completed after 3 seconds.
+type LocalInfoExec struct {
+	// command with arguments
+	command []string
+
+	// stdOut/stdErr buffers
+	stdOut *bytes.Buffer
+	stdErr *bytes.Buffer
+}
+
+// Component to creation new info execs as local processes.
+type LocalInfoExecCreator struct{}
+
+// Return new instance of the local info exec creator.
+func NewLocalInfoExecCreator() *LocalInfoExecCreator {
+	return &LocalInfoExecCreator{}
+}
+
+// Create new local info exec. Container info is ignored since there is the only local container.
+func (*LocalInfoExecCreator) CreateInfoExec(command []string, _ *model.ContainerInfo) InfoExec {
+	var stdOut, stdErr bytes.Buffer
+	return &LocalInfoExec{
+		command: command,
+		stdOut:  &stdOut,
+		stdErr:  &stdErr,
+	}
+}
+
+// Start new local info exec.
+func (infoExec *LocalInfoExec) Start() error {
+	if len(infoExec.command) == 0 {
+		return errors.New("command is empty")
+	}
+	if err := allowInfoExec(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, infoExec.command[0], infoExec.command[1:]...)
+	cmd.Stdout = infoExec.stdOut
+	cmd.Stderr = infoExec.stdErr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded && infoExec.stdErr.Len() == 0 {
+		//process is successfully started but hangs
+		return nil
+	}
+
+	if infoExec.stdErr.Len() != 0 {
+		errOutput := infoExec.stdErr.String()
+		logrus.Debugf("Test command is finished with errors. Stderr: %s", errOutput)
+		return errors.New(errOutput)
+	}
+
+	if err != nil {
+		logrus.Debugf("Failed to run test command. Cause: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Get exec output content.
+func (infoExec *LocalInfoExec) GetOutput() string {
+	return infoExec.stdOut.String()
+}
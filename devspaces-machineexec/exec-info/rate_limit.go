@@ -0,0 +1,43 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec_info
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// limiter of the info execs spawning, it is created on the first use when configuration is parsed.
+	infoExecLimiter     *rate.Limiter
+	infoExecLimiterOnce sync.Once
+)
+
+// allowInfoExec returns error if info execs are spawned faster than configured.
+func allowInfoExec() error {
+	infoExecLimiterOnce.Do(func() {
+		limit := rate.Inf
+		if cfg.InfoExecRate > 0 {
+			limit = rate.Limit(cfg.InfoExecRate)
+		}
+		infoExecLimiter = rate.NewLimiter(limit, cfg.InfoExecBurst)
+	})
+
+	if !infoExecLimiter.Allow() {
+		return errors.New("too many info execs are spawned, try again later")
+	}
+	return nil
+}
@@ -34,7 +34,13 @@ func saveActivity(machineExec *model.MachineExec, stopActivitySaver chan bool) {
 		case <-stopActivitySaver:
 			return
 		case <-ticker.C:
-			machineExec.MsgChan <- make([]byte, 0)
+			select {
+			case machineExec.MsgChan <- make([]byte, 0):
+			case <-stopActivitySaver:
+				return
+			case <-machineExec.StopChan:
+				return
+			}
 		}
 	}
 }
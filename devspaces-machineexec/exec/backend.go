@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"io"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/process"
+)
+
+// ExecBackend creates exec processes in the environment where execs are running.
+// Exec manager uses backend to create execs and manages their life cycle itself.
+type ExecBackend interface {
+	// Resolve resolves exec info
+	// the first container with available shell will be picked up if omit
+	Resolve(container, token string) (*model.ResolvedExec, error)
+
+	// Create resolves container and command of the exec defined by machine exec model object
+	// and returns executor to run it. Resolved command and container are set to the machine exec.
+	Create(machineExec *model.MachineExec) (process.Executor, error)
+
+	// CreateInContainer resolves command of the exec in the already resolved container and returns executor
+	// to run it. Container is used as is, e.g. the debug container which is not listed by the workspace pods yet.
+	CreateInContainer(machineExec *model.MachineExec, containerInfo *model.ContainerInfo) (process.Executor, error)
+
+	// Create a kubeconfig
+	CreateKubeConfig(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error
+
+	// CreateDebugContainer adds ephemeral debug container which targets process namespace of the target container
+	// to the workspace pod and waits until it is running. Running debug container is reused if it already exists.
+	CreateDebugContainer(machineExec *model.MachineExec, target string) (*model.ContainerInfo, error)
+
+	// List available containers
+	ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerMetadata, error)
+
+	// StreamCommand runs command in the container with the permissions of the token owner, without tty,
+	// and streams its input and output. The first available container is used if container is empty.
+	StreamCommand(token string, container string, command []string, options process.StreamOptions) error
+
+	// PortForward tunnels the stream to the port of the container with the permissions of the token owner
+	// until either side closes the connection. The first available container is used if container is empty.
+	PortForward(token string, container string, port int, stream io.ReadWriter) error
+
+	// RunCommand runs command in the container on behalf of the machine exec itself, without tty and input.
+	// Returns combined output of the command. Fails if command doesn't complete within timeout.
+	RunCommand(container string, command []string, timeout time.Duration) (string, error)
+}
@@ -13,8 +13,12 @@
 package exec
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +29,14 @@ import (
 	"github.com/eclipse-che/che-machine-exec/shell"
 )
 
+// argvWrapper changes directory and exports env variables passed as positional parameters
+// and replaces itself with the command, so none of them is interpreted by shell.
+// Parameters are: directory, amount of the env variables, NAME=value pairs and argv.
+const argvWrapper = `[ -z "$1" ] || cd -- "$1" || exit 1; shift; n=$1; shift; ` +
+	`while [ "$n" -gt 0 ]; do export "$1"; shift; n=$((n-1)); done; exec "$@"`
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // CmdResolver resolves exec command - MachineExec#Cmd. Needed to patch command
 // to apply some features which missed up in the original kubernetes exec api.
 type CmdResolver struct {
@@ -45,6 +57,13 @@ func NewCmdResolver(k8sAPI *client.K8sAPI, namespace string) *CmdResolver {
 // Gets original command from exec model(MachineExec#Cmd) and returns patched command
 // to support some features which original kubernetes api doesn't provide.
 func (cmdRslv *CmdResolver) ResolveCmd(exec model.MachineExec, containerInfo *model.ContainerInfo) (resolvedCmd []string, err error) {
+	if exec.Type == model.ArgvExecType {
+		return resolveArgv(exec)
+	}
+	if len(exec.Env) > 0 || exec.Stdin != nil {
+		return nil, errors.New("env and stdin are supported by " + model.ArgvExecType + " exec type only")
+	}
+
 	var (
 		shell, cdCommand string
 		cmd              = exec.Cmd
@@ -74,17 +93,50 @@ func (cmdRslv *CmdResolver) ResolveCmd(exec model.MachineExec, containerInfo *mo
 	}
 
 	if exec.Cwd != "" {
-		if strings.HasPrefix(exec.Cwd, "file://") {
-			if res, err := url.Parse(exec.Cwd); err == nil {
-				exec.Cwd = res.Path
-			}
-		}
-		cdCommand = fmt.Sprintf("cd %s; ", exec.Cwd)
+		cdCommand = fmt.Sprintf("cd %s; ", resolveCwd(exec.Cwd))
 	}
 
 	return []string{shell, "-c", cdCommand + strings.Join(cmd, " ")}, nil
 }
 
+// resolveArgv returns argv to run as is if neither directory nor env variables are set.
+// Otherwise argv is run by the shell wrapper which passes all the values as positional parameters.
+func resolveArgv(exec model.MachineExec) ([]string, error) {
+	if len(exec.Cmd) == 0 {
+		return nil, errors.New("cmd of " + model.ArgvExecType + " exec must not be empty")
+	}
+
+	cwd := resolveCwd(exec.Cwd)
+	if cwd == "" && len(exec.Env) == 0 {
+		return exec.Cmd, nil
+	}
+
+	names := make([]string, 0, len(exec.Env))
+	for name := range exec.Env {
+		if !envNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid env variable name '%s'", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolvedCmd := []string{shell.DefaultShell, "-c", argvWrapper, shell.DefaultShell, cwd, strconv.Itoa(len(names))}
+	for _, name := range names {
+		resolvedCmd = append(resolvedCmd, name+"="+exec.Env[name])
+	}
+	return append(resolvedCmd, exec.Cmd...), nil
+}
+
+// resolveCwd converts file URI to the path.
+func resolveCwd(cwd string) string {
+	if strings.HasPrefix(cwd, "file://") {
+		if res, err := url.Parse(cwd); err == nil {
+			return res.Path
+		}
+	}
+	return cwd
+}
+
 func (cmdRslv *CmdResolver) setUpExecShellPath(exec model.MachineExec, containerInfo *model.ContainerInfo) (shellPath string, err error) {
 	if containerShell, err := cmdRslv.DetectShell(containerInfo); err == nil && cmdRslv.shellIsDefined(containerShell) {
 		logrus.Debugf("Default shell %s for %s/%s is detected in /etc/passwd", containerShell, containerInfo.PodName, containerInfo.ContainerName)
@@ -247,3 +247,58 @@ func TestThrowAnErrorWhenEvenDefaultShellDoesNotWork(t *testing.T) {
 
 	assert.Equal(t, err, actualErr)
 }
+
+func TestShouldRunArgvAsIsWithoutCwdAndEnv(t *testing.T) {
+	exec := model.MachineExec{
+		Type: model.ArgvExecType,
+		Cmd:  []string{"echo", "$(rm -rf /)", "; ls"},
+	}
+
+	cmdResolver := &CmdResolver{&mocks.ContainerShellDetector{}, &mocks.InfoExecCreator{}}
+	resolvedCmd, err := cmdResolver.ResolveCmd(exec, containerInfo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"echo", "$(rm -rf /)", "; ls"}, resolvedCmd)
+}
+
+func TestShouldPassArgvCwdAndEnvAsPositionalParameters(t *testing.T) {
+	exec := model.MachineExec{
+		Type: model.ArgvExecType,
+		Cmd:  []string{"mvn", "clean install"},
+		Cwd:  "file:///projects/my%20project",
+		Env:  map[string]string{"MAVEN_OPTS": "-Xmx1g; reboot", "A": "1"},
+	}
+
+	cmdResolver := &CmdResolver{&mocks.ContainerShellDetector{}, &mocks.InfoExecCreator{}}
+	resolvedCmd, err := cmdResolver.ResolveCmd(exec, containerInfo)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sh", "-c", argvWrapper, "sh", "/projects/my project", "2",
+		"A=1", "MAVEN_OPTS=-Xmx1g; reboot", "mvn", "clean install"}, resolvedCmd)
+}
+
+func TestShouldRejectInvalidArgvEnvName(t *testing.T) {
+	exec := model.MachineExec{
+		Type: model.ArgvExecType,
+		Cmd:  []string{"ls"},
+		Env:  map[string]string{"A=B; C": "1"},
+	}
+
+	cmdResolver := &CmdResolver{&mocks.ContainerShellDetector{}, &mocks.InfoExecCreator{}}
+	_, err := cmdResolver.ResolveCmd(exec, containerInfo)
+
+	assert.NotNil(t, err)
+}
+
+func TestShouldRejectEnvForShellCommand(t *testing.T) {
+	exec := model.MachineExec{
+		Type: "shell",
+		Cmd:  []string{"sh", "-l"},
+		Env:  map[string]string{"A": "1"},
+	}
+
+	cmdResolver := &CmdResolver{&mocks.ContainerShellDetector{}, &mocks.InfoExecCreator{}}
+	_, err := cmdResolver.ResolveCmd(exec, containerInfo)
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,164 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	exec_info "github.com/eclipse-che/che-machine-exec/exec-info"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// prefix of the ephemeral debug container names
+	debugContainerPrefix = "debugger-"
+
+	debugContainerStartTimeout = 2 * time.Minute
+	debugContainerPollInterval = time.Second
+)
+
+// CreateDebugContainer adds ephemeral debug container to the workspace pod and waits until it is running.
+// Pod is patched with the permissions of the user, so ephemeral containers have to be allowed for the user.
+func (backend *KubernetesBackend) CreateDebugContainer(machineExec *model.MachineExec, target string) (*model.ContainerInfo, error) {
+	if cfg.DebugImage == "" {
+		return nil, errors.New("debug containers are disabled, debug image is not configured")
+	}
+
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return nil, err
+	}
+
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	var targetInfo *model.ContainerInfo
+	if target != "" {
+		if targetInfo, err = containerFilter.GetContainer(target); err != nil {
+			return nil, err
+		}
+	} else {
+		containersInfo, err := containerFilter.GetContainerList()
+		if err != nil {
+			return nil, err
+		}
+		if len(containersInfo) == 0 {
+			return nil, errors.New("no containers found to debug")
+		}
+		targetInfo = containersInfo[0]
+	}
+
+	pods := k8sAPI.GetClient().CoreV1().Pods(backend.namespace)
+	pod, err := pods.Get(context.TODO(), targetInfo.PodName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	name := findDebugContainer(pod, targetInfo.ContainerName, cfg.DebugImage)
+	if name != "" {
+		logrus.Debugf("Reusing debug container %s/%s", pod.Name, name)
+		return &model.ContainerInfo{PodName: pod.Name, ContainerName: name}, nil
+	}
+
+	debugContainer := newDebugContainer(targetInfo.ContainerName, cfg.DebugImage)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"ephemeralContainers": []v1.EphemeralContainer{debugContainer},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sAPI.GetClient().CoreV1().RESTClient().
+		Patch(types.StrategicMergePatchType).
+		Namespace(backend.namespace).
+		Resource(exec_info.Pods).
+		Name(pod.Name).
+		SubResource("ephemeralcontainers").
+		Body(patch).
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		return nil, fmt.Errorf("unable to add debug container to the pod %s. Cause: %s", pod.Name, err.Error())
+	}
+	logrus.Printf("Debug container %s/%s targeting %s is added", pod.Name, debugContainer.Name, targetInfo.ContainerName)
+
+	err = wait.PollImmediate(debugContainerPollInterval, debugContainerStartTimeout, func() (bool, error) {
+		pod, err := pods.Get(context.TODO(), targetInfo.PodName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isDebugContainerRunning(pod, debugContainer.Name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("debug container %s/%s is not started. Cause: %s", pod.Name, debugContainer.Name, err.Error())
+	}
+
+	return &model.ContainerInfo{PodName: pod.Name, ContainerName: debugContainer.Name}, nil
+}
+
+// newDebugContainer creates ephemeral container which shares process namespace with the target container.
+// Container keeps running while its tty is open, terminals are created by execs into it.
+func newDebugContainer(target string, image string) v1.EphemeralContainer {
+	return v1.EphemeralContainer{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{
+			Name:                     debugContainerPrefix + strconv.FormatInt(time.Now().UnixNano(), 36),
+			Image:                    image,
+			ImagePullPolicy:          v1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+		},
+		TargetContainerName: target,
+	}
+}
+
+// findDebugContainer returns name of the running debug container with the image which targets the container.
+// Returns empty string if there is no such container.
+func findDebugContainer(pod *v1.Pod, target string, image string) string {
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.TargetContainerName != target || container.Image != image {
+			continue
+		}
+		if running, err := isDebugContainerRunning(pod, container.Name); running && err == nil {
+			return container.Name
+		}
+	}
+	return ""
+}
+
+// isDebugContainerRunning checks status of the ephemeral container.
+// Returns error if container is terminated, so it will never run.
+func isDebugContainerRunning(pod *v1.Pod, name string) (bool, error) {
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name != name {
+			continue
+		}
+		if status.State.Terminated != nil {
+			return false, fmt.Errorf("container is terminated with reason %s", status.State.Terminated.Reason)
+		}
+		return status.State.Running != nil, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/process"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+const debugImage = "quay.io/debug/image"
+
+func createDebugPod(state v1.ContainerState) (*v1.Pod, string) {
+	debugContainer := newDebugContainer("tools", debugImage)
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{EphemeralContainers: []v1.EphemeralContainer{debugContainer}},
+		Status: v1.PodStatus{EphemeralContainerStatuses: []v1.ContainerStatus{
+			{Name: debugContainer.Name, State: state},
+		}},
+	}
+	return pod, debugContainer.Name
+}
+
+func TestShouldCreateDebugContainerTargetingContainer(t *testing.T) {
+	debugContainer := newDebugContainer("tools", debugImage)
+
+	assert.True(t, strings.HasPrefix(debugContainer.Name, debugContainerPrefix))
+	assert.Equal(t, "tools", debugContainer.TargetContainerName)
+	assert.Equal(t, debugImage, debugContainer.Image)
+	assert.True(t, debugContainer.Stdin)
+	assert.True(t, debugContainer.TTY)
+}
+
+func TestShouldReuseRunningDebugContainer(t *testing.T) {
+	pod, name := createDebugPod(v1.ContainerState{Running: &v1.ContainerStateRunning{}})
+
+	assert.Equal(t, name, findDebugContainer(pod, "tools", debugImage))
+	assert.Equal(t, "", findDebugContainer(pod, "other", debugImage))
+	assert.Equal(t, "", findDebugContainer(pod, "tools", "other/image"))
+}
+
+func TestShouldNotReuseTerminatedDebugContainer(t *testing.T) {
+	pod, name := createDebugPod(v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error"}})
+
+	assert.Equal(t, "", findDebugContainer(pod, "tools", debugImage))
+	running, err := isDebugContainerRunning(pod, name)
+	assert.False(t, running)
+	assert.NotNil(t, err)
+}
+
+func TestShouldWaitForDebugContainerToStart(t *testing.T) {
+	pod, name := createDebugPod(v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}})
+
+	running, err := isDebugContainerRunning(pod, name)
+	assert.False(t, running)
+	assert.Nil(t, err)
+}
+
+// debugBackend runs debug execs as local processes and doesn't resolve containers by name.
+type debugBackend struct {
+	*LocalBackend
+}
+
+func (backend *debugBackend) CreateDebugContainer(*model.MachineExec, string) (*model.ContainerInfo, error) {
+	return &backend.container, nil
+}
+
+func (*debugBackend) Create(*model.MachineExec) (process.Executor, error) {
+	return nil, errors.New("debug container is not found")
+}
+
+func TestShouldCreateDebugExecInResolvedContainer(t *testing.T) {
+	backend := &debugBackend{LocalBackend: NewLocalBackend()}
+	manager := NewExecManager(backend)
+	machineExec := &model.MachineExec{Cmd: []string{"sh"}}
+
+	id, err := manager.CreateDebug(machineExec, "tools")
+	assert.Nil(t, err)
+	defer manager.Remove(id)
+
+	assert.True(t, machineExec.Tty)
+	assert.Equal(t, backend.container, machineExec.Container)
+}
@@ -13,15 +13,24 @@
 package exec
 
 import (
+	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
 	"github.com/eclipse-che/che-machine-exec/client"
+	"github.com/eclipse-che/che-machine-exec/process"
+	ws "github.com/eclipse-che/che-machine-exec/ws-conn"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
-var execManager ExecManager
+var (
+	execManager     ExecManager
+	execManagerOnce sync.Once
+)
 
 // ExecManager to manage exec life cycle.
 type ExecManager interface {
@@ -32,6 +41,10 @@ type ExecManager interface {
 	// Create new Exec defined by machine exec model object.
 	Create(machineExec *model.MachineExec) (int, error)
 
+	// CreateDebug creates terminal exec in the ephemeral debug container which targets the container.
+	// Useful for the containers without shell.
+	CreateDebug(machineExec *model.MachineExec, target string) (int, error)
+
 	// Remove information about exec by ExecId.
 	// It's can be useful in case exec error or exec exit.
 	Remove(execId int)
@@ -40,25 +53,70 @@ type ExecManager interface {
 	Check(id int) (int, error)
 
 	// Attach simple websocket connection to the exec stdIn/stdOut by unique exec id.
-	Attach(id int, conn *websocket.Conn) error
+	// Output retained since replayFrom offset is sent to the connection first.
+	// Connection of the client without full access is attached as viewer, its input is dropped.
+	Attach(id int, conn *websocket.Conn, replayFrom int64, client ws.Client) error
+
+	// Attach client connection to the exec output by unique exec id. Doesn't block while exec is running.
+	// Exec input should be sent with Input. Connection of the client without full access is attached as viewer.
+	AttachConnection(id int, conn ws.Connection, replayFrom int64, client ws.Client) error
+
+	// List viewer connections attached to the exec by unique exec id.
+	ListViewers(id int) ([]*ws.Viewer, error)
+
+	// Detach viewer connection from the exec by unique exec id and viewer id.
+	RevokeViewer(id int, viewerID int) error
 
-	// Resize exec by unique id.
+	// Detach client connection from the exec output by unique exec id.
+	DetachConnection(id int, conn ws.Connection)
+
+	// Send input to the exec by unique exec id.
+	Input(id int, data []byte) error
+
+	// Kill exec by unique id. Exec process is killed, or its input is closed
+	// when the process can't be signalled, so shell exits on end of input.
+	Kill(id int) error
+
+	// Resize exec by unique id. Viewers are notified about new terminal size.
 	Resize(id int, cols uint, rows uint) error
 
 	// Create a kubeconfig
 	CreateKubeConfig(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error
 
 	// List available containers
-	ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerInfo, error)
+	ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerMetadata, error)
+
+	// List live execs of the caller user the client is able to reattach to.
+	ListSessions(caller *model.MachineExec) []*model.SessionInfo
+
+	// Stats returns amount of the live execs, their age and transferred bytes.
+	Stats() *model.ExecStats
+
+	// Terminate asks processes of the live execs to exit and waits for them until timeout.
+	// Returns amount of the live execs, how many of them were signalled and how many of them exited.
+	Terminate(timeout time.Duration) (live int, signalled int, exited int)
+
+	// StreamCommand runs command in the container with the permissions of the token owner and streams its input and output.
+	StreamCommand(token string, container string, command []string, options process.StreamOptions) error
+
+	// PortForward tunnels the stream to the port of the container with the permissions of the token owner.
+	PortForward(token string, container string, port int, stream io.ReadWriter) error
+
+	// RunCommand runs command in the container on behalf of the machine exec and returns its output.
+	RunCommand(container string, command []string, timeout time.Duration) (string, error)
 }
 
-// CreateExecManager creates and returns new instance ExecManager.
+// CreateExecManager creates and returns new instance ExecManager with the configured exec backend.
 // Fail with panic if it is impossible.
 func CreateExecManager() (exeManager ExecManager) {
+	if cfg.ExecBackend == cfg.LocalExecBackend {
+		return NewExecManager(NewLocalBackend())
+	}
+
 	if isValidKubernetesInfra() {
 		namespace := GetNamespace()
 		k8sAPIProvider := client.NewK8sAPIProvider()
-		return NewK8sExecManager(namespace, *k8sAPIProvider)
+		return NewExecManager(NewK8sBackend(namespace, *k8sAPIProvider))
 	}
 
 	logrus.Panic("Error: Unable to create manager. Unable to get service account info.")
@@ -68,9 +126,9 @@ func CreateExecManager() (exeManager ExecManager) {
 
 // GetExecManager returns instance exec manager
 func GetExecManager() ExecManager {
-	if execManager == nil {
+	execManagerOnce.Do(func() {
 		execManager = CreateExecManager()
-	}
+	})
 	return execManager
 }
 
@@ -0,0 +1,511 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/eclipse-che/che-machine-exec/output/scrollback"
+	"github.com/eclipse-che/che-machine-exec/output/utf8stream"
+	"github.com/eclipse-che/che-machine-exec/process"
+	ws "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+type machineExecs struct {
+	mutex   *sync.Mutex
+	execMap map[int]*model.MachineExec
+}
+
+// ExecManagerImpl manages life cycle of the execs created by the exec backend.
+type ExecManagerImpl struct {
+	backend ExecBackend
+}
+
+var (
+	execs = machineExecs{
+		mutex:   &sync.Mutex{},
+		execMap: make(map[int]*model.MachineExec),
+	}
+	prevExecID uint64 = 0
+)
+
+// NewExecManager create new instance of the exec manager which creates execs with the backend.
+func NewExecManager(backend ExecBackend) *ExecManagerImpl {
+	return &ExecManagerImpl{backend: backend}
+}
+
+func (manager *ExecManagerImpl) Resolve(container, token string) (*model.ResolvedExec, error) {
+	return manager.backend.Resolve(container, token)
+}
+
+// Create new exec request object
+func (manager *ExecManagerImpl) Create(machineExec *model.MachineExec) (int, error) {
+	if err := allowCreate(); err != nil {
+		return -1, err
+	}
+	if err := allowExec(machineExec); err != nil {
+		return -1, err
+	}
+	return manager.create(machineExec)
+}
+
+// Create terminal exec in the ephemeral debug container
+func (manager *ExecManagerImpl) CreateDebug(machineExec *model.MachineExec, target string) (int, error) {
+	if err := allowCreate(); err != nil {
+		return -1, err
+	}
+	if err := allowExec(machineExec); err != nil {
+		return -1, err
+	}
+
+	containerInfo, err := manager.backend.CreateDebugContainer(machineExec, target)
+	if err != nil {
+		return -1, err
+	}
+	machineExec.Identifier.MachineName = containerInfo.ContainerName
+	machineExec.Tty = true
+
+	return manager.createIn(machineExec, func() (process.Executor, error) {
+		// debug container is just started, so it is not looked up in the workspace pods
+		return manager.backend.CreateInContainer(machineExec, containerInfo)
+	})
+}
+
+func (manager *ExecManagerImpl) create(machineExec *model.MachineExec) (int, error) {
+	return manager.createIn(machineExec, func() (process.Executor, error) {
+		return manager.backend.Create(machineExec)
+	})
+}
+
+// createIn registers the exec with the executor created by the backend.
+func (manager *ExecManagerImpl) createIn(machineExec *model.MachineExec, createExecutor func() (process.Executor, error)) (int, error) {
+	executor, err := createExecutor()
+	if err != nil {
+		return -1, err
+	}
+
+	defer execs.mutex.Unlock()
+	execs.mutex.Lock()
+
+	// limits are checked again with the resolved container, other execs could be created meanwhile.
+	// Process is not started yet
+	if err := checkExecsLimits(machineExec); err != nil {
+		return -1, err
+	}
+
+	machineExec.Executor = executor
+	machineExec.ID = int(atomic.AddUint64(&prevExecID, 1))
+	machineExec.MsgChan = make(chan []byte)
+	machineExec.SizeChan = make(chan process.TerminalSize, 1)
+	// exit and error are not awaited by the health watcher of the expired exec
+	machineExec.ExitChan = make(chan bool, 1)
+	machineExec.ErrorChan = make(chan error, 1)
+	machineExec.ExpiredChan = make(chan bool, 1)
+	machineExec.StopChan = make(chan struct{})
+	machineExec.ConnectionHandler = ws.NewConnHandler()
+	machineExec.Buffer = scrollback.New(cfg.ScrollbackSize)
+	machineExec.CreatedAt = time.Now()
+
+	machineExec.SizeChan <- process.TerminalSize{Width: uint16(machineExec.Cols), Height: uint16(machineExec.Rows)}
+	if machineExec.Tty && machineExec.Cols > 0 && machineExec.Rows > 0 {
+		// no viewers are attached yet, size is remembered for them
+		machineExec.WriteResizeToViewers(uint(machineExec.Cols), uint(machineExec.Rows))
+	}
+
+	execs.execMap[machineExec.ID] = machineExec
+	sessions.register(machineExec, cfg.SessionGracePeriod)
+	metrics.ExecCreated()
+
+	return machineExec.ID, nil
+}
+
+// Remove information about exec
+func (*ExecManagerImpl) Remove(execID int) {
+	defer execs.mutex.Unlock()
+
+	execs.mutex.Lock()
+	if _, ok := execs.execMap[execID]; ok {
+		delete(execs.execMap, execID)
+		metrics.ExecRemoved()
+	}
+	sessions.remove(execID)
+}
+
+// Check if exec with id exists
+func (*ExecManagerImpl) Check(id int) (int, error) {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		logrus.Debugf("Exec '%d' was not found", id)
+		return -1, errors.New("Exec '" + strconv.Itoa(id) + "' was not found")
+	}
+	logrus.Debugf("Exec was found after check: %d", id)
+	return machineExec.ID, nil
+}
+
+// Attach websoket connection to the exec by id.
+// Output retained since replayFrom offset is sent to the connection before the live output.
+// Connection of the client without full access is attached as viewer, its input is dropped.
+func (*ExecManagerImpl) Attach(id int, conn *websocket.Conn, replayFrom int64, client ws.Client) error {
+	machineExec, err := replay(id, replayFrom, client, func(machineExec *model.MachineExec, content []byte) error {
+		if len(content) > 0 {
+			if err := conn.WriteMessage(websocket.TextMessage, content); err != nil {
+				return err
+			}
+		}
+		machineExec.ReadConnection(conn, machineExec.MsgChan, client)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !sessions.startStreaming(id) {
+		return nil
+	}
+
+	return stream(machineExec)
+}
+
+// Attach client connection to the exec output by id. Exec input should be sent with Input.
+// Output retained since replayFrom offset is sent to the connection before the live output.
+// Unlike Attach it does not block while exec is running.
+// Connection of the client without full access is attached as viewer.
+func (*ExecManagerImpl) AttachConnection(id int, conn ws.Connection, replayFrom int64, client ws.Client) error {
+	machineExec, err := replay(id, replayFrom, client, func(machineExec *model.MachineExec, content []byte) error {
+		if len(content) > 0 {
+			if err := conn.Write(content); err != nil {
+				return err
+			}
+		}
+		machineExec.AddConnection(conn, client)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if sessions.startStreaming(id) {
+		go func() {
+			if err := stream(machineExec); err != nil {
+				logrus.Debugf("Exec %d is finished with error. Cause: %s", id, err.Error())
+			}
+		}()
+	}
+	return nil
+}
+
+// Detach client connection from the exec output by id.
+func (*ExecManagerImpl) DetachConnection(id int, conn ws.Connection) {
+	if machineExec := getByID(id); machineExec != nil {
+		machineExec.RemoveConnection(conn)
+	}
+}
+
+// List viewer connections attached to the exec by id.
+func (*ExecManagerImpl) ListViewers(id int) ([]*ws.Viewer, error) {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		return nil, errors.New("Exec '" + strconv.Itoa(id) + "' to list viewers was not found")
+	}
+	return machineExec.ListViewers(), nil
+}
+
+// Detach viewer connection from the exec by id.
+func (*ExecManagerImpl) RevokeViewer(id int, viewerID int) error {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		return errors.New("Exec '" + strconv.Itoa(id) + "' to revoke viewer was not found")
+	}
+	return machineExec.RevokeViewer(viewerID)
+}
+
+// Send input to the exec by id.
+func (*ExecManagerImpl) Input(id int, data []byte) error {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		return errors.New("Exec '" + strconv.Itoa(id) + "' to send input was not found")
+	}
+	if machineExec.Stdin != nil {
+		return errors.New("Exec '" + strconv.Itoa(id) + "' input is predefined")
+	}
+
+	select {
+	case machineExec.MsgChan <- data:
+		return nil
+	case <-machineExec.StopChan:
+		return errors.New("Exec '" + strconv.Itoa(id) + "' input is closed")
+	}
+}
+
+// Kill sends kill signal to the exec process. When the backend can't signal the process,
+// exec input is closed, so shell exits on end of input.
+func (*ExecManagerImpl) Kill(id int) error {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		return errors.New("Exec '" + strconv.Itoa(id) + "' to kill was not found")
+	}
+
+	signalOrStop(machineExec, syscall.SIGKILL)
+	return nil
+}
+
+// replay sends output retained since replayFrom offset with subscribe
+// and cancels exec expiry scheduled after connections loss.
+// Nothing is sent to the client whose viewer access to the exec is revoked.
+func replay(id int, replayFrom int64, client ws.Client, subscribe func(machineExec *model.MachineExec, content []byte) error) (*model.MachineExec, error) {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		logrus.Debugf("Exec '%d' to attach was not found", id)
+		return nil, errors.New("Exec '" + strconv.Itoa(id) + "' to attach was not found")
+	}
+	if err := machineExec.CheckRevoked(client); err != nil {
+		return nil, err
+	}
+	logrus.Debugf("Attach to exec %d", id)
+
+	// restore previous output and subscribe connection to the following one.
+	err := machineExec.Buffer.Replay(replayFrom, func(content []byte, _ int64) error {
+		return subscribe(machineExec, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sessions.attach(id)
+
+	return machineExec, nil
+}
+
+// stream connects exec input/output with the attached connections until exec exits.
+func stream(machineExec *model.MachineExec) error {
+	if cfg.RecordingsPath != "" {
+		startRecording(machineExec)
+		defer stopRecording(machineExec)
+	}
+
+	stopActivitySaver := make(chan bool, 1)
+	go saveActivity(machineExec, stopActivitySaver)
+
+	ptyHandler := PtyHandlerImpl{machineExec: machineExec, filter: &utf8stream.Utf8StreamFilter{}}
+
+	// without tty error output is sent to the clients separately
+	var stderr io.Writer = ptyHandler
+	if !machineExec.Tty {
+		stderr = StderrHandlerImpl{machineExec: machineExec, filter: &utf8stream.Utf8StreamFilter{}}
+	}
+
+	var stdin io.Reader = ptyHandler
+	if machineExec.Stdin != nil {
+		stdin = StdinContentHandler{machineExec: machineExec, reader: strings.NewReader(*machineExec.Stdin)}
+	}
+
+	err := machineExec.Executor.Stream(process.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            ptyHandler,
+		Stderr:            stderr,
+		TerminalSizeQueue: ptyHandler,
+		Tty:               machineExec.Tty,
+	})
+
+	stopActivitySaver <- true
+	// release input and terminal size readers of the finished exec
+	sessions.stop(machineExec)
+	machineExec.ConnectionHandler.CloseConnections()
+
+	// non-zero exit code of the process is not an exec error
+	if code, signal, ok := exitStatus(err); ok {
+		machineExec.ExitCode = code
+		machineExec.ExitSignal = signal
+		machineExec.ExitChan <- true
+		return nil
+	}
+
+	machineExec.ErrorChan <- err
+	return err
+}
+
+// Resize exec output frame.
+func (*ExecManagerImpl) Resize(id int, cols uint, rows uint) error {
+	machineExec := getByID(id)
+	if machineExec == nil {
+		return errors.New("Exec to resize '" + strconv.Itoa(id) + "' was not found")
+	}
+
+	machineExec.SizeChan <- process.TerminalSize{Width: uint16(cols), Height: uint16(rows)}
+	machineExec.WriteResizeToViewers(cols, rows)
+	return nil
+}
+
+func (manager *ExecManagerImpl) CreateKubeConfig(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error {
+	return manager.backend.CreateKubeConfig(kubeConfigParams, containerInfo)
+}
+
+// List live execs of the caller user the client is able to reattach to.
+func (*ExecManagerImpl) ListSessions(caller *model.MachineExec) []*model.SessionInfo {
+	execs.mutex.Lock()
+	machineExecs := make([]*model.MachineExec, 0, len(execs.execMap))
+	for _, machineExec := range execs.execMap {
+		if sameUser(machineExec, caller) {
+			machineExecs = append(machineExecs, machineExec)
+		}
+	}
+	execs.mutex.Unlock()
+
+	sort.Slice(machineExecs, func(i, j int) bool {
+		return machineExecs[i].ID < machineExecs[j].ID
+	})
+
+	result := make([]*model.SessionInfo, 0, len(machineExecs))
+	for _, machineExec := range machineExecs {
+		result = append(result, sessions.info(machineExec))
+	}
+	return result
+}
+
+// sameUser tells whether the execs are created by the same user: by user ID if it's known for both of them,
+// by bearer token otherwise.
+func sameUser(machineExec *model.MachineExec, other *model.MachineExec) bool {
+	if machineExec.UserID != "" && other.UserID != "" {
+		return machineExec.UserID == other.UserID
+	}
+	return machineExec.BearerToken == other.BearerToken
+}
+
+// Terminate sends hangup to the terminals and termination signal to the other processes of the live execs
+// and waits for them to exit until timeout. Input of the execs which can't be signalled is closed.
+// Returns amount of the live execs, how many of them were signalled and how many of them exited.
+func (*ExecManagerImpl) Terminate(timeout time.Duration) (live int, signalled int, exited int) {
+	execs.mutex.Lock()
+	machineExecs := make([]*model.MachineExec, 0, len(execs.execMap))
+	for _, machineExec := range execs.execMap {
+		machineExecs = append(machineExecs, machineExec)
+	}
+	execs.mutex.Unlock()
+
+	for _, machineExec := range machineExecs {
+		if signalOrStop(machineExec, terminationSignal(machineExec)) {
+			signalled++
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		exited = 0
+		for _, machineExec := range machineExecs {
+			if getByID(machineExec.ID) == nil {
+				exited++
+			}
+		}
+		if exited == len(machineExecs) || time.Now().After(deadline) {
+			return len(machineExecs), signalled, exited
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// terminationSignal returns hangup for the terminals and termination signal for the other processes.
+func terminationSignal(machineExec *model.MachineExec) syscall.Signal {
+	if machineExec.Tty {
+		return syscall.SIGHUP
+	}
+	return syscall.SIGTERM
+}
+
+// signalOrStop sends signal to the exec process. If the signal can't be delivered, input of the exec is closed,
+// so that shell exits on end of input like on hangup. Returns whether the signal is delivered.
+func signalOrStop(machineExec *model.MachineExec, sig syscall.Signal) bool {
+	if signaler, ok := machineExec.Executor.(process.Signaler); ok {
+		err := signaler.Signal(sig)
+		if err == nil {
+			return true
+		}
+		logrus.Warnf("Unable to send %s to exec %d, closing its input. Cause: %s", sig, machineExec.ID, err.Error())
+	}
+	sessions.stop(machineExec)
+	return false
+}
+
+// StreamCommand runs command in the container with the token owner permissions. It is not tracked like execs.
+func (manager *ExecManagerImpl) StreamCommand(token string, container string, command []string, options process.StreamOptions) error {
+	return manager.backend.StreamCommand(token, container, command, options)
+}
+
+// PortForward tunnels the stream to the port of the container with the token owner permissions.
+// It is not tracked like execs.
+func (manager *ExecManagerImpl) PortForward(token string, container string, port int, stream io.ReadWriter) error {
+	return manager.backend.PortForward(token, container, port, stream)
+}
+
+// RunCommand runs command in the container on behalf of the machine exec. It is not tracked like execs.
+func (manager *ExecManagerImpl) RunCommand(container string, command []string, timeout time.Duration) (string, error) {
+	return manager.backend.RunCommand(container, command, timeout)
+}
+
+// Stats returns resources used by the live execs.
+func (*ExecManagerImpl) Stats() *model.ExecStats {
+	execs.mutex.Lock()
+	machineExecs := make([]*model.MachineExec, 0, len(execs.execMap))
+	for _, machineExec := range execs.execMap {
+		machineExecs = append(machineExecs, machineExec)
+	}
+	execs.mutex.Unlock()
+
+	sort.Slice(machineExecs, func(i, j int) bool {
+		return machineExecs[i].ID < machineExecs[j].ID
+	})
+
+	now := time.Now()
+	stats := &model.ExecStats{
+		Total:      len(machineExecs),
+		Containers: make(map[string]int),
+		Sessions:   make([]*model.SessionStats, 0, len(machineExecs)),
+	}
+	for _, machineExec := range machineExecs {
+		stats.Containers[containerKey(machineExec.Container)]++
+		stats.Sessions = append(stats.Sessions, &model.SessionStats{
+			ID:            machineExec.ID,
+			ContainerInfo: machineExec.Container,
+			CreatedAt:     machineExec.CreatedAt,
+			Age:           int64(now.Sub(machineExec.CreatedAt).Seconds()),
+			Connections:   machineExec.ConnectionsCount(),
+			BytesIn:       atomic.LoadInt64(&machineExec.BytesIn),
+			BytesOut:      atomic.LoadInt64(&machineExec.BytesOut),
+		})
+	}
+	return stats
+}
+
+// getByID return exec by id.
+func getByID(id int) *model.MachineExec {
+	defer execs.mutex.Unlock()
+
+	execs.mutex.Lock()
+	return execs.execMap[id]
+}
+
+// List available containers
+func (manager *ExecManagerImpl) ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerMetadata, error) {
+	return manager.backend.ListAvailableContainers(machineExec)
+}
@@ -0,0 +1,52 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// Shells report process terminated by signal N with exit code 128 + N.
+const signalExitCodeBase = 128
+
+// exitStatus returns exit code and name of the terminating signal of the exec process
+// from the result of the exec streaming. It returns false if streaming is failed
+// by another reason than non-zero exit code of the process. The signal is known only
+// for the local processes: exit code reported by kubernetes doesn't tell whether
+// the process is terminated by signal or exited by itself with the same code.
+func exitStatus(streamErr error) (code int, signal string, ok bool) {
+	if streamErr == nil {
+		return 0, "", true
+	}
+
+	// local process
+	var processErr *exec.ExitError
+	if errors.As(streamErr, &processErr) {
+		if status, isWaitStatus := processErr.Sys().(syscall.WaitStatus); isWaitStatus && status.Signaled() {
+			return signalExitCodeBase + int(status.Signal()), unix.SignalName(status.Signal()), true
+		}
+		return processErr.ExitCode(), "", true
+	}
+
+	var exitErr utilexec.ExitError
+	if !errors.As(streamErr, &exitErr) {
+		return -1, "", false
+	}
+
+	return exitErr.ExitStatus(), "", true
+}
@@ -0,0 +1,72 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestShouldReturnZeroExitCodeOnSuccess(t *testing.T) {
+	code, signal, ok := exitStatus(nil)
+
+	assert.True(t, ok)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", signal)
+}
+
+func TestShouldReturnExitCodeOfFailedProcess(t *testing.T) {
+	code, signal, ok := exitStatus(utilexec.CodeExitError{Err: errors.New("command terminated with exit code 2"), Code: 2})
+
+	assert.True(t, ok)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "", signal)
+}
+
+func TestShouldNotInferSignalFromExitCodeOfRemoteProcess(t *testing.T) {
+	code, signal, ok := exitStatus(utilexec.CodeExitError{Err: errors.New("command terminated with exit code 137"), Code: 137})
+
+	assert.True(t, ok)
+	assert.Equal(t, 137, code)
+	assert.Equal(t, "", signal)
+}
+
+func TestShouldReturnSignalOfKilledLocalProcess(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	assert.Nil(t, cmd.Start())
+	assert.Nil(t, cmd.Process.Kill())
+
+	code, signal, ok := exitStatus(cmd.Wait())
+
+	assert.True(t, ok)
+	assert.Equal(t, 137, code)
+	assert.Equal(t, "SIGKILL", signal)
+}
+
+func TestShouldNotReturnSignalOfLocalProcessExitedWithHighCode(t *testing.T) {
+	code, signal, ok := exitStatus(exec.Command("sh", "-c", "exit 137").Run())
+
+	assert.True(t, ok)
+	assert.Equal(t, 137, code)
+	assert.Equal(t, "", signal)
+}
+
+func TestShouldNotReturnExitStatusOnStreamingError(t *testing.T) {
+	_, _, ok := exitStatus(errors.New("connection refused"))
+
+	assert.False(t, ok)
+}
@@ -34,19 +34,31 @@ func NewHealthWatcher(exec *model.MachineExec, eventBus *event.Bus, manager Exec
 }
 
 // Look at the exec health and clean up application on exec exit/error,
-// sent exit/error event to the subscribed clients
+// sent exit/error event to the subscribed clients.
+// Exec which stays without connections longer than session grace period
+// is cleaned up as well, its process is terminated like on workspace stop.
 func (watcher *HealthWatcher) CleanUpOnExitOrError() {
 	go func() {
-		select {
-		case <-watcher.exec.ExitChan:
-			watcher.manager.Remove(watcher.exec.ID)
-			execExitEvent := &model.ExecExitEvent{ExecId: watcher.exec.ID}
-			watcher.eventBus.Pub(execExitEvent)
+		for {
+			select {
+			case <-watcher.exec.ExitChan:
+				watcher.manager.Remove(watcher.exec.ID)
+				execExitEvent := &model.ExecExitEvent{ExecId: watcher.exec.ID, ExitCode: watcher.exec.ExitCode, Signal: watcher.exec.ExitSignal}
+				watcher.eventBus.Pub(execExitEvent)
+				return
 
-		case err := <-watcher.exec.ErrorChan:
-			watcher.manager.Remove(watcher.exec.ID)
-			execErrorEvent := &model.ExecErrorEvent{ExecId: watcher.exec.ID, Stack: err.Error()}
-			watcher.eventBus.Pub(execErrorEvent)
+			case err := <-watcher.exec.ErrorChan:
+				watcher.manager.Remove(watcher.exec.ID)
+				execErrorEvent := &model.ExecErrorEvent{ExecId: watcher.exec.ID, Stack: err.Error()}
+				watcher.eventBus.Pub(execErrorEvent)
+				return
+
+			case <-watcher.exec.ExpiredChan:
+				// process which ignores end of input would keep running otherwise
+				signalOrStop(watcher.exec, terminationSignal(watcher.exec))
+				watcher.manager.Remove(watcher.exec.ID)
+				return
+			}
 		}
 	}()
 }
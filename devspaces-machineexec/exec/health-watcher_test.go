@@ -14,12 +14,16 @@ package exec
 
 import (
 	"errors"
+	"os"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/eclipse-che/che-machine-exec/api/model"
 	"github.com/eclipse-che/che-machine-exec/mocks"
+	"github.com/eclipse-che/che-machine-exec/process"
 	"github.com/eclipse/che-go-jsonrpc/event"
+	"github.com/stretchr/testify/assert"
 )
 
 const Exec1ID = 0
@@ -55,3 +59,82 @@ func TestShouldCleanUpExecOnError(t *testing.T) {
 
 	execManagerMock.AssertExpectations(t)
 }
+
+// signalingExecutor records the signals sent to the exec process.
+type signalingExecutor struct {
+	signals chan os.Signal
+}
+
+func (executor *signalingExecutor) Stream(options process.StreamOptions) error {
+	return nil
+}
+
+func (executor *signalingExecutor) Signal(sig os.Signal) error {
+	executor.signals <- sig
+	return nil
+}
+
+func TestShouldTerminateAndCleanUpExpiredExec(t *testing.T) {
+	executor := &signalingExecutor{signals: make(chan os.Signal, 1)}
+	machineExec := &model.MachineExec{ID: Exec1ID, Tty: true, Executor: executor, ErrorChan: make(chan error), ExitChan: make(chan bool), ExpiredChan: make(chan bool, 1)}
+	execManagerMock := &mocks.ExecManager{}
+	eventBus := event.NewBus()
+
+	execManagerMock.On("Remove", Exec1ID).Return().Once()
+
+	healthWatcher := NewHealthWatcher(machineExec, eventBus, execManagerMock)
+	healthWatcher.CleanUpOnExitOrError()
+
+	machineExec.ExpiredChan <- true
+
+	select {
+	case sig := <-executor.signals:
+		assert.Equal(t, syscall.SIGHUP, sig)
+	case <-time.After(time.Second):
+		t.Fatal("expired exec is not signalled")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// watcher is not waiting for exit anymore
+	select {
+	case machineExec.ExitChan <- true:
+		t.Fatal("exit of the expired exec is awaited")
+	default:
+	}
+	execManagerMock.AssertExpectations(t)
+}
+
+type exitEventConsumer struct {
+	events chan *model.ExecExitEvent
+}
+
+func (consumer *exitEventConsumer) Accept(e event.E) {
+	if exitEvent, ok := e.(*model.ExecExitEvent); ok {
+		consumer.events <- exitEvent
+	}
+}
+
+func TestShouldPublishExitCodeAndSignalOnExit(t *testing.T) {
+	machineExec := &model.MachineExec{ID: Exec1ID, ErrorChan: make(chan error), ExitChan: make(chan bool)}
+	execManagerMock := &mocks.ExecManager{}
+	eventBus := event.NewBus()
+	consumer := &exitEventConsumer{events: make(chan *model.ExecExitEvent, 1)}
+	eventBus.Sub(consumer, model.OnExecExit)
+
+	execManagerMock.On("Remove", Exec1ID).Return()
+
+	healthWatcher := NewHealthWatcher(machineExec, eventBus, execManagerMock)
+	healthWatcher.CleanUpOnExitOrError()
+
+	machineExec.ExitCode = 143
+	machineExec.ExitSignal = "SIGTERM"
+	machineExec.ExitChan <- true
+
+	select {
+	case exitEvent := <-consumer.events:
+		assert.Equal(t, 143, exitEvent.ExitCode)
+		assert.Equal(t, "SIGTERM", exitEvent.Signal)
+	case <-time.After(time.Second):
+		t.Fatal("exit event is not published")
+	}
+}
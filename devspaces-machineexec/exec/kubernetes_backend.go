@@ -0,0 +1,539 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/client"
+	exec_info "github.com/eclipse-che/che-machine-exec/exec-info"
+	"github.com/eclipse-che/che-machine-exec/filter"
+	"github.com/eclipse-che/che-machine-exec/kubeconfig"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/eclipse-che/che-machine-exec/process"
+	"github.com/eclipse-che/che-machine-exec/shell"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// signalTimeout is how long it might take to deliver signal to the exec process in the container.
+const signalTimeout = 10 * time.Second
+
+// pidDir is the directory under $HOME of the container user where process ids of the execs are recorded.
+const pidDir = ".machine-exec"
+
+// pidRecordingShells are the shells which are able to record the exec process id before running the command.
+var pidRecordingShells = map[string]bool{"sh": true, "bash": true, "dash": true, "ash": true, "ksh": true, "zsh": true}
+
+// KubernetesBackend creates execs in the kubernetes containers of the workspace pod.
+type KubernetesBackend struct {
+	k8sAPIProvider client.K8sAPIProvider
+
+	namespace string
+
+	// containerCache and shellCache are nil if container cache is disabled or can't be started.
+	containerCache *filter.ContainerCache
+	shellCache     *shell.ShellCache
+}
+
+// NewK8sBackend create new instance of the kubernetes exec backend.
+func NewK8sBackend(
+	namespace string,
+	clientProvider client.K8sAPIProvider,
+) *KubernetesBackend {
+	backend := &KubernetesBackend{
+		namespace:      namespace,
+		k8sAPIProvider: clientProvider,
+	}
+	if cfg.ContainerCache {
+		backend.startContainerCache()
+	}
+	return backend
+}
+
+// startContainerCache starts watching the workspace pods with service account permissions.
+// Shells detected in the containers are cached until the containers are restarted.
+func (backend *KubernetesBackend) startContainerCache() {
+	k8sAPI, err := backend.k8sAPIProvider.GetServiceAccountK8sAPI()
+	if err != nil {
+		logrus.Warnf("Unable to start container cache, workspace pods are listed on each request. Cause: %s", err.Error())
+		return
+	}
+
+	backend.shellCache = shell.NewShellCache()
+	backend.containerCache = filter.NewContainerCache(backend.namespace, k8sAPI.GetClient().CoreV1())
+	backend.containerCache.AddRestartHandler(backend.shellCache.Invalidate)
+	// cache is kept during the whole machine exec lifetime
+	backend.containerCache.Start(make(chan struct{}))
+}
+
+// newContainerFilter creates container filter which uses the container cache when it is warm.
+func (backend *KubernetesBackend) newContainerFilter(k8sAPI *client.K8sAPI) *filter.KubernetesContainerFilter {
+	return filter.NewCachedKubernetesContainerFilter(backend.namespace, k8sAPI.GetClient().CoreV1(), backend.containerCache)
+}
+
+// newCmdResolver creates command resolver which uses the shell cache if it is enabled.
+func (backend *KubernetesBackend) newCmdResolver(k8sAPI *client.K8sAPI) *CmdResolver {
+	cmdResolver := NewCmdResolver(k8sAPI, backend.namespace)
+	if backend.shellCache != nil {
+		cmdResolver.ContainerShellDetector = shell.NewCachedShellDetector(cmdResolver.ContainerShellDetector, backend.shellCache)
+	}
+	return cmdResolver
+}
+
+func (backend *KubernetesBackend) Resolve(container, token string) (*model.ResolvedExec, error) {
+	machineExec := &model.MachineExec{
+		BearerToken: token,
+	}
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return nil, err
+	}
+	logrus.Debug("Successfully Got k8sApi object.")
+
+	if container != "" {
+		containerFilter := backend.newContainerFilter(k8sAPI)
+		containerInfo, err := containerFilter.GetContainer(container)
+		if err != nil {
+			return nil, err
+		}
+		cmdResolver := backend.newCmdResolver(k8sAPI)
+		resolvedCmd, err := cmdResolver.ResolveCmd(*machineExec, containerInfo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logrus.Printf("%s is successfully resolved in auto discovered container %s/%s", resolvedCmd,
+			containerInfo.PodName, containerInfo.ContainerName)
+		return &model.ResolvedExec{
+			ContainerInfo: *containerInfo,
+			Cmd:           resolvedCmd,
+		}, nil
+	} else {
+		return backend.findFirstAvailable(k8sAPI, machineExec)
+	}
+}
+
+//TODO Try to refactor it in the way to be able to reuse it in backend.Create
+func (backend *KubernetesBackend) findFirstAvailable(k8sAPI *client.K8sAPI, machineExec *model.MachineExec) (*model.ResolvedExec, error) {
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	// connect to the first available container. Workaround for Cloud Shell https://github.com/eclipse/che/issues/15434
+	containersInfo, err := containerFilter.GetContainerList()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containersInfo) == 0 {
+		return nil, errors.New("no containers found to exec")
+	}
+
+	errs := make(map[string]error)
+	for _, containerInfo := range containersInfo {
+		cmdResolver := backend.newCmdResolver(k8sAPI)
+		resolvedCmd, err := cmdResolver.ResolveCmd(*machineExec, containerInfo)
+		if err != nil {
+			errs[containerInfo.ContainerName] = err
+			continue
+		}
+
+		logrus.Printf("%s is successfully resolved in auto discovered container %s/%s", resolvedCmd,
+			containerInfo.PodName, containerInfo.ContainerName)
+		return &model.ResolvedExec{
+			ContainerInfo: *containerInfo,
+			Cmd:           resolvedCmd,
+		}, nil
+	}
+
+	var containers []string
+	for _, c := range containersInfo {
+		containers = append(containers, c.PodName+"\\"+c.ContainerName)
+	}
+	var buf strings.Builder
+	for container, err := range errs {
+		buf.WriteString(fmt.Sprintf("- %s: %s\n", container, err.Error()))
+	}
+	return nil, fmt.Errorf("failed to resolve exec in any of {%s} -- errors: \n%s", strings.Join(containers, ", "), buf.String())
+}
+
+// Create new exec request object
+func (backend *KubernetesBackend) Create(machineExec *model.MachineExec) (process.Executor, error) {
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return nil, err
+	}
+	logrus.Debug("Successfully Got k8sApi object.")
+
+	containerFilter := backend.newContainerFilter(k8sAPI)
+
+	if machineExec.Identifier.MachineName != "" {
+		containerInfo, err := containerFilter.FindContainerInfo(&machineExec.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		executor, err := backend.doCreate(machineExec, containerInfo, k8sAPI)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Printf("%s is successfully initialized in user specified container %s/%s", machineExec.Cmd,
+			containerInfo.PodName, containerInfo.ContainerName)
+		return executor, nil
+	}
+	// connect to the first available container. Workaround for Cloud Shell https://github.com/eclipse/che/issues/15434
+	containersInfo, err := containerFilter.GetContainerList()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containersInfo) == 0 {
+		return nil, errors.New("no containers found to exec")
+	}
+
+	errs := make(map[string]error)
+	for _, containerInfo := range containersInfo {
+		executor, err := backend.doCreate(machineExec, containerInfo, k8sAPI)
+		if err != nil {
+			//attempt to initialize terminal in this container failed
+			//proceed to next one
+			errs[containerInfo.ContainerName] = err
+			continue
+		}
+		logrus.Printf("%s is successfully initialized in auto discovered container %s/%s", machineExec.Cmd,
+			containerInfo.PodName, containerInfo.ContainerName)
+		return executor, nil
+	}
+
+	var containers []string
+	for _, c := range containersInfo {
+		containers = append(containers, c.PodName+"\\"+c.ContainerName)
+	}
+	var buf strings.Builder
+	for container, err := range errs {
+		buf.WriteString(fmt.Sprintf("- %s: %s\n", container, err.Error()))
+	}
+	return nil, fmt.Errorf("failed to initialize terminal in any of {%s} -- errors: \n%s", strings.Join(containers, ", "), buf.String())
+}
+
+// CreateInContainer creates exec in the resolved container without looking it up in the workspace pods.
+func (backend *KubernetesBackend) CreateInContainer(machineExec *model.MachineExec, containerInfo *model.ContainerInfo) (process.Executor, error) {
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return nil, err
+	}
+
+	executor, err := backend.doCreate(machineExec, containerInfo, k8sAPI)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Printf("%s is successfully initialized in container %s/%s", machineExec.Cmd,
+		containerInfo.PodName, containerInfo.ContainerName)
+	return executor, nil
+}
+
+func (backend *KubernetesBackend) doCreate(machineExec *model.MachineExec, containerInfo *model.ContainerInfo, k8sAPI *client.K8sAPI) (process.Executor, error) {
+	resolveStart := time.Now()
+	cmdResolver := backend.newCmdResolver(k8sAPI)
+	resolvedCmd, err := cmdResolver.ResolveCmd(*machineExec, containerInfo)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveCreatePhase(metrics.ShellDetectionPhase, time.Since(resolveStart))
+
+	// process id is recorded to be able to signal the process on pre-stop, kubernetes exec api does not provide it
+	command, pidFile := resolvedCmd, ""
+	if cfg.PreStopTimeout > 0 {
+		command, pidFile = recordPid(resolvedCmd)
+	}
+
+	req := k8sAPI.GetClient().CoreV1().RESTClient().
+		Post().
+		Namespace(backend.namespace).
+		Resource(exec_info.Pods).
+		Name(containerInfo.PodName).
+		SubResource(exec_info.Exec).
+		// set up params
+		VersionedParams(&v1.PodExecOptions{
+			Container: containerInfo.ContainerName,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+			Stdin:     true,
+			TTY:       machineExec.Tty,
+		}, scheme.ParameterCodec)
+
+	executor, err := k8sAPI.NewExecutor(exec_info.Post, req.URL())
+	if err != nil {
+		return nil, err
+	}
+	machineExec.Cmd = resolvedCmd
+	machineExec.Container = *containerInfo
+
+	return &kubernetesExecutor{executor: executor, backend: backend, container: containerInfo, pidFile: pidFile}, nil
+}
+
+// recordPid makes the shell command write the shell process id to the file with random name
+// in the private directory under $HOME of the container user. The file is removed when the shell exits.
+// Returns the command and the file path relative to $HOME, empty if command is not run by a known shell.
+func recordPid(command []string) ([]string, string) {
+	if len(command) < 3 || command[1] != "-c" || !pidRecordingShells[filepath.Base(command[0])] {
+		return command, ""
+	}
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		logrus.Warnf("Unable to generate name of the process id file. Cause: %s", err.Error())
+		return command, ""
+	}
+
+	pidFile := pidDir + "/" + hex.EncodeToString(bytes) + ".pid"
+	recording := make([]string, len(command))
+	copy(recording, command)
+	// process is not affected if the file can't be written. The file is not removed by the trap
+	// if the command replaces the shell with exec, so executor removes it as well
+	recording[2] = fmt.Sprintf(`machine_exec_pid_file="$HOME/%s"; `+
+		`{ (umask 077 && mkdir -p "${machine_exec_pid_file%%/*}" && echo $$ > "$machine_exec_pid_file"); `+
+		`trap 'rm -f "$machine_exec_pid_file"' EXIT; } 2>/dev/null; %s`, pidFile, command[2])
+	return recording, pidFile
+}
+
+func (backend *KubernetesBackend) CreateKubeConfig(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error {
+	machineExec := &model.MachineExec{
+		BearerToken: kubeConfigParams.BearerToken,
+	}
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return err
+	}
+
+	currentNamespace := GetNamespace()
+	infoExecCreator := exec_info.NewKubernetesInfoExecCreator(currentNamespace, k8sAPI.GetClient().CoreV1(), k8sAPI.GetConfig())
+
+	if kubeConfigParams.Namespace == "" {
+		kubeConfigParams.Namespace = currentNamespace
+	}
+	tokenExpiry := kubeconfig.TokenExpiry(k8sAPI.GetConfig(), kubeConfigParams.BearerToken)
+	err = kubeconfig.CreateKubeConfig(infoExecCreator, kubeConfigParams, containerInfo, tokenExpiry)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// List available containers
+func (backend *KubernetesBackend) ListAvailableContainers(machineExec *model.MachineExec) (containers []*model.ContainerMetadata, err error) {
+
+	// use only token from this struct
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(machineExec)
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return nil, err
+	}
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	containers, err = containerFilter.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// StreamCommand runs command in the container with the permissions of the token owner.
+func (backend *KubernetesBackend) StreamCommand(token string, container string, command []string, options process.StreamOptions) error {
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(&model.MachineExec{BearerToken: token})
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return err
+	}
+
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	var containerInfo *model.ContainerInfo
+	if container != "" {
+		if containerInfo, err = containerFilter.GetContainer(container); err != nil {
+			return err
+		}
+	} else {
+		containersInfo, err := containerFilter.GetContainerList()
+		if err != nil {
+			return err
+		}
+		if len(containersInfo) == 0 {
+			return errors.New("no containers found to exec")
+		}
+		containerInfo = containersInfo[0]
+	}
+
+	req := k8sAPI.GetClient().CoreV1().RESTClient().
+		Post().
+		Namespace(backend.namespace).
+		Resource(exec_info.Pods).
+		Name(containerInfo.PodName).
+		SubResource(exec_info.Exec).
+		VersionedParams(&v1.PodExecOptions{
+			Container: containerInfo.ContainerName,
+			Command:   command,
+			Stdin:     options.Stdin != nil,
+			Stdout:    options.Stdout != nil,
+			Stderr:    options.Stderr != nil,
+		}, scheme.ParameterCodec)
+
+	executor, err := k8sAPI.NewExecutor(exec_info.Post, req.URL())
+	if err != nil {
+		return err
+	}
+	return (&kubernetesExecutor{executor: executor}).Stream(options)
+}
+
+// RunCommand runs command in the container with service account permissions.
+func (backend *KubernetesBackend) RunCommand(container string, command []string, timeout time.Duration) (string, error) {
+	k8sAPI, err := backend.k8sAPIProvider.GetServiceAccountK8sAPI()
+	if err != nil {
+		return "", err
+	}
+
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	containerInfo, err := containerFilter.FindContainerInfo(&model.MachineIdentifier{MachineName: container})
+	if err != nil {
+		return "", err
+	}
+	return backend.runCommand(k8sAPI, containerInfo, command, timeout)
+}
+
+func (backend *KubernetesBackend) runCommand(k8sAPI *client.K8sAPI, containerInfo *model.ContainerInfo, command []string, timeout time.Duration) (string, error) {
+	req := k8sAPI.GetClient().CoreV1().RESTClient().
+		Post().
+		Namespace(backend.namespace).
+		Resource(exec_info.Pods).
+		Name(containerInfo.PodName).
+		SubResource(exec_info.Exec).
+		VersionedParams(&v1.PodExecOptions{
+			Container: containerInfo.ContainerName,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := k8sAPI.NewExecutor(exec_info.Post, req.URL())
+	if err != nil {
+		return "", err
+	}
+
+	output := &syncBuffer{}
+	streamEnd := make(chan error, 1)
+	go func() {
+		streamEnd <- executor.Stream(remotecommand.StreamOptions{Stdout: output, Stderr: output})
+	}()
+
+	select {
+	case err = <-streamEnd:
+		return output.String(), err
+	case <-time.After(timeout):
+		// remote command streaming can't be cancelled, so its connection is closed to stop it
+		if closer, ok := executor.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return output.String(), fmt.Errorf("command is not completed in %s", timeout)
+	}
+}
+
+// kubernetesExecutor streams exec process over kubernetes remote command protocol.
+// Exec process is signalled with kill command in the container if its process id is recorded.
+type kubernetesExecutor struct {
+	executor remotecommand.Executor
+
+	backend   *KubernetesBackend
+	container *model.ContainerInfo
+	pidFile   string
+}
+
+// Signal sends signal to the process group of the exec process if the process is the group leader,
+// like terminal processes are, otherwise to the process itself.
+func (executor *kubernetesExecutor) Signal(sig os.Signal) error {
+	if executor.pidFile == "" {
+		return errors.New("process id of the exec is not recorded")
+	}
+	sysSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %s", sig)
+	}
+
+	k8sAPI, err := executor.backend.k8sAPIProvider.GetServiceAccountK8sAPI()
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(`read -r pid < "$HOME/%[1]s" && { kill -%[2]d -"$pid" 2>/dev/null || kill -%[2]d "$pid"; }`, executor.pidFile, int(sysSig))
+	output, err := executor.backend.runCommand(k8sAPI, executor.container, []string{shell.DefaultShell, "-c", script}, signalTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to signal the exec process: %s %s", err.Error(), strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (executor *kubernetesExecutor) Stream(options process.StreamOptions) error {
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:  options.Stdin,
+		Stdout: options.Stdout,
+		Stderr: options.Stderr,
+		Tty:    options.Tty,
+	}
+	if options.TerminalSizeQueue != nil {
+		streamOptions.TerminalSizeQueue = &terminalSizeQueue{queue: options.TerminalSizeQueue}
+	}
+	err := executor.executor.Stream(streamOptions)
+	if executor.pidFile != "" {
+		go executor.removePidFile()
+	}
+	return err
+}
+
+// removePidFile removes the process id file of the finished exec, which is left if the shell was replaced with exec.
+func (executor *kubernetesExecutor) removePidFile() {
+	k8sAPI, err := executor.backend.k8sAPIProvider.GetServiceAccountK8sAPI()
+	if err != nil {
+		logrus.Debugf("Unable to remove process id file of the exec. Cause: %s", err.Error())
+		return
+	}
+
+	script := fmt.Sprintf(`rm -f "$HOME/%s"`, executor.pidFile)
+	if output, err := executor.backend.runCommand(k8sAPI, executor.container, []string{shell.DefaultShell, "-c", script}, signalTimeout); err != nil {
+		logrus.Debugf("Unable to remove process id file of the exec. Cause: %s %s", err.Error(), strings.TrimSpace(output))
+	}
+}
+
+type terminalSizeQueue struct {
+	queue process.TerminalSizeQueue
+}
+
+func (sizeQueue *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size := sizeQueue.queue.Next()
+	if size == nil {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Width: size.Width, Height: size.Height}
+}
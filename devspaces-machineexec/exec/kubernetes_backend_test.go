@@ -0,0 +1,57 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldRecordPidOfShellCommandInPrivateFile(t *testing.T) {
+	home := t.TempDir()
+	command, pidFile := recordPid([]string{"sh", "-c", `cat "$HOME/$1"`, "sh"})
+	assert.True(t, strings.HasPrefix(pidFile, pidDir+"/"))
+
+	cmd := exec.Command(command[0], append(command[1:], pidFile)...)
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	output, err := cmd.Output()
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(cmd.ProcessState.Pid()), strings.TrimSpace(string(output)))
+
+	// file is removed once the shell exits
+	_, err = os.Stat(filepath.Join(home, pidFile))
+	assert.True(t, os.IsNotExist(err))
+	dirInfo, err := os.Stat(filepath.Join(home, pidDir))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0700), dirInfo.Mode().Perm())
+}
+
+func TestShouldRecordPidInUniqueFiles(t *testing.T) {
+	_, pidFile := recordPid([]string{"sh", "-c", "echo started"})
+	_, otherPidFile := recordPid([]string{"sh", "-c", "echo started"})
+
+	assert.NotEqual(t, pidFile, otherPidFile)
+}
+
+func TestShouldNotRecordPidOfArgvCommand(t *testing.T) {
+	command, pidFile := recordPid([]string{"top", "-b"})
+
+	assert.Equal(t, []string{"top", "-b"}, command)
+	assert.Empty(t, pidFile)
+}
@@ -0,0 +1,99 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// limiter of the execs creation, it is created on the first use when configuration is parsed.
+	createLimiter     *rate.Limiter
+	createLimiterOnce sync.Once
+)
+
+// allowCreate returns error if execs are created faster than configured.
+func allowCreate() error {
+	createLimiterOnce.Do(func() {
+		limit := rate.Inf
+		if cfg.ExecCreateRate > 0 {
+			limit = rate.Limit(cfg.ExecCreateRate)
+		}
+		createLimiter = rate.NewLimiter(limit, cfg.ExecCreateBurst)
+	})
+
+	if !createLimiter.Allow() {
+		return rest.NewError(http.StatusTooManyRequests, "too many execs are created, try again later")
+	}
+	return nil
+}
+
+// allowExec returns error if exec limits are already reached before the container of the exec is resolved,
+// so that the backend doesn't detect shell or create debug container for the exec which is refused anyway.
+func allowExec(machineExec *model.MachineExec) error {
+	defer execs.mutex.Unlock()
+	execs.mutex.Lock()
+
+	return checkExecsLimits(machineExec)
+}
+
+// checkExecsLimits returns error if live execs with the same token or in the same container
+// reached configured limit. If container is not resolved yet, execs in the container with
+// the requested name are counted. Execs mutex must be held by the caller.
+func checkExecsLimits(machineExec *model.MachineExec) error {
+	if cfg.MaxExecsPerToken <= 0 && cfg.MaxExecsPerContainer <= 0 {
+		return nil
+	}
+
+	container := containerKey(machineExec.Container)
+	sameContainer := func(liveExec *model.MachineExec) bool {
+		return containerKey(liveExec.Container) == container
+	}
+	if machineExec.Container.ContainerName == "" {
+		container = machineExec.Identifier.MachineName
+		sameContainer = func(liveExec *model.MachineExec) bool {
+			return container != "" && liveExec.Container.ContainerName == container
+		}
+	}
+
+	tokenExecs, containerExecs := 0, 0
+	for _, liveExec := range execs.execMap {
+		if liveExec.BearerToken == machineExec.BearerToken {
+			tokenExecs++
+		}
+		if sameContainer(liveExec) {
+			containerExecs++
+		}
+	}
+
+	if cfg.MaxExecsPerToken > 0 && tokenExecs >= cfg.MaxExecsPerToken {
+		return rest.NewError(http.StatusTooManyRequests,
+			fmt.Sprintf("limit of %d live execs per user is reached", cfg.MaxExecsPerToken))
+	}
+	if cfg.MaxExecsPerContainer > 0 && containerExecs >= cfg.MaxExecsPerContainer {
+		return rest.NewError(http.StatusTooManyRequests,
+			fmt.Sprintf("limit of %d live execs in container %s is reached", cfg.MaxExecsPerContainer, container))
+	}
+	return nil
+}
+
+func containerKey(containerInfo model.ContainerInfo) string {
+	return containerInfo.PodName + "/" + containerInfo.ContainerName
+}
@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/process"
+	ws "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/eclipse/che-go-jsonrpc/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldLimitExecsPerContainerAndReportStats(t *testing.T) {
+	defer func(limit int) { cfg.MaxExecsPerContainer = limit }(cfg.MaxExecsPerContainer)
+	cfg.MaxExecsPerContainer = 1
+
+	manager := NewExecManager(NewLocalBackend())
+	machineExec := &model.MachineExec{Type: "process", Cmd: []string{"cat"}}
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	NewHealthWatcher(machineExec, event.NewBus(), manager).CleanUpOnExitOrError()
+	defer manager.Remove(id)
+	defer manager.Kill(id)
+
+	_, err = manager.Create(&model.MachineExec{Type: "process", Cmd: []string{"cat"}})
+	assert.NotNil(t, err)
+
+	conn := &collectingConnection{}
+	assert.Nil(t, manager.AttachConnection(id, conn, 0, ws.Client{Mode: ws.FullAccess}))
+	assert.Nil(t, manager.Input(id, []byte("hello\n")))
+	assert.Eventually(t, func() bool {
+		stdout, _ := conn.output()
+		return stdout == "hello\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stats := manager.Stats()
+	assert.Equal(t, 1, stats.Total)
+	assert.Equal(t, 1, stats.Containers[containerKey(machineExec.Container)])
+	assert.Equal(t, id, stats.Sessions[0].ID)
+	assert.Equal(t, int64(6), stats.Sessions[0].BytesIn)
+	assert.Equal(t, int64(6), stats.Sessions[0].BytesOut)
+}
+
+// countingBackend counts the execs created by the local backend.
+type countingBackend struct {
+	*LocalBackend
+	created int
+}
+
+func (backend *countingBackend) Create(machineExec *model.MachineExec) (process.Executor, error) {
+	backend.created++
+	return backend.LocalBackend.Create(machineExec)
+}
+
+func TestShouldCheckLimitsBeforeExecIsCreatedByBackend(t *testing.T) {
+	defer func(limit int) { cfg.MaxExecsPerToken = limit }(cfg.MaxExecsPerToken)
+	cfg.MaxExecsPerToken = 1
+
+	backend := &countingBackend{LocalBackend: NewLocalBackend()}
+	manager := NewExecManager(backend)
+	machineExec := &model.MachineExec{Type: "process", Cmd: []string{"cat"}, BearerToken: "limited"}
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	NewHealthWatcher(machineExec, event.NewBus(), manager).CleanUpOnExitOrError()
+	defer manager.Remove(id)
+	defer manager.Kill(id)
+
+	_, err = manager.Create(&model.MachineExec{Type: "process", Cmd: []string{"cat"}, BearerToken: "limited"})
+	assert.EqualError(t, err, "limit of 1 live execs per user is reached")
+	_, err = manager.CreateDebug(&model.MachineExec{BearerToken: "limited"}, LocalContainerName)
+	assert.EqualError(t, err, "limit of 1 live execs per user is reached")
+	assert.Equal(t, 1, backend.created)
+}
+
+func TestShouldListSessionsOfCallerOnly(t *testing.T) {
+	manager := NewExecManager(NewLocalBackend())
+	create := func(token string, userID string) int {
+		machineExec := &model.MachineExec{Type: "process", Cmd: []string{"cat"}, BearerToken: token, UserID: userID}
+		id, err := manager.Create(machineExec)
+		assert.Nil(t, err)
+		NewHealthWatcher(machineExec, event.NewBus(), manager).CleanUpOnExitOrError()
+		t.Cleanup(func() {
+			manager.Kill(id)
+			manager.Remove(id)
+		})
+		return id
+	}
+	aliceID := create("alice-token", "alice")
+	bobID := create("bob-token", "bob")
+
+	ids := func(caller *model.MachineExec) []int {
+		result := []int{}
+		for _, session := range manager.ListSessions(caller) {
+			result = append(result, session.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []int{aliceID}, ids(&model.MachineExec{BearerToken: "alice-refreshed-token", UserID: "alice"}))
+	assert.Equal(t, []int{bobID}, ids(&model.MachineExec{BearerToken: "bob-token"}))
+	assert.Empty(t, ids(&model.MachineExec{BearerToken: "mallory-token", UserID: "mallory"}))
+}
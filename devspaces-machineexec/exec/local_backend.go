@@ -0,0 +1,178 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	exec_info "github.com/eclipse-che/che-machine-exec/exec-info"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/eclipse-che/che-machine-exec/process"
+	"github.com/eclipse-che/che-machine-exec/shell"
+	"github.com/sirupsen/logrus"
+)
+
+// LocalContainerName is the name of the container local execs are reported to run in.
+const LocalContainerName = "local"
+
+// LocalBackend spawns execs as local processes of the machine exec,
+// so it is able to serve terminals inside a single container or on a developer machine.
+// There is the only container, which is named after the host.
+type LocalBackend struct {
+	container   model.ContainerInfo
+	cmdResolver *CmdResolver
+}
+
+// NewLocalBackend create new instance of the local exec backend.
+func NewLocalBackend() *LocalBackend {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.Warnf("Unable to get hostname. Cause: %s", err.Error())
+		hostname = "localhost"
+	}
+
+	infoExecCreator := exec_info.NewLocalInfoExecCreator()
+	shellDetector := &shell.ShellDetector{
+		InfoExecCreator: infoExecCreator,
+		ExecInfoParser:  shell.NewExecInfoParser(),
+	}
+	return &LocalBackend{
+		container: model.ContainerInfo{PodName: hostname, ContainerName: LocalContainerName},
+		cmdResolver: &CmdResolver{
+			ContainerShellDetector: shellDetector,
+			InfoExecCreator:        infoExecCreator,
+		},
+	}
+}
+
+func (backend *LocalBackend) Resolve(container, _ string) (*model.ResolvedExec, error) {
+	if container != "" && container != backend.container.ContainerName {
+		return nil, errors.New("container '" + container + "' is not found")
+	}
+
+	resolvedCmd, err := backend.cmdResolver.ResolveCmd(model.MachineExec{}, &backend.container)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ResolvedExec{
+		ContainerInfo: backend.container,
+		Cmd:           resolvedCmd,
+	}, nil
+}
+
+// Create new exec request object
+func (backend *LocalBackend) Create(machineExec *model.MachineExec) (process.Executor, error) {
+	if name := machineExec.Identifier.MachineName; name != "" && name != backend.container.ContainerName {
+		return nil, errors.New("container '" + name + "' is not found")
+	}
+	return backend.CreateInContainer(machineExec, &backend.container)
+}
+
+// CreateInContainer creates local process, container must be the local one.
+func (backend *LocalBackend) CreateInContainer(machineExec *model.MachineExec, containerInfo *model.ContainerInfo) (process.Executor, error) {
+	if *containerInfo != backend.container {
+		return nil, errors.New("container '" + containerInfo.ContainerName + "' is not found")
+	}
+
+	resolveStart := time.Now()
+	resolvedCmd, err := backend.cmdResolver.ResolveCmd(*machineExec, &backend.container)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveCreatePhase(metrics.ShellDetectionPhase, time.Since(resolveStart))
+	machineExec.Cmd = resolvedCmd
+	machineExec.Container = backend.container
+
+	logrus.Printf("%s is successfully initialized as local process", machineExec.Cmd)
+	return &localExecutor{command: resolvedCmd}, nil
+}
+
+// StreamCommand runs command as a local process, container must be the local one or empty.
+func (backend *LocalBackend) StreamCommand(_ string, container string, command []string, options process.StreamOptions) error {
+	if container != "" && container != backend.container.ContainerName {
+		return errors.New("container '" + container + "' is not found")
+	}
+	if len(command) == 0 {
+		return errors.New("command is empty")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = options.Stdin
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	return cmd.Run()
+}
+
+// PortForward tunnels the stream to the port on the local host, container must be the local one or empty.
+func (backend *LocalBackend) PortForward(_ string, container string, port int, stream io.ReadWriter) error {
+	if container != "" && container != backend.container.ContainerName {
+		return errors.New("container '" + container + "' is not found")
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pipe(stream, conn)
+	return err
+}
+
+// RunCommand runs command as a local process, container must be the local one or empty.
+func (backend *LocalBackend) RunCommand(container string, command []string, timeout time.Duration) (string, error) {
+	if container != "" && container != backend.container.ContainerName {
+		return "", errors.New("container '" + container + "' is not found")
+	}
+	if len(command) == 0 {
+		return "", errors.New("command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf("command is not completed in %s", timeout)
+	}
+	return string(output), err
+}
+
+// Kubeconfig is not needed for local execs since there is no cluster to access.
+func (*LocalBackend) CreateKubeConfig(*model.KubeConfigParams, *model.ContainerInfo) error {
+	logrus.Debug("Local exec backend doesn't create kubeconfig")
+	return nil
+}
+
+// Local execs run without containers, so there is nothing to debug.
+func (*LocalBackend) CreateDebugContainer(*model.MachineExec, string) (*model.ContainerInfo, error) {
+	return nil, errors.New("debug containers are not supported by the local exec backend")
+}
+
+// List available containers
+func (backend *LocalBackend) ListAvailableContainers(*model.MachineExec) ([]*model.ContainerMetadata, error) {
+	return []*model.ContainerMetadata{{
+		ContainerInfo:   backend.container,
+		Kind:            model.RegularContainerKind,
+		DefaultTerminal: true,
+	}}, nil
+}
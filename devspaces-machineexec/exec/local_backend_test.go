@@ -0,0 +1,313 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	ws "github.com/eclipse-che/che-machine-exec/ws-conn"
+	"github.com/eclipse/che-go-jsonrpc/event"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type collectingConnection struct {
+	lock   sync.Mutex
+	stdout strings.Builder
+	stderr strings.Builder
+}
+
+func (conn *collectingConnection) Write(data []byte) error {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	conn.stdout.Write(data)
+	return nil
+}
+
+func (conn *collectingConnection) WriteStderr(data []byte) error {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	conn.stderr.Write(data)
+	return nil
+}
+
+func (conn *collectingConnection) Close() error {
+	return nil
+}
+
+func (conn *collectingConnection) output() (string, string) {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	return conn.stdout.String(), conn.stderr.String()
+}
+
+func runLocalExec(t *testing.T, machineExec *model.MachineExec, input ...string) (*collectingConnection, *model.ExecExitEvent) {
+	manager := NewExecManager(NewLocalBackend())
+	eventBus := event.NewBus()
+	consumer := &exitEventConsumer{events: make(chan *model.ExecExitEvent, 1)}
+	eventBus.Sub(consumer, model.OnExecExit)
+
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	NewHealthWatcher(machineExec, eventBus, manager).CleanUpOnExitOrError()
+
+	conn := &collectingConnection{}
+	assert.Nil(t, manager.AttachConnection(id, conn, 0, ws.Client{Mode: ws.FullAccess}))
+	for _, data := range input {
+		assert.Nil(t, manager.Input(id, []byte(data)))
+	}
+
+	select {
+	case exitEvent := <-consumer.events:
+		return conn, exitEvent
+	case <-time.After(5 * time.Second):
+		t.Fatal("local exec is not finished")
+		return nil, nil
+	}
+}
+
+func TestShouldRunLocalProcessWithSeparateStderr(t *testing.T) {
+	machineExec := &model.MachineExec{
+		Type: "process",
+		Cmd:  []string{"echo out; echo err >&2; exit 3"},
+	}
+
+	conn, exitEvent := runLocalExec(t, machineExec)
+	stdout, stderr := conn.output()
+
+	assert.Equal(t, "out\n", stdout)
+	assert.Equal(t, "err\n", stderr)
+	assert.Equal(t, 3, exitEvent.ExitCode)
+	assert.Equal(t, LocalContainerName, machineExec.Container.ContainerName)
+}
+
+func TestShouldRunLocalShellInTerminal(t *testing.T) {
+	machineExec := &model.MachineExec{
+		Cmd:  []string{"sh"},
+		Tty:  true,
+		Cols: 80,
+		Rows: 24,
+	}
+
+	conn, exitEvent := runLocalExec(t, machineExec, "echo hello\n", "exit 5\n")
+	stdout, _ := conn.output()
+
+	assert.Contains(t, stdout, "hello")
+	assert.Equal(t, 5, exitEvent.ExitCode)
+}
+
+func TestShouldRunLocalArgvWithEnvCwdAndStdin(t *testing.T) {
+	cwd := t.TempDir() + "/dir with spaces"
+	assert.Nil(t, os.Mkdir(cwd, 0755))
+	stdin := "input $(echo injected)\n"
+	machineExec := &model.MachineExec{
+		Type:  model.ArgvExecType,
+		Cmd:   []string{"sh", "-c", `printf '%s|%s|' "$GREETING" "$(pwd)"; cat`},
+		Cwd:   cwd,
+		Env:   map[string]string{"GREETING": "hello; $(echo injected)"},
+		Stdin: &stdin,
+	}
+
+	conn, exitEvent := runLocalExec(t, machineExec)
+	stdout, _ := conn.output()
+
+	assert.Equal(t, "hello; $(echo injected)|"+cwd+"|input $(echo injected)\n", stdout)
+	assert.Equal(t, 0, exitEvent.ExitCode)
+}
+
+func TestShouldKillLocalProcessIgnoringInputEnd(t *testing.T) {
+	manager := NewExecManager(NewLocalBackend())
+	eventBus := event.NewBus()
+	consumer := &exitEventConsumer{events: make(chan *model.ExecExitEvent, 1)}
+	eventBus.Sub(consumer, model.OnExecExit)
+	machineExec := &model.MachineExec{
+		Type: model.ArgvExecType,
+		Cmd:  []string{"sh", "-c", "echo started; exec sleep 30"},
+	}
+
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	NewHealthWatcher(machineExec, eventBus, manager).CleanUpOnExitOrError()
+	conn := &collectingConnection{}
+	assert.Nil(t, manager.AttachConnection(id, conn, 0, ws.Client{Mode: ws.FullAccess}))
+	assert.Eventually(t, func() bool {
+		stdout, _ := conn.output()
+		return stdout == "started\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, manager.Kill(id))
+
+	select {
+	case <-consumer.events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("local exec is not killed")
+	}
+}
+
+func TestShouldForwardLocalPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(conn, request); err == nil {
+			_, _ = conn.Write(bytes.ToUpper(request))
+		}
+	}()
+
+	// input is kept open, so the tunnel is closed by the remote end
+	input, inputWriter := io.Pipe()
+	defer inputWriter.Close()
+	go func() {
+		_, _ = inputWriter.Write([]byte("ping"))
+	}()
+	output := &bytes.Buffer{}
+	stream := struct {
+		io.Reader
+		io.Writer
+	}{input, output}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	err = NewLocalBackend().PortForward("", "", port, stream)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "PING", output.String())
+}
+
+func TestShouldNotForwardPortOfUnknownContainer(t *testing.T) {
+	err := NewLocalBackend().PortForward("", "unknown", 8080, &bytes.Buffer{})
+
+	assert.EqualError(t, err, "container 'unknown' is not found")
+}
+
+type viewerConnection struct {
+	collectingConnection
+	sizes   chan [2]uint
+	revoked chan bool
+}
+
+func (conn *viewerConnection) WriteResize(cols uint, rows uint) error {
+	conn.sizes <- [2]uint{cols, rows}
+	return nil
+}
+
+func (conn *viewerConnection) Revoke() {
+	conn.revoked <- true
+}
+
+func TestShouldListResizeAndRevokeViewers(t *testing.T) {
+	manager := NewExecManager(NewLocalBackend())
+	machineExec := &model.MachineExec{
+		Cmd:  []string{"sh"},
+		Tty:  true,
+		Cols: 80,
+		Rows: 24,
+	}
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	defer manager.Remove(id)
+	defer func() { _ = manager.Kill(id) }()
+
+	owner := &collectingConnection{}
+	assert.Nil(t, manager.AttachConnection(id, owner, 0, ws.Client{User: "owner", Mode: ws.FullAccess}))
+	viewer := &viewerConnection{sizes: make(chan [2]uint, 2), revoked: make(chan bool, 1)}
+	assert.Nil(t, manager.AttachConnection(id, viewer, 0, ws.Client{User: "viewer", Mode: ws.ReadOnlyAccess}))
+	assert.Equal(t, [2]uint{80, 24}, <-viewer.sizes)
+
+	viewers, err := manager.ListViewers(id)
+	assert.Nil(t, err)
+	assert.Len(t, viewers, 1)
+	assert.Equal(t, "viewer", viewers[0].User)
+
+	assert.Nil(t, manager.Resize(id, 100, 30))
+	assert.Equal(t, [2]uint{100, 30}, <-viewer.sizes)
+
+	assert.Nil(t, manager.RevokeViewer(id, viewers[0].ID))
+	assert.True(t, <-viewer.revoked)
+	viewers, err = manager.ListViewers(id)
+	assert.Nil(t, err)
+	assert.Empty(t, viewers)
+
+	// revoked user can't attach as viewer again
+	err = manager.AttachConnection(id, &collectingConnection{}, 0, ws.Client{User: "viewer", Mode: ws.ReadOnlyAccess})
+	assert.Equal(t, ws.ErrViewerRevoked, err)
+	assert.Nil(t, manager.AttachConnection(id, &collectingConnection{}, 0, ws.Client{User: "other", Mode: ws.ReadOnlyAccess}))
+
+	assert.EqualError(t, manager.RevokeViewer(id, 1000), "viewer 1000 is not found")
+}
+
+// readResize skips exec output and returns the next terminal size sent to the websocket viewer.
+func readResize(t *testing.T, conn *websocket.Conn) ws.ResizeEvent {
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgType == websocket.BinaryMessage {
+			event := ws.ResizeEvent{}
+			assert.Nil(t, json.Unmarshal(data, &event))
+			return event
+		}
+	}
+}
+
+func TestShouldSendTerminalSizeToWebsocketViewer(t *testing.T) {
+	manager := NewExecManager(NewLocalBackend())
+	machineExec := &model.MachineExec{
+		Cmd:  []string{"sh"},
+		Tty:  true,
+		Cols: 80,
+		Rows: 24,
+	}
+	id, err := manager.Create(machineExec)
+	assert.Nil(t, err)
+	defer manager.Remove(id)
+	defer func() { _ = manager.Kill(id) }()
+	assert.Nil(t, manager.AttachConnection(id, &collectingConnection{}, 0, ws.Client{User: "owner", Mode: ws.FullAccess}))
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = manager.Attach(id, wsConn, 0, ws.Client{User: "viewer", Mode: ws.ReadOnlyAccess})
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, ws.ResizeEvent{Event: ws.OnTerminalResized, Cols: 80, Rows: 24}, readResize(t, conn))
+	assert.Nil(t, manager.Resize(id, 100, 30))
+	assert.Equal(t, ws.ResizeEvent{Event: ws.OnTerminalResized, Cols: 100, Rows: 30}, readResize(t, conn))
+}
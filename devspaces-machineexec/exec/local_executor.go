@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/eclipse-che/che-machine-exec/process"
+	"github.com/sirupsen/logrus"
+)
+
+// localExecutor runs exec as a local process of the machine exec.
+type localExecutor struct {
+	command []string
+
+	processLock sync.Mutex
+	process     *os.Process
+	// whether process is the leader of its own process group
+	groupLeader bool
+}
+
+// Signal sends signal to the local process, terminal process group is signalled as a whole.
+func (executor *localExecutor) Signal(sig os.Signal) error {
+	executor.processLock.Lock()
+	defer executor.processLock.Unlock()
+
+	if executor.process == nil {
+		return errors.New("process is not started")
+	}
+	if sysSig, ok := sig.(syscall.Signal); ok && executor.groupLeader {
+		return syscall.Kill(-executor.process.Pid, sysSig)
+	}
+	return executor.process.Signal(sig)
+}
+
+func (executor *localExecutor) started(process *os.Process, groupLeader bool) {
+	executor.processLock.Lock()
+	defer executor.processLock.Unlock()
+
+	executor.process = process
+	executor.groupLeader = groupLeader
+}
+
+func (executor *localExecutor) Stream(options process.StreamOptions) error {
+	if len(executor.command) == 0 {
+		return errors.New("command is empty")
+	}
+	cmd := exec.Command(executor.command[0], executor.command[1:]...)
+
+	if options.Tty {
+		return executor.streamTty(cmd, options)
+	}
+
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	executor.started(cmd.Process, false)
+
+	go func() {
+		if options.Stdin != nil {
+			if _, err := io.Copy(stdin, options.Stdin); err != nil {
+				logrus.Debugf("Input of the local process is closed. Cause: %s", err.Error())
+			}
+		}
+		_ = stdin.Close()
+	}()
+
+	return cmd.Wait()
+}
+
+func (executor *localExecutor) streamTty(cmd *exec.Cmd, options process.StreamOptions) error {
+	pty, tty, err := openPty()
+	if err != nil {
+		return err
+	}
+	// terminal is not resized once it is closed
+	ptyLock := &sync.Mutex{}
+	ptyClosed := false
+	defer func() {
+		defer ptyLock.Unlock()
+		ptyLock.Lock()
+
+		ptyClosed = true
+		_ = pty.Close()
+	}()
+
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	// run process in the new session with the terminal as controlling one, like login shell does
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+
+	err = cmd.Start()
+	// terminal is used by the process only
+	_ = tty.Close()
+	if err != nil {
+		return err
+	}
+	executor.started(cmd.Process, true)
+
+	if options.TerminalSizeQueue != nil {
+		go func() {
+			for size := options.TerminalSizeQueue.Next(); size != nil; size = options.TerminalSizeQueue.Next() {
+				ptyLock.Lock()
+				if !ptyClosed {
+					if err := setPtySize(pty, size); err != nil {
+						logrus.Debugf("Unable to resize local terminal. Cause: %s", err.Error())
+					}
+				}
+				ptyLock.Unlock()
+			}
+		}()
+	}
+
+	if options.Stdin != nil {
+		go func() {
+			if _, err := io.Copy(pty, options.Stdin); err != nil {
+				logrus.Debugf("Input of the local process is closed. Cause: %s", err.Error())
+			}
+			// there is no way to close terminal input, so hang up the process like terminal does
+			_ = cmd.Process.Signal(syscall.SIGHUP)
+		}()
+	}
+
+	var output sync.WaitGroup
+	output.Add(1)
+	go func() {
+		defer output.Done()
+		// reading fails with EIO when the process and its children close the terminal
+		_, _ = io.Copy(options.Stdout, pty)
+	}()
+
+	err = cmd.Wait()
+	output.Wait()
+	return err
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	exec_info "github.com/eclipse-che/che-machine-exec/exec-info"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	// subresource of the pod to forward ports of
+	portForwardSubresource = "portforward"
+	// protocol of the port forward streams over SPDY
+	portForwardProtocol = "portforward.k8s.io"
+)
+
+// PortForward tunnels the stream to the port of the workspace pod the container belongs to
+// with the permissions of the token owner. The first available container is used if container is empty.
+func (backend *KubernetesBackend) PortForward(token string, container string, port int, stream io.ReadWriter) error {
+	k8sAPI, err := backend.k8sAPIProvider.GetK8sAPI(&model.MachineExec{BearerToken: token})
+	if err != nil {
+		logrus.Debugf("Unable to get k8sAPI %s", err.Error())
+		return err
+	}
+
+	containerFilter := backend.newContainerFilter(k8sAPI)
+	var containerInfo *model.ContainerInfo
+	if container != "" {
+		if containerInfo, err = containerFilter.GetContainer(container); err != nil {
+			return err
+		}
+	} else {
+		containersInfo, err := containerFilter.GetContainerList()
+		if err != nil {
+			return err
+		}
+		if len(containersInfo) == 0 {
+			return errors.New("no containers found to forward port")
+		}
+		containerInfo = containersInfo[0]
+	}
+
+	req := k8sAPI.GetClient().CoreV1().RESTClient().
+		Post().
+		Namespace(backend.namespace).
+		Resource(exec_info.Pods).
+		Name(containerInfo.PodName).
+		SubResource(portForwardSubresource)
+
+	transport, upgrader, err := spdy.RoundTripperFor(k8sAPI.GetConfig())
+	if err != nil {
+		return err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, exec_info.Post, req.URL())
+	conn, protocol, err := dialer.Dial(portForwardProtocol)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if protocol != portForwardProtocol {
+		return fmt.Errorf("unable to negotiate port forward protocol, server returned %q", protocol)
+	}
+
+	return forwardPort(conn, port, stream)
+}
+
+// forwardPort creates error and data streams for the port in the connection
+// and copies bytes between the data stream and the stream.
+func forwardPort(conn httpstream.Connection, port int, stream io.ReadWriter) error {
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(port))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("unable to create error stream: %s", err.Error())
+	}
+	// error stream is read only
+	errorStream.Close()
+
+	errorCh := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorCh <- fmt.Errorf("unable to read error stream: %s", err.Error())
+		case len(message) > 0:
+			errorCh <- fmt.Errorf("unable to forward port %d: %s", port, string(message))
+		}
+		close(errorCh)
+	}()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("unable to create data stream: %s", err.Error())
+	}
+	defer dataStream.Reset()
+
+	if remoteDone, err := pipe(stream, dataStream); !remoteDone || err != nil {
+		return err
+	}
+	// data stream is closed by the pod when forwarding fails, so the cause is in the error stream
+	return <-errorCh
+}
+
+// pipe copies bytes between the stream and the remote end in both directions until one of the directions ends.
+// Returns true if the remote end was done first and error of the direction which ended.
+// Callers have to close the remote end to stop the other direction.
+func pipe(stream io.ReadWriter, remote io.ReadWriter) (remoteDone bool, err error) {
+	remoteCh := make(chan error, 1)
+	localCh := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(stream, remote)
+		remoteCh <- err
+	}()
+	go func() {
+		_, err := io.Copy(remote, stream)
+		localCh <- err
+	}()
+
+	select {
+	case err := <-remoteCh:
+		return true, err
+	case err := <-localCh:
+		return false, err
+	}
+}
@@ -13,21 +13,35 @@
 package exec
 
 import (
+	"io"
+	"strings"
+	"sync/atomic"
+
 	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	"github.com/eclipse-che/che-machine-exec/output/utf8stream"
-	"k8s.io/client-go/tools/remotecommand"
+	"github.com/eclipse-che/che-machine-exec/process"
 )
 
-// Kubernetes pty handler
+// Exec pty handler
 type PtyHandlerImpl struct {
 	machineExec *model.MachineExec
 	filter      *utf8stream.Utf8StreamFilter
 }
 
 func (t PtyHandlerImpl) Read(p []byte) (int, error) {
-	data := <-t.machineExec.MsgChan
-
-	return copy(p, data), nil
+	select {
+	case data := <-t.machineExec.MsgChan:
+		n := copy(p, data)
+		atomic.AddInt64(&t.machineExec.BytesIn, int64(n))
+		metrics.AddBytesIn(n)
+		if t.machineExec.Recorder != nil {
+			t.machineExec.Recorder.Input(data[:n])
+		}
+		return n, nil
+	case <-t.machineExec.StopChan:
+		return 0, io.EOF
+	}
 }
 
 func CreatePtyHandlerImpl(machineExec *model.MachineExec, filter *utf8stream.Utf8StreamFilter) *PtyHandlerImpl {
@@ -35,20 +49,64 @@ func CreatePtyHandlerImpl(machineExec *model.MachineExec, filter *utf8stream.Utf
 }
 
 func (t PtyHandlerImpl) Write(p []byte) (int, error) {
+	atomic.AddInt64(&t.machineExec.BytesOut, int64(len(p)))
+	metrics.AddBytesOut(len(p))
 
 	filteredCharacters := t.filter.ProcessRaw(p)
 
-	t.machineExec.Buffer.Write(filteredCharacters)
-
-	t.machineExec.WriteDataToWsConnections(filteredCharacters)
+	t.machineExec.Buffer.Write(filteredCharacters, t.machineExec.WriteDataToWsConnections)
+	if t.machineExec.Recorder != nil {
+		t.machineExec.Recorder.Output(filteredCharacters)
+	}
 
 	// Original length of the data must be returned to continue reading of the buffer correctly.
 	return len(p), nil
 }
 
-func (t PtyHandlerImpl) Next() *remotecommand.TerminalSize {
+// Exec stderr handler. It is used for execs without tty to send error output separately.
+// Error output is retained in the same scrollback buffer, so it is replayed as regular output.
+type StderrHandlerImpl struct {
+	machineExec *model.MachineExec
+	filter      *utf8stream.Utf8StreamFilter
+}
+
+func (t StderrHandlerImpl) Write(p []byte) (int, error) {
+	atomic.AddInt64(&t.machineExec.BytesOut, int64(len(p)))
+	metrics.AddBytesOut(len(p))
+	filteredCharacters := t.filter.ProcessRaw(p)
+
+	t.machineExec.Buffer.Write(filteredCharacters, t.machineExec.WriteStderrToWsConnections)
+	if t.machineExec.Recorder != nil {
+		t.machineExec.Recorder.Output(filteredCharacters)
+	}
+
+	return len(p), nil
+}
+
+// Exec stdin handler for the execs with predefined input. Input is closed after the content is read.
+type StdinContentHandler struct {
+	machineExec *model.MachineExec
+	reader      *strings.Reader
+}
+
+func (t StdinContentHandler) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	atomic.AddInt64(&t.machineExec.BytesIn, int64(n))
+	metrics.AddBytesIn(n)
+	if t.machineExec.Recorder != nil && n > 0 {
+		t.machineExec.Recorder.Input(p[:n])
+	}
+	return n, err
+}
+
+func (t PtyHandlerImpl) Next() *process.TerminalSize {
 	select {
 	case size := <-t.machineExec.SizeChan:
+		if t.machineExec.Recorder != nil {
+			t.machineExec.Recorder.Resize(int(size.Width), int(size.Height))
+		}
 		return &size
+	case <-t.machineExec.StopChan:
+		return nil
 	}
 }
@@ -0,0 +1,53 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/eclipse-che/che-machine-exec/process"
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens new pseudo terminal and returns its master and slave sides.
+func openPty() (pty *os.File, tty *os.File, err error) {
+	pty, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fd := int(pty.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = pty.Close()
+		return nil, nil, err
+	}
+	ptyNumber, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		_ = pty.Close()
+		return nil, nil, err
+	}
+
+	tty, err = os.OpenFile("/dev/pts/"+strconv.FormatUint(uint64(ptyNumber), 10), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		_ = pty.Close()
+		return nil, nil, err
+	}
+	return pty, tty, nil
+}
+
+// setPtySize changes size of the pseudo terminal.
+func setPtySize(pty *os.File, size *process.TerminalSize) error {
+	return unix.IoctlSetWinsize(int(pty.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Row: size.Height, Col: size.Width})
+}
@@ -0,0 +1,31 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+//go:build !linux
+
+package exec
+
+import (
+	"errors"
+	"os"
+
+	"github.com/eclipse-che/che-machine-exec/process"
+)
+
+// openPty is implemented for linux only, local execs without tty are supported on other platforms.
+func openPty() (pty *os.File, tty *os.File, err error) {
+	return nil, nil, errors.New("tty is not supported by local exec backend on this platform")
+}
+
+func setPtySize(*os.File, *process.TerminalSize) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"strings"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/output/recording"
+	"github.com/sirupsen/logrus"
+)
+
+// startRecording starts recording of the exec input and output.
+// Exec is not failed if recording can't be started.
+func startRecording(machineExec *model.MachineExec) {
+	header := recording.Header{
+		Width:   machineExec.Cols,
+		Height:  machineExec.Rows,
+		Command: strings.Join(machineExec.Cmd, " "),
+		Title:   machineExec.Container.PodName + "/" + machineExec.Container.ContainerName,
+		User:    machineExec.UserID,
+	}
+
+	if cfg.RecordingsQuota > 0 {
+		// the new recording may grow up to the max size
+		recording.EnforceQuota(cfg.RecordingsPath, cfg.RecordingsQuota, cfg.RecordingMaxSize)
+	}
+	recorder, err := recording.NewRecorder(cfg.RecordingsPath, machineExec.ID, header, cfg.RecordingMaxSize)
+	if err != nil {
+		logrus.Errorf("Unable to start recording of exec %d. Cause: %s", machineExec.ID, err.Error())
+		return
+	}
+	machineExec.Recorder = recorder
+}
+
+// stopRecording finishes recording of the exec input and output.
+func stopRecording(machineExec *model.MachineExec) {
+	if machineExec.Recorder == nil {
+		return
+	}
+	if err := machineExec.Recorder.Close(); err != nil {
+		logrus.Errorf("Unable to finish recording of exec %d. Cause: %s", machineExec.ID, err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package exec
+
+import (
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/sirupsen/logrus"
+)
+
+// sessionState holds exec state which should outlive websocket connections.
+type sessionState struct {
+	streaming  bool
+	stopped    bool
+	detachedAt *time.Time
+	expiry     *time.Timer
+}
+
+type execSessions struct {
+	mutex  *sync.Mutex
+	states map[int]*sessionState
+}
+
+var sessions = execSessions{
+	mutex:  &sync.Mutex{},
+	states: make(map[int]*sessionState),
+}
+
+// register starts tracking session of the created exec.
+func (s *execSessions) register(machineExec *model.MachineExec, gracePeriod time.Duration) {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	s.states[machineExec.ID] = &sessionState{}
+
+	machineExec.ConnectionHandler.OnConnectionsLost(func() {
+		s.detach(machineExec, gracePeriod)
+	})
+}
+
+// remove stops tracking session and cancels scheduled expiry.
+func (s *execSessions) remove(id int) {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	if state, ok := s.states[id]; ok {
+		if state.expiry != nil {
+			state.expiry.Stop()
+		}
+		delete(s.states, id)
+	}
+}
+
+// startStreaming returns true if exec output streaming is not started yet
+// and the caller is responsible to start it.
+func (s *execSessions) startStreaming(id int) bool {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	state, ok := s.states[id]
+	if !ok || state.streaming {
+		return false
+	}
+	state.streaming = true
+	return true
+}
+
+// attach cancels expiry of the session scheduled after connections loss.
+func (s *execSessions) attach(id int) {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	if state, ok := s.states[id]; ok {
+		if state.expiry != nil {
+			state.expiry.Stop()
+			state.expiry = nil
+		}
+		state.detachedAt = nil
+	}
+}
+
+// detach schedules session expiry if nobody reattaches to the exec within grace period.
+func (s *execSessions) detach(machineExec *model.MachineExec, gracePeriod time.Duration) {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	state, ok := s.states[machineExec.ID]
+	if !ok || machineExec.ConnectionsCount() > 0 {
+		return
+	}
+
+	now := time.Now()
+	state.detachedAt = &now
+
+	if gracePeriod <= 0 {
+		return
+	}
+
+	logrus.Debugf("Exec %d lost all connections. It will be terminated in %s if nobody reattaches", machineExec.ID, gracePeriod)
+	if state.expiry != nil {
+		state.expiry.Stop()
+	}
+	state.expiry = time.AfterFunc(gracePeriod, func() {
+		s.expire(machineExec)
+	})
+}
+
+func (s *execSessions) expire(machineExec *model.MachineExec) {
+	s.mutex.Lock()
+	state, ok := s.states[machineExec.ID]
+	expired := ok && !state.stopped && state.detachedAt != nil && machineExec.ConnectionsCount() == 0
+	if expired {
+		state.expiry = nil
+		state.stopped = true
+	}
+	s.mutex.Unlock()
+
+	if !expired {
+		return
+	}
+
+	logrus.Infof("Exec %d is terminated since nobody reattached within grace period", machineExec.ID)
+	close(machineExec.StopChan)
+
+	select {
+	case machineExec.ExpiredChan <- true:
+	default:
+	}
+}
+
+// stop closes exec input if it is not closed yet.
+func (s *execSessions) stop(machineExec *model.MachineExec) {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	state, ok := s.states[machineExec.ID]
+	if !ok || state.stopped {
+		return
+	}
+	state.stopped = true
+	close(machineExec.StopChan)
+}
+
+// info returns information about exec session.
+func (s *execSessions) info(machineExec *model.MachineExec) *model.SessionInfo {
+	defer s.mutex.Unlock()
+	s.mutex.Lock()
+
+	info := &model.SessionInfo{
+		ID:            machineExec.ID,
+		Identifier:    machineExec.Identifier,
+		ContainerInfo: machineExec.Container,
+		Cmd:           machineExec.Cmd,
+		Type:          machineExec.Type,
+		Tty:           machineExec.Tty,
+		CreatedAt:     machineExec.CreatedAt,
+		Connections:   machineExec.ConnectionsCount(),
+	}
+	if state, ok := s.states[machineExec.ID]; ok {
+		info.DetachedAt = state.detachedAt
+	}
+	info.ScrollbackStart, info.ScrollbackEnd = machineExec.Buffer.Offsets()
+
+	return info
+}
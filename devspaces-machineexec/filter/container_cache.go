@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package filter
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ContainerRestartHandler is notified when the container of the workspace pod is restarted
+// or the pod is deleted.
+type ContainerRestartHandler func(podName string, containerName string)
+
+// ContainerCache keeps the workspace pods selected by cfg.PodSelector up to date with the pod watch,
+// so workspace containers are found without listing the pods on each request.
+type ContainerCache struct {
+	informer cache.SharedIndexInformer
+
+	lock            sync.RWMutex
+	restartHandlers []ContainerRestartHandler
+}
+
+// NewContainerCache creates new cache of the workspace pods in the namespace.
+// Cache is filled after it is started.
+func NewContainerCache(namespace string, podGetterApi corev1.PodsGetter) *ContainerCache {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = cfg.PodSelector
+			return podGetterApi.Pods(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = cfg.PodSelector
+			return podGetterApi.Pods(namespace).Watch(context.TODO(), options)
+		},
+	}
+
+	containerCache := &ContainerCache{
+		informer: cache.NewSharedIndexInformer(listWatch, &v1.Pod{}, 0, cache.Indexers{}),
+	}
+	containerCache.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: containerCache.onPodUpdate,
+		DeleteFunc: containerCache.onPodDelete,
+	})
+	return containerCache
+}
+
+// Start watches the workspace pods until stop channel is closed. Doesn't block.
+func (containerCache *ContainerCache) Start(stop <-chan struct{}) {
+	go containerCache.informer.Run(stop)
+}
+
+// IsWarm returns true when the cache is filled with the workspace pods and is able to serve requests.
+func (containerCache *ContainerCache) IsWarm() bool {
+	return containerCache.informer.HasSynced()
+}
+
+// AddRestartHandler registers handler to be notified about restarted containers.
+func (containerCache *ContainerCache) AddRestartHandler(handler ContainerRestartHandler) {
+	containerCache.lock.Lock()
+	defer containerCache.lock.Unlock()
+
+	containerCache.restartHandlers = append(containerCache.restartHandlers, handler)
+}
+
+// getRunningPods returns the cached workspace pods which are running, sorted by name.
+func (containerCache *ContainerCache) getRunningPods() *v1.PodList {
+	pods := &v1.PodList{}
+	for _, obj := range containerCache.informer.GetStore().List() {
+		pod, ok := obj.(*v1.Pod)
+		if ok && pod.Status.Phase == v1.PodRunning {
+			pods.Items = append(pods.Items, *pod)
+		}
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+	return pods
+}
+
+func (containerCache *ContainerCache) onPodUpdate(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*v1.Pod)
+	if !ok {
+		return
+	}
+
+	for _, containerName := range restartedContainers(oldPod, newPod) {
+		logrus.Debugf("Container %s/%s is restarted", newPod.Name, containerName)
+		containerCache.notifyRestart(newPod.Name, containerName)
+	}
+}
+
+func (containerCache *ContainerCache) onPodDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+
+	for _, container := range pod.Spec.Containers {
+		containerCache.notifyRestart(pod.Name, container.Name)
+	}
+}
+
+func (containerCache *ContainerCache) notifyRestart(podName string, containerName string) {
+	containerCache.lock.RLock()
+	defer containerCache.lock.RUnlock()
+
+	for _, handler := range containerCache.restartHandlers {
+		handler(podName, containerName)
+	}
+}
+
+// restartedContainers returns names of the containers which are restarted between the pod updates.
+func restartedContainers(oldPod, newPod *v1.Pod) (containers []string) {
+	oldStatuses := make(map[string]v1.ContainerStatus)
+	for _, status := range oldPod.Status.ContainerStatuses {
+		oldStatuses[status.Name] = status
+	}
+
+	for _, status := range newPod.Status.ContainerStatuses {
+		oldStatus, ok := oldStatuses[status.Name]
+		if !ok {
+			continue
+		}
+		if oldStatus.RestartCount != status.RestartCount ||
+			(oldStatus.ContainerID != "" && oldStatus.ContainerID != status.ContainerID) {
+			containers = append(containers, status.Name)
+		}
+	}
+	return containers
+}
@@ -0,0 +1,110 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func startContainerCache(t *testing.T, pods ...corev1.Pod) (*ContainerCache, *watch.FakeWatcher, *mocks.PodInterface, chan struct{}) {
+	podGetter := &mocks.PodsGetter{}
+	podInterface := &mocks.PodInterface{}
+	watcher := watch.NewFake()
+
+	podGetter.On("Pods", Namespace).Return(podInterface)
+	podInterface.On("List", mock.Anything, mock.Anything).Return(&corev1.PodList{Items: pods}, nil)
+	podInterface.On("Watch", mock.Anything, mock.Anything).Return(watcher, nil)
+
+	stop := make(chan struct{})
+	containerCache := NewContainerCache(Namespace, podGetter)
+	containerCache.Start(stop)
+	assert.Eventually(t, containerCache.IsWarm, 5*time.Second, 10*time.Millisecond)
+
+	return containerCache, watcher, podInterface, stop
+}
+
+func createRunningPod(podName string, containers []corev1.Container, restartCount int32) corev1.Pod {
+	pod := createPod(podName, containers)
+	pod.Status.Phase = corev1.PodRunning
+	for _, container := range containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses,
+			corev1.ContainerStatus{Name: container.Name, RestartCount: restartCount})
+	}
+	return pod
+}
+
+func TestShouldFindContainersInWarmCacheWithoutListingPods(t *testing.T) {
+	container1 := createContainer(MachineName1, ContainerName1)
+	container2 := createContainer(MachineName2, ContainerName2)
+	pod1 := createRunningPod(PodName1, []corev1.Container{container2}, 0)
+	pod2 := createRunningPod(PodName2, []corev1.Container{container1}, 0)
+	pendingPod := createPod("pending", []corev1.Container{createContainer(MachineName3, ContainerName3)})
+
+	containerCache, _, podInterface, stop := startContainerCache(t, pod2, pendingPod, pod1)
+	defer close(stop)
+
+	containerFilter := NewCachedKubernetesContainerFilter(Namespace, &mocks.PodsGetter{}, containerCache)
+	containerInfo, err := containerFilter.FindContainerInfo(identifier)
+	assert.Nil(t, err)
+	assert.Equal(t, ContainerName1, containerInfo.ContainerName)
+	assert.Equal(t, PodName2, containerInfo.PodName)
+
+	containersInfo, err := containerFilter.GetContainerList()
+	assert.Nil(t, err)
+	assert.Len(t, containersInfo, 2)
+	assert.Equal(t, PodName1, containersInfo[0].PodName)
+
+	podInterface.AssertNumberOfCalls(t, "List", 1)
+}
+
+func TestShouldNotifyAboutRestartedContainers(t *testing.T) {
+	container1 := createContainer(MachineName1, ContainerName1)
+	container2 := createContainer(MachineName2, ContainerName2)
+	pod := createRunningPod(PodName1, []corev1.Container{container1, container2}, 0)
+
+	containerCache, watcher, _, stop := startContainerCache(t, pod)
+	defer close(stop)
+
+	restarted := make(chan string, 2)
+	containerCache.AddRestartHandler(func(podName string, containerName string) {
+		restarted <- podName + "/" + containerName
+	})
+
+	restartedPod := pod.DeepCopy()
+	restartedPod.Status.ContainerStatuses[1].RestartCount = 1
+	watcher.Modify(restartedPod)
+
+	select {
+	case container := <-restarted:
+		assert.Equal(t, PodName1+"/"+ContainerName2, container)
+	case <-time.After(5 * time.Second):
+		t.Fatal("container restart is not notified")
+	}
+
+	watcher.Delete(restartedPod)
+	for _, containerName := range []string{ContainerName1, ContainerName2} {
+		select {
+		case container := <-restarted:
+			assert.Equal(t, PodName1+"/"+containerName, container)
+		case <-time.After(5 * time.Second):
+			t.Fatal("pod deletion is not notified")
+		}
+	}
+}
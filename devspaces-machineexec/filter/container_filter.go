@@ -19,6 +19,9 @@ type ContainerFilter interface {
 	// Return list Che workspace containers.
 	GetContainerList() (containersInfo []*model.ContainerInfo, err error)
 
+	// Return metadata of the Che workspace containers for the clients to choose where to open terminal.
+	ListContainers() (containers []*model.ContainerMetadata, err error)
+
 	// Find container information by Che specific container identifier.
 	// Return error in case fail filter operation.
 	FindContainerInfo(identifier *model.MachineIdentifier) (containerInfo *model.ContainerInfo, err error)
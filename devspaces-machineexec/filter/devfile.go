@@ -0,0 +1,124 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package filter
+
+import (
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// UserFacingAttribute of the devfile component set to false excludes the component containers
+	// from the containers where terminals are opened.
+	UserFacingAttribute = "che-machine-exec.eclipse.org/user-facing"
+	// DefaultTerminalAttribute of the devfile component set to true makes the component container
+	// the preferred one to open terminal when container is not specified.
+	DefaultTerminalAttribute = "che-machine-exec.eclipse.org/default-terminal"
+)
+
+var (
+	devfileMetadata     *DevfileMetadata
+	devfileMetadataOnce sync.Once
+)
+
+type devfileComponent struct {
+	Name       string                 `json:"name"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// flattened devworkspace contains either devworkspace template spec or the whole devworkspace.
+type flattenedDevWorkspace struct {
+	Components []devfileComponent `json:"components,omitempty"`
+	Spec       struct {
+		Template struct {
+			Components []devfileComponent `json:"components,omitempty"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// DevfileMetadata holds attributes of the devfile components by component name.
+// Nil metadata means devfile is not available and all containers are treated equally.
+type DevfileMetadata struct {
+	components map[string]map[string]interface{}
+}
+
+// GetDevfileMetadata returns metadata loaded from the devfile at cfg.DevfilePath once.
+// Returns nil if devfile path is not configured or devfile can't be read.
+func GetDevfileMetadata() *DevfileMetadata {
+	devfileMetadataOnce.Do(func() {
+		if cfg.DevfilePath == "" {
+			return
+		}
+		metadata, err := LoadDevfileMetadata(cfg.DevfilePath)
+		if err != nil {
+			logrus.Warnf("Unable to read devfile %s, devfile attributes are ignored. Cause: %s", cfg.DevfilePath, err.Error())
+			return
+		}
+		devfileMetadata = metadata
+	})
+	return devfileMetadata
+}
+
+// LoadDevfileMetadata reads attributes of the devfile components from the flattened devworkspace.
+func LoadDevfileMetadata(path string) (*DevfileMetadata, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDevfileMetadata(content)
+}
+
+func parseDevfileMetadata(content []byte) (*DevfileMetadata, error) {
+	devWorkspace := &flattenedDevWorkspace{}
+	if err := yaml.Unmarshal(content, devWorkspace); err != nil {
+		return nil, err
+	}
+
+	components := devWorkspace.Components
+	if len(components) == 0 {
+		components = devWorkspace.Spec.Template.Components
+	}
+
+	metadata := &DevfileMetadata{components: make(map[string]map[string]interface{})}
+	for _, component := range components {
+		metadata.components[component.Name] = component.Attributes
+	}
+	return metadata, nil
+}
+
+// component returns attributes of the devfile component. Ok is false if there is no such component.
+func (metadata *DevfileMetadata) component(name string) (attributes map[string]interface{}, ok bool) {
+	if metadata == nil {
+		return nil, false
+	}
+	attributes, ok = metadata.components[name]
+	return attributes, ok
+}
+
+// boolAttribute returns value of the boolean attribute which could be set either as bool or as string.
+func boolAttribute(attributes map[string]interface{}, name string, defaultValue bool) bool {
+	switch value := attributes[name].(type) {
+	case bool:
+		return value
+	case string:
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
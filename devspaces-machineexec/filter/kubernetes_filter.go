@@ -16,6 +16,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/eclipse-che/che-machine-exec/api/model"
 	"github.com/eclipse-che/che-machine-exec/cfg"
@@ -38,6 +39,11 @@ type KubernetesContainerFilter struct {
 
 	podGetterApi corev1.PodsGetter
 	namespace    string
+
+	// containerCache is used instead of listing the pods when it is warm. Can be nil.
+	containerCache *ContainerCache
+	// devfile provides attributes of the devfile components. Can be nil.
+	devfile *DevfileMetadata
 }
 
 // Create new kubernetes container filter.
@@ -45,10 +51,43 @@ func NewKubernetesContainerFilter(namespace string, podGetterApi corev1.PodsGett
 	return &KubernetesContainerFilter{
 		namespace:    namespace,
 		podGetterApi: podGetterApi,
+		devfile:      GetDevfileMetadata(),
 	}
 }
 
+// Create new kubernetes container filter which looks for the workspace pods in the container cache
+// when it is warm.
+func NewCachedKubernetesContainerFilter(namespace string, podGetterApi corev1.PodsGetter, containerCache *ContainerCache) *KubernetesContainerFilter {
+	return &KubernetesContainerFilter{
+		namespace:      namespace,
+		podGetterApi:   podGetterApi,
+		containerCache: containerCache,
+		devfile:        GetDevfileMetadata(),
+	}
+}
+
+// Return list of the user-facing workspace containers where terminal could be opened.
+// Container marked by devfile as the default terminal target goes first,
+// then containers of the devfile components and then the rest ones.
 func (filter *KubernetesContainerFilter) GetContainerList() (containersInfo []*model.ContainerInfo, err error) {
+	containers, err := filter.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, container := range containers {
+		if container.Kind == model.RegularContainerKind {
+			containerInfo := container.ContainerInfo
+			containersInfo = append(containersInfo, &containerInfo)
+		}
+	}
+
+	return containersInfo, nil
+}
+
+// ListContainers returns metadata of the user-facing workspace containers including running
+// init and ephemeral containers, in the same order as GetContainerList.
+func (filter *KubernetesContainerFilter) ListContainers() (containers []*model.ContainerMetadata, err error) {
 	pods, err := filter.getWorkspacePods()
 	if err != nil {
 		return nil, err
@@ -56,13 +95,35 @@ func (filter *KubernetesContainerFilter) GetContainerList() (containersInfo []*m
 
 	for _, pod := range pods.Items {
 		for _, container := range pod.Spec.Containers {
-			containersInfo = append(containersInfo, &model.ContainerInfo{ContainerName: container.Name, PodName: pod.Name})
+			containers = append(containers, filter.describe(pod.Name, container.Name, container.Image, model.RegularContainerKind))
+		}
+		for _, container := range pod.Spec.InitContainers {
+			if isRunning(pod.Status.InitContainerStatuses, container.Name) {
+				containers = append(containers, filter.describe(pod.Name, container.Name, container.Image, model.InitContainerKind))
+			}
+		}
+		for _, container := range pod.Spec.EphemeralContainers {
+			if isRunning(pod.Status.EphemeralContainerStatuses, container.Name) {
+				containers = append(containers, filter.describe(pod.Name, container.Name, container.Image, model.EphemeralContainerKind))
+			}
 		}
 	}
 
-	return containersInfo, nil
+	userFacing := containers[:0]
+	for _, container := range containers {
+		if boolAttribute(container.Attributes, UserFacingAttribute, true) {
+			userFacing = append(userFacing, container)
+		}
+	}
+	sort.SliceStable(userFacing, func(i, j int) bool {
+		return rank(userFacing[i]) < rank(userFacing[j])
+	})
+
+	return userFacing, nil
 }
 
+// Find container by name. Init and ephemeral containers could be found by name as well,
+// as long as they are running.
 func (filter *KubernetesContainerFilter) GetContainer(name string) (containerInfo *model.ContainerInfo, err error) {
 	pods, err := filter.getWorkspacePods()
 	if err != nil {
@@ -70,10 +131,8 @@ func (filter *KubernetesContainerFilter) GetContainer(name string) (containerInf
 	}
 
 	for _, pod := range pods.Items {
-		for _, container := range pod.Spec.Containers {
-			if container.Name == name {
-				return &model.ContainerInfo{ContainerName: container.Name, PodName: pod.Name}, nil
-			}
+		if containerName := findContainerByName(pod, name); containerName != "" {
+			return &model.ContainerInfo{ContainerName: containerName, PodName: pod.Name}, nil
 		}
 	}
 
@@ -89,6 +148,9 @@ func (filter *KubernetesContainerFilter) FindContainerInfo(identifier *model.Mac
 
 	for _, pod := range wsPods.Items {
 		containerName := findContainerName(pod, identifier.MachineName)
+		if containerName == "" {
+			containerName = findContainerByName(pod, identifier.MachineName)
+		}
 		if containerName != "" {
 			return &model.ContainerInfo{ContainerName: containerName, PodName: pod.Name}, nil
 		}
@@ -97,11 +159,30 @@ func (filter *KubernetesContainerFilter) FindContainerInfo(identifier *model.Mac
 	return nil, errors.New("container with name " + identifier.MachineName + " was not found.")
 }
 
-func (filter *KubernetesContainerFilter) getWorkspacePods() (*v1.PodList, error) {
-	filterOptions := metav1.ListOptions{LabelSelector: cfg.PodSelector, FieldSelector: "status.phase=Running"}
-	wsPods, err := filter.podGetterApi.Pods(filter.namespace).List(context.TODO(), filterOptions)
-	if err != nil {
-		return nil, err
+// describe creates metadata of the container with attributes of the devfile component the container is created for.
+func (filter *KubernetesContainerFilter) describe(podName, containerName, image, kind string) *model.ContainerMetadata {
+	container := &model.ContainerMetadata{
+		ContainerInfo: model.ContainerInfo{PodName: podName, ContainerName: containerName},
+		Kind:          kind,
+		Image:         image,
+	}
+	if attributes, ok := filter.devfile.component(containerName); ok {
+		container.Component = containerName
+		container.Attributes = attributes
+		container.DefaultTerminal = boolAttribute(attributes, DefaultTerminalAttribute, false)
+	}
+	return container
+}
+
+func (filter *KubernetesContainerFilter) getWorkspacePods() (wsPods *v1.PodList, err error) {
+	if filter.containerCache != nil && filter.containerCache.IsWarm() {
+		wsPods = filter.containerCache.getRunningPods()
+	} else {
+		filterOptions := metav1.ListOptions{LabelSelector: cfg.PodSelector, FieldSelector: "status.phase=Running"}
+		wsPods, err = filter.podGetterApi.Pods(filter.namespace).List(context.TODO(), filterOptions)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(wsPods.Items) == 0 {
@@ -125,3 +206,45 @@ func findContainerName(pod v1.Pod, machineName string) string {
 	}
 	return ""
 }
+
+// findContainerByName looks for the container with the name among regular, init and ephemeral containers of the pod.
+// Init and ephemeral containers are found only while they are running.
+func findContainerByName(pod v1.Pod, name string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return container.Name
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name && isRunning(pod.Status.InitContainerStatuses, name) {
+			return container.Name
+		}
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == name && isRunning(pod.Status.EphemeralContainerStatuses, name) {
+			return container.Name
+		}
+	}
+	return ""
+}
+
+func isRunning(statuses []v1.ContainerStatus, name string) bool {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// rank defines order of the containers to open terminal in when container is not specified.
+func rank(container *model.ContainerMetadata) int {
+	switch {
+	case container.DefaultTerminal:
+		return 0
+	case container.Component != "":
+		return 1
+	default:
+		return 2
+	}
+}
@@ -41,7 +41,7 @@ const (
 	PodName2 = "pod2"
 )
 
-var identifier = &model.MachineIdentifier{"dev-machine"}
+var identifier = &model.MachineIdentifier{MachineName: "dev-machine"}
 
 func init() {
 	os.Setenv("CHE_WORKSPACE_ID", "some_workspace")
@@ -228,3 +228,81 @@ func createPod(podName string, containers []corev1.Container) corev1.Pod {
 	podSpec := corev1.PodSpec{Containers: containers}
 	return corev1.Pod{Spec: podSpec, ObjectMeta: v1.ObjectMeta{Name: podName}}
 }
+
+const flattenedDevfile = `
+components:
+  - name: tool1Wqe
+    attributes:
+      che-machine-exec.eclipse.org/default-terminal: true
+  - name: tool2iop
+    attributes:
+      che-machine-exec.eclipse.org/user-facing: "false"
+  - name: tool3fds
+`
+
+func TestShouldOrderContainersByDevfileAttributes(t *testing.T) {
+	podGetter := &mocks.PodsGetter{}
+	podInterface := &mocks.PodInterface{}
+
+	gateway := corev1.Container{Name: "che-gateway"}
+	container1 := createContainer(MachineName1, ContainerName1)
+	container2 := createContainer(MachineName2, ContainerName2)
+	container3 := createContainer(MachineName3, ContainerName3)
+	pod := createPod(PodName1, []corev1.Container{gateway, container2, container3, container1})
+	podList := corev1.PodList{Items: []corev1.Pod{pod}}
+
+	podGetter.On("Pods", Namespace).Return(podInterface).Once()
+	podInterface.On("List", mock.Anything, mock.Anything).Return(&podList, nil)
+
+	devfile, err := parseDevfileMetadata([]byte(flattenedDevfile))
+	assert.Nil(t, err)
+	containerFilter := NewKubernetesContainerFilter(Namespace, podGetter)
+	containerFilter.devfile = devfile
+
+	containersInfo, err := containerFilter.GetContainerList()
+	assert.Nil(t, err)
+
+	var names []string
+	for _, containerInfo := range containersInfo {
+		names = append(names, containerInfo.ContainerName)
+	}
+	assert.Equal(t, []string{ContainerName1, ContainerName3, "che-gateway"}, names)
+}
+
+func TestShouldListRunningEphemeralAndInitContainers(t *testing.T) {
+	podGetter := &mocks.PodsGetter{}
+	podInterface := &mocks.PodInterface{}
+
+	pod := createPod(PodName1, []corev1.Container{createContainer(MachineName1, ContainerName1)})
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init-running"}, {Name: "init-completed"}}
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+		{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox"}},
+	}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "init-running", State: running},
+		{Name: "init-completed", State: terminated},
+	}
+	pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "debugger", State: running}}
+	podList := corev1.PodList{Items: []corev1.Pod{pod}}
+
+	podGetter.On("Pods", Namespace).Return(podInterface)
+	podInterface.On("List", mock.Anything, mock.Anything).Return(&podList, nil)
+
+	containerFilter := NewKubernetesContainerFilter(Namespace, podGetter)
+	containers, err := containerFilter.ListContainers()
+	assert.Nil(t, err)
+	assert.Len(t, containers, 3)
+	assert.Equal(t, model.InitContainerKind, containers[1].Kind)
+	assert.Equal(t, "init-running", containers[1].ContainerName)
+	assert.Equal(t, model.EphemeralContainerKind, containers[2].Kind)
+	assert.Equal(t, "busybox", containers[2].Image)
+
+	containerInfo, err := containerFilter.GetContainer("debugger")
+	assert.Nil(t, err)
+	assert.Equal(t, "debugger", containerInfo.ContainerName)
+
+	_, err = containerFilter.GetContainer("init-completed")
+	assert.NotNil(t, err)
+}
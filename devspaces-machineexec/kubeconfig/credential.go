@@ -0,0 +1,207 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package kubeconfig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/sirupsen/logrus"
+)
+
+// CredentialKeyHeader is the header the credential plugin sends its key in.
+const CredentialKeyHeader = "X-Credential-Key"
+
+// CredentialPath is the path of the machine exec endpoint the credential plugin gets the token from.
+const CredentialPath = "/exec/credential"
+
+// ExecCredential is the response of the exec credential plugin kubectl and oc understand.
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+type ExecCredentialStatus struct {
+	Token string `json:"token"`
+	// ExpirationTimestamp is omitted if it is unknown when token expires.
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// trackedKubeConfig is kubeconfig created in the container.
+type trackedKubeConfig struct {
+	params model.KubeConfigParams
+	// user whose token the kubeconfig is created with
+	userID string
+	// key the credential plugin in the container gets the token with
+	key string
+}
+
+// userToken is the current token of the user.
+type userToken struct {
+	token string
+	// expiry is zero if it is unknown when token expires
+	expiry time.Time
+}
+
+// kubeConfigs keeps the current token of each user and the containers where kubeconfig is created.
+// Users are identified by ID, all the tokens belong to the same user if authentication is disabled.
+type kubeConfigs struct {
+	mutex sync.Mutex
+
+	tokens map[string]*userToken
+
+	containers map[model.ContainerInfo]*trackedKubeConfig
+}
+
+var tracked = newKubeConfigs()
+
+func newKubeConfigs() *kubeConfigs {
+	return &kubeConfigs{
+		tokens:     make(map[string]*userToken),
+		containers: make(map[model.ContainerInfo]*trackedKubeConfig),
+	}
+}
+
+// setToken remembers the token of the user and returns true if it replaces the other token of the user.
+func (configs *kubeConfigs) setToken(userID string, token string, expiry time.Time) (refreshed bool) {
+	configs.mutex.Lock()
+	defer configs.mutex.Unlock()
+
+	if previous, ok := configs.tokens[userID]; ok {
+		refreshed = previous.token != "" && token != "" && previous.token != token
+	}
+	configs.tokens[userID] = &userToken{token: token, expiry: expiry}
+	if !expiry.IsZero() {
+		logrus.Infof("Kubeconfig token of the user '%s' expires at %s", userID, expiry.Format(time.RFC3339))
+	}
+	return refreshed
+}
+
+// keyOf returns the credential key of the container, new key is generated for the container seen the first time
+// or initialized by the other user, so that the plugin of the other user can't get the token anymore.
+func (configs *kubeConfigs) keyOf(containerInfo model.ContainerInfo, userID string) (string, error) {
+	configs.mutex.Lock()
+	defer configs.mutex.Unlock()
+
+	if trackedConfig, ok := configs.containers[containerInfo]; ok && trackedConfig.userID == userID {
+		return trackedConfig.key, nil
+	}
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func (configs *kubeConfigs) add(containerInfo model.ContainerInfo, kubeConfigParams *model.KubeConfigParams, key string) {
+	configs.mutex.Lock()
+	defer configs.mutex.Unlock()
+
+	params := *kubeConfigParams
+	// token is kept once for all the containers of the user
+	params.BearerToken = ""
+	configs.containers[containerInfo] = &trackedKubeConfig{params: params, userID: kubeConfigParams.UserID, key: key}
+}
+
+func (configs *kubeConfigs) remove(containerInfo model.ContainerInfo) {
+	configs.mutex.Lock()
+	defer configs.mutex.Unlock()
+
+	delete(configs.containers, containerInfo)
+}
+
+// snapshot returns the containers where kubeconfig is created by the user.
+func (configs *kubeConfigs) snapshot(userID string) map[model.ContainerInfo]trackedKubeConfig {
+	configs.mutex.Lock()
+	defer configs.mutex.Unlock()
+
+	snapshot := make(map[model.ContainerInfo]trackedKubeConfig, len(configs.containers))
+	for containerInfo, trackedConfig := range configs.containers {
+		if trackedConfig.userID == userID {
+			snapshot[containerInfo] = *trackedConfig
+		}
+	}
+	return snapshot
+}
+
+// GetCredential returns the current token of the user who created kubeconfig with the key
+// to the credential plugin.
+func GetCredential(key string) (*ExecCredential, error) {
+	tracked.mutex.Lock()
+	defer tracked.mutex.Unlock()
+
+	var keyOwner *trackedKubeConfig
+	for _, trackedConfig := range tracked.containers {
+		if subtle.ConstantTimeCompare([]byte(trackedConfig.key), []byte(key)) == 1 {
+			keyOwner = trackedConfig
+		}
+	}
+	if key == "" || keyOwner == nil {
+		return nil, rest.NewError(http.StatusUnauthorized, "credential key is not valid")
+	}
+	current, ok := tracked.tokens[keyOwner.userID]
+	if !ok || current.token == "" {
+		return nil, rest.NewError(http.StatusUnauthorized, "credential key is not valid")
+	}
+	if !current.expiry.IsZero() && time.Now().After(current.expiry) {
+		logrus.Warnf("Kubeconfig token of the user '%s' expired at %s", keyOwner.userID, current.expiry.Format(time.RFC3339))
+		return nil, rest.NewError(http.StatusUnauthorized, "token is expired, kubeconfig is updated once machine exec gets a fresh token")
+	}
+
+	credential := &ExecCredential{
+		APIVersion: execCredentialAPIVersion,
+		Kind:       "ExecCredential",
+		Status:     ExecCredentialStatus{Token: current.token},
+	}
+	if !current.expiry.IsZero() {
+		expiry := current.expiry
+		credential.Status.ExpirationTimestamp = &expiry
+	}
+	return credential, nil
+}
+
+// credentialPluginText creates the credential plugin stub which gets the token from machine exec with curl or wget.
+func credentialPluginText(key string) (string, error) {
+	_, port, err := net.SplitHostPort(cfg.URL)
+	if err != nil {
+		return "", fmt.Errorf("unable to find machine exec port in %s. Cause: %s", cfg.URL, err.Error())
+	}
+	scheme, insecure, noCheckCertificate := "http", "", ""
+	if cfg.UseTLS {
+		scheme, insecure, noCheckCertificate = "https", " -k", " --no-check-certificate"
+	}
+	url := scheme + "://" + net.JoinHostPort("127.0.0.1", port) + CredentialPath
+
+	return fmt.Sprintf(`#!/bin/sh
+# Generated by che-machine-exec. Prints the current token of the user for kubectl and oc.
+url='%[1]s'
+key='%[2]s'
+if command -v curl >/dev/null 2>&1; then
+  exec curl -sSf%[3]s -H "%[5]s: $key" "$url"
+elif command -v wget >/dev/null 2>&1; then
+  exec wget -q -O -%[4]s --header "%[5]s: $key" "$url"
+fi
+echo 'curl or wget is required to get the token from che-machine-exec' >&2
+exit 1
+`, url, key, insecure, noCheckCertificate, CredentialKeyHeader), nil
+}
@@ -0,0 +1,109 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package kubeconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// prefix of the OpenShift OAuth access tokens
+const openShiftTokenPrefix = "sha256~"
+
+var UserOAuthAccessTokenResource = schema.GroupVersionResource{
+	Group:    "oauth.openshift.io",
+	Version:  "v1",
+	Resource: "useroauthaccesstokens",
+}
+
+// TokenExpiry returns time when the token expires. Expiry of the OpenShift OAuth access token is requested
+// from the cluster with the config authenticated by the token, JWT token is parsed.
+// Zero time is returned if it is unknown when token expires.
+func TokenExpiry(config *rest.Config, token string) time.Time {
+	if token == "" {
+		return time.Time{}
+	}
+
+	var expiry time.Time
+	var err error
+	if strings.HasPrefix(token, openShiftTokenPrefix) {
+		expiry, err = openShiftTokenExpiry(config, token)
+	} else {
+		expiry, err = jwtExpiry(token)
+	}
+	if err != nil {
+		logrus.Debugf("Unable to find out when token expires. Cause: %s", err.Error())
+		return time.Time{}
+	}
+	return expiry
+}
+
+// openShiftTokenExpiry gets the user OAuth access token object which is named after the token hash.
+func openShiftTokenExpiry(config *rest.Config, token string) (time.Time, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hash := sha256.Sum256([]byte(strings.TrimPrefix(token, openShiftTokenPrefix)))
+	name := openShiftTokenPrefix + base64.RawURLEncoding.EncodeToString(hash[:])
+	accessToken, err := client.Resource(UserOAuthAccessTokenResource).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiresIn, _, err := unstructured.NestedInt64(accessToken.Object, "expiresIn")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expiresIn <= 0 {
+		// token never expires
+		return time.Time{}, nil
+	}
+	return accessToken.GetCreationTimestamp().Add(time.Duration(expiresIn) * time.Second), nil
+}
+
+// jwtExpiry reads expiry from the "exp" claim of the JWT token without verifying the token.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("token is not JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
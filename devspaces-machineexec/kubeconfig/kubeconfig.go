@@ -17,14 +17,24 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
 	execInfo "github.com/eclipse-che/che-machine-exec/exec-info"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	// name of the credential plugin stub which is created next to the kubeconfig
+	credentialPluginName = "machine-exec-credential"
+	// api version of the exec credentials the plugin returns
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
 type KubeConfig struct {
 	APIVersion     string     `yaml:"apiVersion"`
 	Clusters       []Clusters `yaml:"clusters"`
@@ -49,8 +59,16 @@ type Users struct {
 	User User   `yaml:"user"`
 }
 
+// User is authenticated either with the token or with the exec credential plugin.
 type User struct {
-	Token string `yaml:"token"`
+	Token string      `yaml:"token,omitempty"`
+	Exec  *ExecConfig `yaml:"exec,omitempty"`
+}
+
+// ExecConfig defines exec credential plugin which provides token of the user.
+type ExecConfig struct {
+	APIVersion string `yaml:"apiVersion"`
+	Command    string `yaml:"command"`
 }
 
 type Contexts struct {
@@ -64,9 +82,10 @@ type Context struct {
 	User      string `yaml:"user"`
 }
 
-func generateKubeConfig(token, server, namespace, username string) *KubeConfig {
-	currentContext := fmt.Sprintf("%s-context", username)
-	return &KubeConfig{
+// generateKubeConfig creates kubeconfig with the context for each namespace.
+// The context of the first namespace is the current one.
+func generateKubeConfig(user User, server string, namespaces []string, username string) *KubeConfig {
+	kubeconfig := &KubeConfig{
 		APIVersion: "v1",
 		Clusters: []Clusters{
 			{
@@ -80,34 +99,38 @@ func generateKubeConfig(token, server, namespace, username string) *KubeConfig {
 		Users: []Users{
 			{
 				Name: username,
-				User: User{
-					Token: token,
-				},
+				User: user,
 			},
 		},
-		Contexts: []Contexts{
-			{
-				Context: Context{
-					Cluster:   server,
-					Namespace: namespace,
-					User:      username,
-				},
-				Name: currentContext,
+		Kind: "Config",
+	}
+
+	for i, namespace := range namespaces {
+		context := fmt.Sprintf("%s-%s-context", username, namespace)
+		if i == 0 {
+			context = fmt.Sprintf("%s-context", username)
+			kubeconfig.CurrentContext = context
+		}
+		kubeconfig.Contexts = append(kubeconfig.Contexts, Contexts{
+			Context: Context{
+				Cluster:   server,
+				Namespace: namespace,
+				User:      username,
 			},
-		},
-		CurrentContext: currentContext,
-		Kind:           "Config",
+			Name: context,
+		})
 	}
+	return kubeconfig
 }
 
-func createKubeConfigText(token, namespace, username string) (string, error) {
+func createKubeConfigText(user User, kubeConfigParams *model.KubeConfigParams) (string, error) {
 	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
 	if host == "" || port == "" {
 		return "", errors.New("Could not find $KUBERNETES_SERVICE_HOST or $KUBERNETES_SERVICE_PORT")
 	}
 
 	server := "https://" + net.JoinHostPort(host, port)
-	kubeconfig := generateKubeConfig(token, server, namespace, username)
+	kubeconfig := generateKubeConfig(user, server, contextNamespaces(kubeConfigParams), kubeConfigParams.Username)
 
 	bytes, err := yaml.Marshal(&kubeconfig)
 	if err != nil {
@@ -117,40 +140,102 @@ func createKubeConfigText(token, namespace, username string) (string, error) {
 	return string(bytes), nil
 }
 
+// contextNamespaces returns the namespace of the user followed by the additional namespaces without duplicates.
+func contextNamespaces(kubeConfigParams *model.KubeConfigParams) []string {
+	namespaces := []string{kubeConfigParams.Namespace}
+	for _, namespace := range kubeConfigParams.Namespaces {
+		duplicate := false
+		for _, added := range namespaces {
+			duplicate = duplicate || added == namespace
+		}
+		if namespace != "" && !duplicate {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
 // CreateKubeConfig creates a kubeconfig located at $KUBECONFIG if set.
 // If it is not set then fall back to $HOME/.kube/config
-func CreateKubeConfig(cmdRslv execInfo.InfoExecCreator, kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error {
-	config, err := createKubeConfigText(kubeConfigParams.BearerToken, kubeConfigParams.Namespace, kubeConfigParams.Username)
+// Container is remembered, so when kubeconfig is created with the fresh token of the user,
+// kubeconfigs of all the containers remembered for the same user are rewritten. tokenExpiry is zero if it is unknown.
+func CreateKubeConfig(cmdRslv execInfo.InfoExecCreator, kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo, tokenExpiry time.Time) error {
+	refreshed := tracked.setToken(kubeConfigParams.UserID, kubeConfigParams.BearerToken, tokenExpiry)
+
+	key, err := tracked.keyOf(*containerInfo, kubeConfigParams.UserID)
 	if err != nil {
 		return err
 	}
+	if err := writeKubeConfig(cmdRslv, kubeConfigParams, key, containerInfo); err != nil {
+		return err
+	}
+	tracked.add(*containerInfo, kubeConfigParams, key)
+
+	if refreshed {
+		rewriteKubeConfigs(cmdRslv, kubeConfigParams.UserID, kubeConfigParams.BearerToken, containerInfo)
+	}
+	return nil
+}
 
-	kubeconfigDir, err := findKubeConfigDir(cmdRslv, containerInfo)
+// rewriteKubeConfigs rewrites kubeconfigs in the containers remembered for the user except the given one with the token.
+// Containers where kubeconfig can't be rewritten are forgotten, since they are likely gone.
+func rewriteKubeConfigs(cmdRslv execInfo.InfoExecCreator, userID string, token string, except *model.ContainerInfo) {
+	for containerInfo, trackedConfig := range tracked.snapshot(userID) {
+		if containerInfo == *except {
+			continue
+		}
+		kubeConfigParams := trackedConfig.params
+		kubeConfigParams.BearerToken = token
+		if err := writeKubeConfig(cmdRslv, &kubeConfigParams, trackedConfig.key, &containerInfo); err != nil {
+			logrus.Warnf("Unable to rewrite kubeconfig in %s/%s. Cause: %s", containerInfo.PodName, containerInfo.ContainerName, err.Error())
+			tracked.remove(containerInfo)
+			continue
+		}
+		logrus.Debugf("Kubeconfig is rewritten in %s/%s with the fresh token", containerInfo.PodName, containerInfo.ContainerName)
+	}
+}
+
+// writeKubeConfig writes kubeconfig into the container. When exec credential plugin is enabled,
+// the plugin stub is written next to the kubeconfig, otherwise the token is written into kubeconfig.
+func writeKubeConfig(cmdRslv execInfo.InfoExecCreator, kubeConfigParams *model.KubeConfigParams, key string, containerInfo *model.ContainerInfo) error {
+	kubeconfigPath, err := findKubeConfigPath(cmdRslv, containerInfo)
 	if err != nil {
 		return err
 	}
 
-	err = createKubeConfigDir(cmdRslv, kubeconfigDir, containerInfo)
+	if !cfg.KubeConfigCredentialPlugin || kubeConfigParams.BearerToken == "" {
+		config, err := createKubeConfigText(User{Token: kubeConfigParams.BearerToken}, kubeConfigParams)
+		if err != nil {
+			return err
+		}
+		return syncFile(cmdRslv, kubeconfigPath, config, "600", containerInfo)
+	}
+
+	pluginPath := path.Join(path.Dir(kubeconfigPath), credentialPluginName)
+	config, err := createKubeConfigText(User{Exec: &ExecConfig{APIVersion: execCredentialAPIVersion, Command: pluginPath}}, kubeConfigParams)
 	if err != nil {
 		return err
 	}
-
-	err = syncKubeConfig(cmdRslv, config, kubeconfigDir, containerInfo)
+	plugin, err := credentialPluginText(key)
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := syncFile(cmdRslv, pluginPath, plugin, "700", containerInfo); err != nil {
+		return err
+	}
+	return syncFile(cmdRslv, kubeconfigPath, config, "600", containerInfo)
 }
 
-func findKubeConfigDir(cmdRslv execInfo.InfoExecCreator, containerInfo *model.ContainerInfo) (string, error) {
+// findKubeConfigPath returns the first path of $KUBECONFIG if it is set, otherwise $HOME/.kube/config.
+func findKubeConfigPath(cmdRslv execInfo.InfoExecCreator, containerInfo *model.ContainerInfo) (string, error) {
 	infoExec := cmdRslv.CreateInfoExec([]string{"sh", "-c", "echo $KUBECONFIG"}, containerInfo)
 	if err := infoExec.Start(); err != nil {
 		logrus.Debugf("Could not retrieve $KUBECONFIG in %s/%s. Error: %s", containerInfo.PodName, containerInfo.ContainerName, err.Error())
 		return "", errors.New("Could not retrieve $KUBECONFIG")
 	}
-	kubeconfigDir := infoExec.GetOutput()
-	if kubeconfigDir != "\n" {
-		return strings.Replace(kubeconfigDir, "/config", "", 1), nil
+	kubeconfigPath := strings.TrimSpace(strings.SplitN(infoExec.GetOutput(), ":", 2)[0])
+	if kubeconfigPath != "" {
+		return kubeconfigPath, nil
 	}
 
 	infoExec = cmdRslv.CreateInfoExec([]string{"sh", "-c", "echo $HOME"}, containerInfo)
@@ -158,24 +243,18 @@ func findKubeConfigDir(cmdRslv execInfo.InfoExecCreator, containerInfo *model.Co
 		logrus.Debugf("Could not retrieve $HOME in %s/%s. Error: %s", containerInfo.PodName, containerInfo.ContainerName, err.Error())
 		return "", errors.New("Could not retrieve $HOME")
 	}
-	kubeconfigDir = strings.TrimSuffix(infoExec.GetOutput(), "\n")
-	return kubeconfigDir + "/.kube", nil
-}
-
-func createKubeConfigDir(cmdRslv execInfo.InfoExecCreator, kubeconfigDir string, containerInfo *model.ContainerInfo) error {
-	infoExec := cmdRslv.CreateInfoExec([]string{"sh", "-c", "mkdir -p " + kubeconfigDir}, containerInfo)
-	if err := infoExec.Start(); err != nil {
-		logrus.Debugf("Could not create directory %s in %s/%s. Error: %s", kubeconfigDir, containerInfo.PodName, containerInfo.ContainerName, err.Error())
-		return errors.New("Could not create directory: " + kubeconfigDir)
-	}
-	return nil
+	home := strings.TrimSuffix(infoExec.GetOutput(), "\n")
+	return home + "/.kube/config", nil
 }
 
-func syncKubeConfig(cmdRslv execInfo.InfoExecCreator, config string, kubeconfigLocation string, containerInfo *model.ContainerInfo) error {
-	infoExec := cmdRslv.CreateInfoExec([]string{"sh", "-c", "echo \"" + config + "\" > " + kubeconfigLocation + "/config"}, containerInfo)
+// syncFile writes content into the file in the container creating its directory if needed.
+// Content and path are passed as arguments of the shell, so they are not interpreted by it.
+func syncFile(cmdRslv execInfo.InfoExecCreator, filePath string, content string, mode string, containerInfo *model.ContainerInfo) error {
+	script := `mkdir -p "$(dirname "$1")" && printf '%s' "$2" > "$1" && chmod "$3" "$1"`
+	infoExec := cmdRslv.CreateInfoExec([]string{"sh", "-c", script, "sh", filePath, content, mode}, containerInfo)
 	if err := infoExec.Start(); err != nil {
-		logrus.Debugf("Could not write kubeconfig to %s in %s/%s. Error: %s", kubeconfigLocation, containerInfo.PodName, containerInfo.ContainerName, err.Error())
-		return errors.New("Could not write kubeconfig to: " + kubeconfigLocation)
+		logrus.Debugf("Could not write %s in %s/%s. Error: %s", filePath, containerInfo.PodName, containerInfo.ContainerName, err.Error())
+		return errors.New("Could not write kubeconfig to: " + filePath)
 	}
 	return nil
 }
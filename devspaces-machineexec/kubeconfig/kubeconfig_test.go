@@ -0,0 +1,239 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	execInfo "github.com/eclipse-che/che-machine-exec/exec-info"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+// fakeContainers keeps files written into the containers by info execs.
+type fakeContainers struct {
+	files map[model.ContainerInfo]map[string]string
+	// containers where nothing could be written
+	broken map[model.ContainerInfo]bool
+}
+
+func newFakeContainers() *fakeContainers {
+	return &fakeContainers{
+		files:  make(map[model.ContainerInfo]map[string]string),
+		broken: make(map[model.ContainerInfo]bool),
+	}
+}
+
+func (containers *fakeContainers) CreateInfoExec(command []string, containerInfo *model.ContainerInfo) execInfo.InfoExec {
+	return &fakeInfoExec{containers: containers, command: command, containerInfo: *containerInfo}
+}
+
+type fakeInfoExec struct {
+	containers    *fakeContainers
+	command       []string
+	containerInfo model.ContainerInfo
+	output        string
+}
+
+func (infoExec *fakeInfoExec) Start() error {
+	switch infoExec.command[2] {
+	case "echo $KUBECONFIG":
+		infoExec.output = "\n"
+	case "echo $HOME":
+		infoExec.output = "/home/user\n"
+	default:
+		if infoExec.containers.broken[infoExec.containerInfo] {
+			return errors.New("container is gone")
+		}
+		files, ok := infoExec.containers.files[infoExec.containerInfo]
+		if !ok {
+			files = make(map[string]string)
+			infoExec.containers.files[infoExec.containerInfo] = files
+		}
+		files[infoExec.command[4]] = infoExec.command[5]
+	}
+	return nil
+}
+
+func (infoExec *fakeInfoExec) GetOutput() string {
+	return infoExec.output
+}
+
+func setUp(t *testing.T, credentialPlugin bool) {
+	tracked = newKubeConfigs()
+	previousCredentialPlugin, previousURL := cfg.KubeConfigCredentialPlugin, cfg.URL
+	cfg.KubeConfigCredentialPlugin, cfg.URL = credentialPlugin, "0.0.0.0:4444"
+	_ = os.Setenv("KUBERNETES_SERVICE_HOST", "172.30.0.1")
+	_ = os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	t.Cleanup(func() {
+		cfg.KubeConfigCredentialPlugin, cfg.URL = previousCredentialPlugin, previousURL
+		_ = os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		_ = os.Unsetenv("KUBERNETES_SERVICE_PORT")
+	})
+}
+
+func parseKubeConfig(t *testing.T, text string) *KubeConfig {
+	kubeconfig := &KubeConfig{}
+	if err := yaml.Unmarshal([]byte(text), kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	return kubeconfig
+}
+
+func TestShouldCreateContextsForAdditionalNamespaces(t *testing.T) {
+	setUp(t, false)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	params := &model.KubeConfigParams{
+		Namespace:   "user-che",
+		Namespaces:  []string{"shared", "user-che", "", "shared", "staging"},
+		Username:    "Developer",
+		BearerToken: "token",
+	}
+
+	err := CreateKubeConfig(containers, params, tools, time.Time{})
+
+	assert.Nil(t, err)
+	kubeconfig := parseKubeConfig(t, containers.files[*tools]["/home/user/.kube/config"])
+	assert.Equal(t, "Developer-context", kubeconfig.CurrentContext)
+	assert.Equal(t, "token", kubeconfig.Users[0].User.Token)
+	var contexts []string
+	for _, context := range kubeconfig.Contexts {
+		contexts = append(contexts, context.Name+"="+context.Context.Namespace)
+	}
+	assert.Equal(t, []string{"Developer-context=user-che", "Developer-shared-context=shared", "Developer-staging-context=staging"}, contexts)
+}
+
+func TestShouldRewriteKubeConfigsWithFreshToken(t *testing.T) {
+	setUp(t, false)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	node := &model.ContainerInfo{PodName: "workspace", ContainerName: "node"}
+	gone := &model.ContainerInfo{PodName: "workspace", ContainerName: "gone"}
+	for _, containerInfo := range []*model.ContainerInfo{tools, node, gone} {
+		params := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "expired"}
+		assert.Nil(t, CreateKubeConfig(containers, params, containerInfo, time.Time{}))
+	}
+	containers.broken[*gone] = true
+
+	params := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "fresh"}
+	err := CreateKubeConfig(containers, params, tools, time.Time{})
+
+	assert.Nil(t, err)
+	for _, containerInfo := range []*model.ContainerInfo{tools, node} {
+		kubeconfig := parseKubeConfig(t, containers.files[*containerInfo]["/home/user/.kube/config"])
+		assert.Equal(t, "fresh", kubeconfig.Users[0].User.Token)
+	}
+	kubeconfig := parseKubeConfig(t, containers.files[*gone]["/home/user/.kube/config"])
+	assert.Equal(t, "expired", kubeconfig.Users[0].User.Token)
+	assert.NotContains(t, tracked.snapshot(""), *gone)
+}
+
+func TestShouldUseCredentialPlugin(t *testing.T) {
+	setUp(t, true)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	params := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "token"}
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	err := CreateKubeConfig(containers, params, tools, expiry)
+
+	assert.Nil(t, err)
+	kubeconfig := parseKubeConfig(t, containers.files[*tools]["/home/user/.kube/config"])
+	assert.Empty(t, kubeconfig.Users[0].User.Token)
+	assert.Equal(t, &ExecConfig{APIVersion: execCredentialAPIVersion, Command: "/home/user/.kube/machine-exec-credential"}, kubeconfig.Users[0].User.Exec)
+
+	plugin := containers.files[*tools]["/home/user/.kube/machine-exec-credential"]
+	key := tracked.snapshot("")[*tools].key
+	assert.Contains(t, plugin, "url='http://127.0.0.1:4444/exec/credential'")
+	assert.Contains(t, plugin, "key='"+key+"'")
+
+	credential, err := GetCredential(key)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", credential.Status.Token)
+	assert.Equal(t, expiry, *credential.Status.ExpirationTimestamp)
+}
+
+func TestShouldNotReturnCredentialForUnknownKeyOrExpiredToken(t *testing.T) {
+	setUp(t, true)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	params := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "token"}
+	assert.Nil(t, CreateKubeConfig(containers, params, tools, time.Now().Add(-time.Minute)))
+
+	_, err := GetCredential("unknown")
+	assert.Equal(t, rest.NewError(http.StatusUnauthorized, "credential key is not valid"), err)
+	_, err = GetCredential("")
+	assert.Equal(t, rest.NewError(http.StatusUnauthorized, "credential key is not valid"), err)
+	_, err = GetCredential(tracked.snapshot("")[*tools].key)
+	assert.True(t, strings.HasPrefix(err.Error(), "token is expired"))
+}
+
+func TestShouldKeepTokenOfEachUser(t *testing.T) {
+	setUp(t, true)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	node := &model.ContainerInfo{PodName: "workspace", ContainerName: "node"}
+	ownerParams := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "owner-token", UserID: "owner"}
+	assert.Nil(t, CreateKubeConfig(containers, ownerParams, tools, time.Time{}))
+	granteeParams := &model.KubeConfigParams{Namespace: "user-che", Username: "Developer", BearerToken: "grantee-token", UserID: "grantee"}
+	assert.Nil(t, CreateKubeConfig(containers, granteeParams, node, time.Time{}))
+	ownerKey := tracked.snapshot("owner")[*tools].key
+	granteeKey := tracked.snapshot("grantee")[*node].key
+
+	granteeParams.BearerToken = "fresh-grantee-token"
+	assert.Nil(t, CreateKubeConfig(containers, granteeParams, node, time.Time{}))
+
+	credential, err := GetCredential(ownerKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "owner-token", credential.Status.Token)
+	credential, err = GetCredential(granteeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "fresh-grantee-token", credential.Status.Token)
+	assert.Contains(t, containers.files[*tools]["/home/user/.kube/machine-exec-credential"], "key='"+ownerKey+"'")
+}
+
+func TestShouldNotRewriteKubeConfigsOfOtherUser(t *testing.T) {
+	setUp(t, false)
+	containers := newFakeContainers()
+	tools := &model.ContainerInfo{PodName: "workspace", ContainerName: "tools"}
+	node := &model.ContainerInfo{PodName: "workspace", ContainerName: "node"}
+	assert.Nil(t, CreateKubeConfig(containers, &model.KubeConfigParams{Username: "Developer", BearerToken: "owner-token", UserID: "owner"}, tools, time.Time{}))
+	assert.Nil(t, CreateKubeConfig(containers, &model.KubeConfigParams{Username: "Developer", BearerToken: "grantee-token", UserID: "grantee"}, node, time.Time{}))
+
+	err := CreateKubeConfig(containers, &model.KubeConfigParams{Username: "Developer", BearerToken: "fresh-grantee-token", UserID: "grantee"}, node, time.Time{})
+
+	assert.Nil(t, err)
+	kubeconfig := parseKubeConfig(t, containers.files[*tools]["/home/user/.kube/config"])
+	assert.Equal(t, "owner-token", kubeconfig.Users[0].User.Token)
+	kubeconfig = parseKubeConfig(t, containers.files[*node]["/home/user/.kube/config"])
+	assert.Equal(t, "fresh-grantee-token", kubeconfig.Users[0].User.Token)
+}
+
+func TestShouldReadJWTExpiry(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"developer","exp":1700000000}`))
+
+	expiry := TokenExpiry(nil, "eyJhbGciOiJSUzI1NiJ9."+payload+".signature")
+
+	assert.Equal(t, time.Unix(1700000000, 0), expiry)
+	assert.True(t, TokenExpiry(nil, "opaque").IsZero())
+}
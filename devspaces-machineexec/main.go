@@ -14,13 +14,19 @@ package main
 
 import (
 	"net/http"
+	"os"
 
 	"github.com/eclipse-che/che-machine-exec/timeout"
 
 	jsonRpcApi "github.com/eclipse-che/che-machine-exec/api/jsonrpc"
 	"github.com/eclipse-che/che-machine-exec/api/rest"
 	"github.com/eclipse-che/che-machine-exec/api/websocket"
+	"github.com/eclipse-che/che-machine-exec/auth"
 	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/kubeconfig"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/eclipse-che/che-machine-exec/output/recording"
 	jsonrpc "github.com/eclipse/che-go-jsonrpc"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -57,15 +63,30 @@ func main() {
 	})
 
 	// attach to get exec output and sent user input(by simple websocket)
-	// Todo: rework to use only one websocket connection https://github.com/eclipse-che/che-machine-exec/issues/4
+	// kept for clients which do not support multiplexed protocol
 	r.GET("/attach/:id", func(c *gin.Context) {
 		websocket.HandleAttach(c)
 	})
 
+	// create, attach and control execs over the single websocket connection(binary multiplexed protocol)
+	r.GET("/v1/multiplex", func(c *gin.Context) {
+		websocket.HandleMultiplex(c)
+	})
+
+	// tunnel bytes to the port of the workspace container(binary websocket messages)
+	r.GET("/portforward", func(c *gin.Context) {
+		websocket.HandlePortForward(c)
+	})
+
 	r.POST("/exec/config", func(c *gin.Context) {
 		rest.HandleKubeConfig(c)
 	})
 
+	// current token for the credential plugin of the kubeconfigs created in the containers
+	r.GET(kubeconfig.CredentialPath, func(c *gin.Context) {
+		rest.HandleCredential(c)
+	})
+
 	r.POST("/exec/init", func(c *gin.Context) {
 		rest.HandleInit(c)
 	})
@@ -74,6 +95,45 @@ func main() {
 		rest.HandleActivityTick(c, activityManager)
 	})
 
+	// postpone workspace stop by idle or run timeout once
+	r.POST("/activity/postpone", func(c *gin.Context) {
+		rest.HandleStopPostpone(c, activityManager, runtimeManager)
+	})
+
+	// live execs amount, age and transferred bytes
+	r.GET("/exec/stats", func(c *gin.Context) {
+		rest.HandleExecStats(c)
+	})
+
+	// tar archives and files transfer to and from the workspace containers
+	r.GET("/files/archive", func(c *gin.Context) {
+		rest.HandleDownloadArchive(c)
+	})
+
+	r.POST("/files/archive", func(c *gin.Context) {
+		rest.HandleUploadArchive(c)
+	})
+
+	r.GET("/files/content", func(c *gin.Context) {
+		rest.HandleDownloadFile(c)
+	})
+
+	r.PUT("/files/content", func(c *gin.Context) {
+		rest.HandleUploadFile(c)
+	})
+
+	r.GET("/recordings", func(c *gin.Context) {
+		rest.HandleListRecordings(c)
+	})
+
+	r.GET("/recordings/:name", func(c *gin.Context) {
+		rest.HandleDownloadRecording(c)
+	})
+
+	if cfg.EnableMetrics {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
 	r.GET("/healthz", func(c *gin.Context) {
 		c.Writer.WriteHeader(http.StatusOK)
 	})
@@ -94,6 +154,19 @@ func main() {
 		runtimeManager.Start()
 	}
 
+	if cfg.RecordingsPath != "" && cfg.RecordingRetention > 0 {
+		recording.StartCleanup(cfg.RecordingsPath, cfg.RecordingRetention)
+	}
+
+	if auth.IsEnabled() {
+		if cfg.Authenticator == cfg.KubernetesAuthenticator {
+			if err := auth.UseKubernetesAuthenticator(exec.GetNamespace()); err != nil {
+				logrus.Fatal("Unable to set up kubernetes authenticator. Cause: ", err.Error())
+			}
+		}
+		watchAccessPolicy()
+	}
+
 	if cfg.UseTLS {
 		if err := r.RunTLS(cfg.URL, "/var/serving-cert/tls.crt", "/var/serving-cert/tls.key"); err != nil {
 			logrus.Fatal("Unable to start server with TLS enabled. Cause: ", err.Error())
@@ -104,3 +177,23 @@ func main() {
 		}
 	}
 }
+
+// watchAccessPolicy starts reading access policy from the configured sources.
+func watchAccessPolicy() {
+	if cfg.AccessPolicyPath != "" {
+		auth.WatchAccessPolicyFile(cfg.AccessPolicyPath)
+	}
+
+	if cfg.AccessPolicyAnnotation {
+		workspaceName, isFound := os.LookupEnv("CHE_WORKSPACE_NAME")
+		if !isFound {
+			workspaceName = os.Getenv("DEVWORKSPACE_NAME")
+		}
+		if workspaceName == "" {
+			logrus.Fatal("CHE_WORKSPACE_NAME or DEVWORKSPACE_NAME environment variables must be set to watch access policy annotation")
+		}
+		if err := auth.WatchAccessPolicyAnnotation(exec.GetNamespace(), workspaceName); err != nil {
+			logrus.Fatal("Unable to watch access policy annotation. Cause: ", err.Error())
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package metrics
+
+// This package contains prometheus metrics of the machine exec.
+// Metrics are always collected, but they are served on /metrics endpoint only if it is enabled in configuration.
+// SREs are able to alert on workspaces which fail to stop by idle or run timeout with help of these metrics.
@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "machine_exec"
+
+// Phases of the exec creation.
+const (
+	// ShellDetectionPhase detects shell and resolves exec command in the container.
+	ShellDetectionPhase = "shell_detection"
+	// SPDYSetupPhase upgrades connection to the kubernetes api to stream exec over SPDY.
+	SPDYSetupPhase = "spdy_setup"
+	// WebsocketSetupPhase upgrades connection to the kubernetes api to stream exec over websocket.
+	WebsocketSetupPhase = "websocket_setup"
+)
+
+// Endpoints which clients connect to over websocket.
+const (
+	ConnectEndpoint     = "connect"
+	AttachEndpoint      = "attach"
+	MultiplexEndpoint   = "multiplex"
+	PortForwardEndpoint = "portforward"
+)
+
+// Failures of the websocket keep alive.
+const (
+	// PingFailure means that ping message was not sent to the client.
+	PingFailure = "ping"
+	// PongFailure means that client did not respond with pong message in time.
+	PongFailure = "pong"
+)
+
+// Timeouts which stop the workspace.
+const (
+	IdleTimeout = "idle"
+	RunTimeout  = "run"
+)
+
+var (
+	activeExecs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_execs",
+		Help:      "Amount of the live execs.",
+	})
+	createDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "exec_create_duration_seconds",
+		Help:      "Duration of the exec creation phases.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"phase"})
+	websocketConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Amount of the open client websocket connections.",
+	}, []string{"endpoint"})
+	transferredBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exec_bytes_total",
+		Help:      "Amount of bytes sent to the exec input and received from the exec output.",
+	}, []string{"direction"})
+	keepAliveFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_keepalive_failures_total",
+		Help:      "Amount of the client websocket connections which failed ping or pong.",
+	}, []string{"type"})
+	timeoutEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workspace_timeout_enabled",
+		Help:      "Whether workspace is stopped by the timeout.",
+	}, []string{"timeout"})
+	stopRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "workspace_stop_retries_total",
+		Help:      "Amount of the failed attempts to stop the workspace which are retried.",
+	}, []string{"timeout"})
+
+	deadlinesLock = &sync.Mutex{}
+	deadlines     = make(map[string]time.Time)
+)
+
+func init() {
+	prometheus.MustRegister(
+		activeExecs,
+		createDuration,
+		websocketConnections,
+		transferredBytes,
+		keepAliveFailures,
+		timeoutEnabled,
+		stopRetries,
+	)
+	for _, timeout := range []string{IdleTimeout, RunTimeout} {
+		timeout := timeout
+		prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "workspace_timeout_remaining_seconds",
+			Help:        "Time left before the workspace is stopped by the timeout, 0 if workspace should be stopped already.",
+			ConstLabels: prometheus.Labels{"timeout": timeout},
+		}, func() float64 {
+			return timeLeft(timeout).Seconds()
+		}))
+	}
+}
+
+// Handler serves metrics in prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ExecCreated counts new live exec.
+func ExecCreated() {
+	activeExecs.Inc()
+}
+
+// ExecRemoved counts removed exec.
+func ExecRemoved() {
+	activeExecs.Dec()
+}
+
+// ObserveCreatePhase records duration of the exec creation phase.
+func ObserveCreatePhase(phase string, duration time.Duration) {
+	createDuration.WithLabelValues(phase).Observe(duration.Seconds())
+}
+
+// WebsocketConnected counts new client websocket connection to the endpoint.
+func WebsocketConnected(endpoint string) {
+	websocketConnections.WithLabelValues(endpoint).Inc()
+}
+
+// WebsocketDisconnected counts closed client websocket connection to the endpoint.
+func WebsocketDisconnected(endpoint string) {
+	websocketConnections.WithLabelValues(endpoint).Dec()
+}
+
+// AddBytesIn counts bytes sent to the exec input.
+func AddBytesIn(n int) {
+	transferredBytes.WithLabelValues("in").Add(float64(n))
+}
+
+// AddBytesOut counts bytes received from the exec output.
+func AddBytesOut(n int) {
+	transferredBytes.WithLabelValues("out").Add(float64(n))
+}
+
+// KeepAliveFailed counts websocket connection which failed ping or pong.
+func KeepAliveFailed(failure string) {
+	keepAliveFailures.WithLabelValues(failure).Inc()
+}
+
+// SetTimeoutDeadline records the time when workspace is going to be stopped by the timeout.
+func SetTimeoutDeadline(timeout string, deadline time.Time) {
+	defer deadlinesLock.Unlock()
+	deadlinesLock.Lock()
+
+	deadlines[timeout] = deadline
+	timeoutEnabled.WithLabelValues(timeout).Set(1)
+}
+
+// StopRetried counts failed attempt to stop the workspace by the timeout.
+func StopRetried(timeout string) {
+	stopRetries.WithLabelValues(timeout).Inc()
+}
+
+func timeLeft(timeout string) time.Duration {
+	defer deadlinesLock.Unlock()
+	deadlinesLock.Lock()
+
+	deadline, ok := deadlines[timeout]
+	if !ok {
+		return 0
+	}
+	if left := time.Until(deadline); left > 0 {
+		return left
+	}
+	return 0
+}
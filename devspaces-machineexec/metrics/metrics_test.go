@@ -0,0 +1,45 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldReportTimeLeftBeforeWorkspaceStop(t *testing.T) {
+	SetTimeoutDeadline(RunTimeout, time.Now().Add(time.Hour))
+	assert.InDelta(t, time.Hour.Seconds(), timeLeft(RunTimeout).Seconds(), 5)
+	assert.Equal(t, float64(1), testutil.ToFloat64(timeoutEnabled.WithLabelValues(RunTimeout)))
+
+	// workspace should be stopped already, but stop is retried
+	SetTimeoutDeadline(RunTimeout, time.Now().Add(-time.Minute))
+	assert.Equal(t, time.Duration(0), timeLeft(RunTimeout))
+}
+
+func TestShouldReportZeroTimeLeftIfTimeoutIsDisabled(t *testing.T) {
+	assert.Equal(t, time.Duration(0), timeLeft(IdleTimeout))
+}
+
+func TestShouldCountActiveExecs(t *testing.T) {
+	before := testutil.ToFloat64(activeExecs)
+
+	ExecCreated()
+	ExecCreated()
+	ExecRemoved()
+
+	assert.Equal(t, before+1, testutil.ToFloat64(activeExecs))
+}
@@ -3,9 +3,16 @@
 package mocks
 
 import (
+	io "io"
+
 	model "github.com/eclipse-che/che-machine-exec/api/model"
+	process "github.com/eclipse-che/che-machine-exec/process"
 	websocket "github.com/gorilla/websocket"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	ws_conn "github.com/eclipse-che/che-machine-exec/ws-conn"
 )
 
 // ExecManager is an autogenerated mock type for the ExecManager type
@@ -13,13 +20,27 @@ type ExecManager struct {
 	mock.Mock
 }
 
-// Attach provides a mock function with given fields: id, conn
-func (_m *ExecManager) Attach(id int, conn *websocket.Conn) error {
-	ret := _m.Called(id, conn)
+// Attach provides a mock function with given fields: id, conn, replayFrom, client
+func (_m *ExecManager) Attach(id int, conn *websocket.Conn, replayFrom int64, client ws_conn.Client) error {
+	ret := _m.Called(id, conn, replayFrom, client)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, *websocket.Conn, int64, ws_conn.Client) error); ok {
+		r0 = rf(id, conn, replayFrom, client)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AttachConnection provides a mock function with given fields: id, conn, replayFrom, client
+func (_m *ExecManager) AttachConnection(id int, conn ws_conn.Connection, replayFrom int64, client ws_conn.Client) error {
+	ret := _m.Called(id, conn, replayFrom, client)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, *websocket.Conn) error); ok {
-		r0 = rf(id, conn)
+	if rf, ok := ret.Get(0).(func(int, ws_conn.Connection, int64, ws_conn.Client) error); ok {
+		r0 = rf(id, conn, replayFrom, client)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -69,6 +90,27 @@ func (_m *ExecManager) Create(machineExec *model.MachineExec) (int, error) {
 	return r0, r1
 }
 
+// CreateDebug provides a mock function with given fields: machineExec, target
+func (_m *ExecManager) CreateDebug(machineExec *model.MachineExec, target string) (int, error) {
+	ret := _m.Called(machineExec, target)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(*model.MachineExec, string) int); ok {
+		r0 = rf(machineExec, target)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.MachineExec, string) error); ok {
+		r1 = rf(machineExec, target)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateKubeConfig provides a mock function with given fields: kubeConfigParams, containerInfo
 func (_m *ExecManager) CreateKubeConfig(kubeConfigParams *model.KubeConfigParams, containerInfo *model.ContainerInfo) error {
 	ret := _m.Called(kubeConfigParams, containerInfo)
@@ -83,16 +125,49 @@ func (_m *ExecManager) CreateKubeConfig(kubeConfigParams *model.KubeConfigParams
 	return r0
 }
 
+// DetachConnection provides a mock function with given fields: id, conn
+func (_m *ExecManager) DetachConnection(id int, conn ws_conn.Connection) {
+	_m.Called(id, conn)
+}
+
+// Input provides a mock function with given fields: id, data
+func (_m *ExecManager) Input(id int, data []byte) error {
+	ret := _m.Called(id, data)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, []byte) error); ok {
+		r0 = rf(id, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Kill provides a mock function with given fields: id
+func (_m *ExecManager) Kill(id int) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ListAvailableContainers provides a mock function with given fields: machineExec
-func (_m *ExecManager) ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerInfo, error) {
+func (_m *ExecManager) ListAvailableContainers(machineExec *model.MachineExec) ([]*model.ContainerMetadata, error) {
 	ret := _m.Called(machineExec)
 
-	var r0 []*model.ContainerInfo
-	if rf, ok := ret.Get(0).(func(*model.MachineExec) []*model.ContainerInfo); ok {
+	var r0 []*model.ContainerMetadata
+	if rf, ok := ret.Get(0).(func(*model.MachineExec) []*model.ContainerMetadata); ok {
 		r0 = rf(machineExec)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.ContainerInfo)
+			r0 = ret.Get(0).([]*model.ContainerMetadata)
 		}
 	}
 
@@ -106,11 +181,78 @@ func (_m *ExecManager) ListAvailableContainers(machineExec *model.MachineExec) (
 	return r0, r1
 }
 
+// ListSessions provides a mock function with given fields: caller
+func (_m *ExecManager) ListSessions(caller *model.MachineExec) []*model.SessionInfo {
+	ret := _m.Called(caller)
+
+	var r0 []*model.SessionInfo
+	if rf, ok := ret.Get(0).(func(*model.MachineExec) []*model.SessionInfo); ok {
+		r0 = rf(caller)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.SessionInfo)
+		}
+	}
+
+	return r0
+}
+
+// ListViewers provides a mock function with given fields: id
+func (_m *ExecManager) ListViewers(id int) ([]*ws_conn.Viewer, error) {
+	ret := _m.Called(id)
+
+	var r0 []*ws_conn.Viewer
+	if rf, ok := ret.Get(0).(func(int) []*ws_conn.Viewer); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ws_conn.Viewer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortForward provides a mock function with given fields: token, container, port, stream
+func (_m *ExecManager) PortForward(token string, container string, port int, stream io.ReadWriter) error {
+	ret := _m.Called(token, container, port, stream)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, int, io.ReadWriter) error); ok {
+		r0 = rf(token, container, port, stream)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Remove provides a mock function with given fields: execId
 func (_m *ExecManager) Remove(execId int) {
 	_m.Called(execId)
 }
 
+// RevokeViewer provides a mock function with given fields: id, viewerID
+func (_m *ExecManager) RevokeViewer(id int, viewerID int) error {
+	ret := _m.Called(id, viewerID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, int) error); ok {
+		r0 = rf(id, viewerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Resize provides a mock function with given fields: id, cols, rows
 func (_m *ExecManager) Resize(id int, cols uint, rows uint) error {
 	ret := _m.Called(id, cols, rows)
@@ -125,6 +267,57 @@ func (_m *ExecManager) Resize(id int, cols uint, rows uint) error {
 	return r0
 }
 
+// RunCommand provides a mock function with given fields: container, command, timeout
+func (_m *ExecManager) RunCommand(container string, command []string, timeout time.Duration) (string, error) {
+	ret := _m.Called(container, command, timeout)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, []string, time.Duration) string); ok {
+		r0 = rf(container, command, timeout)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []string, time.Duration) error); ok {
+		r1 = rf(container, command, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamCommand provides a mock function with given fields: token, container, command, options
+func (_m *ExecManager) StreamCommand(token string, container string, command []string, options process.StreamOptions) error {
+	ret := _m.Called(token, container, command, options)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, []string, process.StreamOptions) error); ok {
+		r0 = rf(token, container, command, options)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Stats provides a mock function with given fields:
+func (_m *ExecManager) Stats() *model.ExecStats {
+	ret := _m.Called()
+
+	var r0 *model.ExecStats
+	if rf, ok := ret.Get(0).(func() *model.ExecStats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ExecStats)
+		}
+	}
+
+	return r0
+}
+
 // Resolve provides a mock function with given fields: container, token
 func (_m *ExecManager) Resolve(container string, token string) (*model.ResolvedExec, error) {
 	ret := _m.Called(container, token)
@@ -147,3 +340,31 @@ func (_m *ExecManager) Resolve(container string, token string) (*model.ResolvedE
 
 	return r0, r1
 }
+
+// Terminate provides a mock function with given fields: timeout
+func (_m *ExecManager) Terminate(timeout time.Duration) (int, int, int) {
+	ret := _m.Called(timeout)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(time.Duration) int); ok {
+		r0 = rf(timeout)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(time.Duration) int); ok {
+		r1 = rf(timeout)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 int
+	if rf, ok := ret.Get(2).(func(time.Duration) int); ok {
+		r2 = rf(timeout)
+	} else {
+		r2 = ret.Get(2).(int)
+	}
+
+	return r0, r1, r2
+}
@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package recording
+
+// This package is aimed to keep an audit trail of the commands run in the workspace.
+// Exec input, output and terminal resizes are recorded with timestamps
+// to files of asciicast v2 format https://docs.asciinema.org/manual/asciicast/v2/
+// which might be replayed by asciinema player or analysed by any JSON lines tool.
+// Recording is limited by size in bytes, recordings older than retention period are removed.
+// The oldest recordings are removed as well when total size of the recordings exceeds the quota.
+// The header tells the user who created the exec, so the users get only their own recordings.
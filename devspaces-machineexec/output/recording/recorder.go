@@ -0,0 +1,319 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Extension of the recording files.
+	Extension = ".cast"
+
+	asciicastVersion = 2
+
+	outputEvent = "o"
+	inputEvent  = "i"
+	resizeEvent = "r"
+
+	// Period to look for the expired recordings.
+	cleanupPeriod = time.Hour
+)
+
+// Header is the first line of the asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	// User is ID of the authenticated user who created the exec, empty if authentication is disabled.
+	User string `json:"user,omitempty"`
+}
+
+// recording files which are being written, they are not removed to fit the quota
+var active = struct {
+	lock  *sync.Mutex
+	paths map[string]bool
+}{lock: &sync.Mutex{}, paths: make(map[string]bool)}
+
+// Recorder writes exec input and output to the asciicast v2 file.
+type Recorder struct {
+	lock *sync.Mutex
+	file *os.File
+	path string
+
+	start time.Time
+
+	size    int64
+	maxSize int64
+	// recording is stopped since the max size is reached or recorder is closed
+	stopped bool
+}
+
+// NewRecorder creates recording file for the exec in dir and writes the header to it.
+// Recording is stopped when the file reaches maxSize bytes, non-positive maxSize means no limit.
+func NewRecorder(dir string, execID int, header Header, maxSize int64) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	name := strconv.FormatInt(start.Unix(), 10) + "-" + strconv.Itoa(execID) + Extension
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Version = asciicastVersion
+	header.Timestamp = start.Unix()
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	recorder := &Recorder{
+		lock:    &sync.Mutex{},
+		file:    file,
+		path:    path,
+		start:   start,
+		maxSize: maxSize,
+	}
+	if err := recorder.writeLine(headerLine); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	active.lock.Lock()
+	active.paths[path] = true
+	active.lock.Unlock()
+	return recorder, nil
+}
+
+// Output records data written by the exec process.
+func (recorder *Recorder) Output(data []byte) {
+	recorder.record(outputEvent, string(data))
+}
+
+// Input records data sent to the exec process.
+func (recorder *Recorder) Input(data []byte) {
+	recorder.record(inputEvent, string(data))
+}
+
+// Resize records the terminal size change.
+func (recorder *Recorder) Resize(cols, rows int) {
+	recorder.record(resizeEvent, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close finishes recording.
+func (recorder *Recorder) Close() error {
+	defer recorder.lock.Unlock()
+	recorder.lock.Lock()
+
+	recorder.stopped = true
+
+	active.lock.Lock()
+	delete(active.paths, recorder.path)
+	active.lock.Unlock()
+	return recorder.file.Close()
+}
+
+func (recorder *Recorder) record(eventType string, data string) {
+	if len(data) == 0 {
+		return
+	}
+
+	defer recorder.lock.Unlock()
+	recorder.lock.Lock()
+
+	if recorder.stopped {
+		return
+	}
+
+	elapsed := time.Since(recorder.start).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, eventType, data})
+	if err != nil {
+		logrus.Errorf("Unable to encode recording event. Cause: %s", err.Error())
+		return
+	}
+
+	if err := recorder.writeLine(line); err != nil {
+		logrus.Errorf("Unable to write recording %s. Cause: %s", recorder.path, err.Error())
+	}
+}
+
+// Should be called with acquired lock.
+func (recorder *Recorder) writeLine(line []byte) error {
+	if recorder.maxSize > 0 && recorder.size+int64(len(line))+1 > recorder.maxSize {
+		recorder.stopped = true
+		logrus.Warnf("Recording %s reached max size of %d bytes, the rest of the session is not recorded", recorder.path, recorder.maxSize)
+		return nil
+	}
+
+	n, err := recorder.file.Write(append(line, '\n'))
+	recorder.size += int64(n)
+	return err
+}
+
+// Info describes recording file.
+type Info struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// User who created the recorded exec, see Header.
+	User string `json:"user,omitempty"`
+}
+
+// List returns recordings stored in dir sorted by name, the oldest first.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Info{}, nil
+		}
+		return nil, err
+	}
+
+	result := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), Extension) {
+			continue
+		}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		header, err := ReadHeader(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			logrus.Debugf("Unable to read header of recording %s. Cause: %s", entry.Name(), err.Error())
+		}
+		result = append(result, Info{Name: entry.Name(), Size: fileInfo.Size(), Modified: fileInfo.ModTime(), User: header.User})
+	}
+	return result, nil
+}
+
+// ReadHeader reads header of the recording file.
+func ReadHeader(path string) (Header, error) {
+	header := Header{}
+	file, err := os.Open(path)
+	if err != nil {
+		return header, err
+	}
+	defer file.Close()
+
+	err = json.NewDecoder(file).Decode(&header)
+	return header, err
+}
+
+// Path returns path of the recording with the name stored in dir.
+// Name must not point outside of dir.
+func Path(dir string, name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasSuffix(name, Extension) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid recording name '%s'", name)
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// RemoveExpired removes recordings stored in dir which were not modified longer than retention period.
+// Recordings which are being written are not removed.
+func RemoveExpired(dir string, retention time.Duration) {
+	recordings, err := List(dir)
+	if err != nil {
+		logrus.Errorf("Unable to list recordings to clean up. Cause: %s", err.Error())
+		return
+	}
+
+	active.lock.Lock()
+	defer active.lock.Unlock()
+
+	expiredBefore := time.Now().Add(-retention)
+	for _, recording := range recordings {
+		if recording.Modified.After(expiredBefore) {
+			continue
+		}
+		path := filepath.Join(dir, recording.Name)
+		if active.paths[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logrus.Errorf("Unable to remove expired recording %s. Cause: %s", recording.Name, err.Error())
+			continue
+		}
+		logrus.Debugf("Expired recording %s is removed", recording.Name)
+	}
+}
+
+// EnforceQuota removes the oldest recordings stored in dir until the recordings with reserved more bytes fit the quota.
+// Recordings which are being written are not removed.
+func EnforceQuota(dir string, quota int64, reserved int64) {
+	recordings, err := List(dir)
+	if err != nil {
+		logrus.Errorf("Unable to list recordings to enforce quota. Cause: %s", err.Error())
+		return
+	}
+
+	total := reserved
+	for _, recording := range recordings {
+		total += recording.Size
+	}
+
+	active.lock.Lock()
+	defer active.lock.Unlock()
+
+	for _, recording := range recordings {
+		if total <= quota {
+			return
+		}
+		path := filepath.Join(dir, recording.Name)
+		if active.paths[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logrus.Errorf("Unable to remove recording %s to fit quota. Cause: %s", recording.Name, err.Error())
+			continue
+		}
+		total -= recording.Size
+		logrus.Debugf("Recording %s is removed to fit quota of %d bytes", recording.Name, quota)
+	}
+}
+
+// StartCleanup periodically removes recordings older than retention period.
+func StartCleanup(dir string, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(cleanupPeriod)
+		defer ticker.Stop()
+
+		for {
+			RemoveExpired(dir, retention)
+			<-ticker.C
+		}
+	}()
+}
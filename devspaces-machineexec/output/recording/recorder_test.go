@@ -0,0 +1,168 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package recording
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readLines(t *testing.T, dir string) []string {
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, recordings[0].Name))
+	assert.Nil(t, err)
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestShouldRecordAsciicastHeaderAndEvents(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24, Command: "/bin/bash"}, 0)
+	assert.Nil(t, err)
+
+	recorder.Input([]byte("ls\n"))
+	recorder.Output([]byte("file.txt\n"))
+	recorder.Resize(100, 40)
+	assert.Nil(t, recorder.Close())
+
+	lines := readLines(t, dir)
+	assert.Len(t, lines, 4)
+
+	header := Header{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 80, header.Width)
+	assert.Equal(t, "/bin/bash", header.Command)
+
+	var event []interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "i", event[1])
+	assert.Equal(t, "ls\n", event[2])
+	assert.Nil(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, "o", event[1])
+	assert.Equal(t, "file.txt\n", event[2])
+	assert.Nil(t, json.Unmarshal([]byte(lines[3]), &event))
+	assert.Equal(t, "r", event[1])
+	assert.Equal(t, "100x40", event[2])
+}
+
+func TestShouldStopRecordingWhenMaxSizeIsReached(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24}, 100)
+	assert.Nil(t, err)
+
+	recorder.Output([]byte("a"))
+	recorder.Output([]byte(strings.Repeat("b", 100)))
+	recorder.Output([]byte("c"))
+	assert.Nil(t, recorder.Close())
+
+	lines := readLines(t, dir)
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"a"`)
+}
+
+func TestShouldNotRecordAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24}, 0)
+	assert.Nil(t, err)
+
+	assert.Nil(t, recorder.Close())
+	recorder.Output([]byte("output"))
+
+	assert.Len(t, readLines(t, dir), 1)
+}
+
+func TestShouldRejectRecordingNameOutsideOfDir(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Path(dir, "../secret"+Extension)
+	assert.NotNil(t, err)
+
+	_, err = Path(dir, "notes.txt")
+	assert.NotNil(t, err)
+}
+
+func TestShouldRemoveExpiredRecordings(t *testing.T) {
+	dir := t.TempDir()
+	expired := filepath.Join(dir, "1-1"+Extension)
+	actual := filepath.Join(dir, "2-2"+Extension)
+	assert.Nil(t, os.WriteFile(expired, []byte("{}\n"), 0600))
+	assert.Nil(t, os.WriteFile(actual, []byte("{}\n"), 0600))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(expired, old, old))
+
+	RemoveExpired(dir, time.Hour)
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+	assert.Equal(t, "2-2"+Extension, recordings[0].Name)
+}
+
+func TestShouldNotRemoveExpiredRecordingBeingWritten(t *testing.T) {
+	dir := t.TempDir()
+	// recording of idle exec is not modified longer than retention period, but it is not removed
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24}, 0)
+	assert.Nil(t, err)
+	defer recorder.Close()
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(recorder.path, old, old))
+
+	RemoveExpired(dir, time.Hour)
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+	assert.Equal(t, filepath.Base(recorder.path), recordings[0].Name)
+}
+
+func TestShouldListRecordingsWithUser(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24, User: "user-id"}, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Close())
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	assert.Len(t, recordings, 1)
+	assert.Equal(t, "user-id", recordings[0].User)
+}
+
+func TestShouldRemoveOldestRecordingsToFitQuota(t *testing.T) {
+	dir := t.TempDir()
+	// recording being written is the oldest one, but it is not removed
+	recorder, err := NewRecorder(dir, 1, Header{Width: 80, Height: 24}, 0)
+	assert.Nil(t, err)
+	defer recorder.Close()
+	for _, name := range []string{"9999999991-2", "9999999992-3", "9999999993-4"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name+Extension), []byte(strings.Repeat("a", 100)), 0600))
+	}
+
+	EnforceQuota(dir, 300, 100)
+
+	recordings, err := List(dir)
+	assert.Nil(t, err)
+	var names []string
+	for _, recording := range recordings {
+		names = append(names, recording.Name)
+	}
+	assert.Equal(t, []string{filepath.Base(recorder.path), "9999999993-4" + Extension}, names)
+}
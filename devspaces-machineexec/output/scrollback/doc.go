@@ -0,0 +1,22 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package scrollback
+
+// This component was implemented to have ability to restore exec output.
+// Client side should have ability to reconnect to the created exec
+// and get previous output starting from the known position.
+// This package is an implementation of ring buffer which stores exec output
+// limited by size in bytes. Every byte of the output has an absolute offset,
+// so the client can ask to replay the output from the last offset it has seen.
+// When output is discarded from the ring, terminal modes and text attributes
+// set by the discarded part are remembered and replayed before the retained output.
@@ -0,0 +1,158 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package scrollback
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultSize is the scrollback size in bytes used when size is not configured.
+const DefaultSize = 1024 * 1024
+
+// Buffer is a byte bounded ring buffer which accumulates exec output
+// to restore it for the reattached clients.
+type Buffer struct {
+	lock *sync.Mutex
+	data []byte
+
+	// absolute offset of the first retained byte
+	start int64
+	// absolute offset of the byte following the last written one
+	end int64
+
+	// state of the terminal at the start offset
+	state *terminalState
+}
+
+// New creates new scrollback buffer which retains up to size bytes of output.
+func New(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Buffer{
+		lock:  &sync.Mutex{},
+		data:  make([]byte, size),
+		state: newTerminalState(),
+	}
+}
+
+// Write appends data to the buffer. If publish is not nil, it is invoked with the data
+// while the buffer is locked, so clients attached with Replay never miss or duplicate output.
+func (buff *Buffer) Write(data []byte, publish func(data []byte)) {
+	defer buff.lock.Unlock()
+	buff.lock.Lock()
+
+	buff.write(data)
+
+	if publish != nil {
+		publish(data)
+	}
+}
+
+// Replay invokes subscribe with the output retained since offset and the offset
+// of the next byte to be written. No output is written while subscribe is running.
+// If a part of the requested output is already discarded, the retained output
+// is prepended with escape sequences which restore the terminal state.
+func (buff *Buffer) Replay(offset int64, subscribe func(content []byte, next int64) error) error {
+	defer buff.lock.Unlock()
+	buff.lock.Lock()
+
+	var content []byte
+	switch {
+	case offset >= buff.end:
+		content = []byte{}
+	case offset < buff.start:
+		content = append(buff.state.RestoreSequence(), buff.slice(buff.start, buff.end)...)
+	default:
+		content = buff.slice(offset, buff.end)
+	}
+
+	return subscribe(content, buff.end)
+}
+
+// Offsets returns absolute offsets of the first retained byte
+// and of the byte following the last written one.
+func (buff *Buffer) Offsets() (start int64, end int64) {
+	defer buff.lock.Unlock()
+	buff.lock.Lock()
+
+	return buff.start, buff.end
+}
+
+func (buff *Buffer) write(data []byte) {
+	size := int64(len(buff.data))
+	newEnd := buff.end + int64(len(data))
+	newStart := buff.start
+	if newEnd-newStart > size {
+		newStart = newEnd - size
+	}
+
+	// remember state changed by the output which is going to be discarded
+	if newStart > buff.start {
+		discardedEnd := newStart
+		if discardedEnd > buff.end {
+			discardedEnd = buff.end
+		}
+		buff.state.Feed(buff.slice(buff.start, discardedEnd))
+		if newStart > buff.end {
+			buff.state.Feed(data[:newStart-buff.end])
+		}
+	}
+
+	retained := data
+	if newStart > buff.end {
+		retained = data[newStart-buff.end:]
+	}
+	buff.copyIn(newEnd-int64(len(retained)), retained)
+
+	buff.start = newStart
+	buff.end = newEnd
+
+	if buff.start > 0 {
+		// do not start replay in the middle of an escape sequence or utf-8 character
+		for buff.start < buff.end && (!buff.state.IsGround() || !utf8.RuneStart(buff.at(buff.start))) {
+			buff.state.Feed([]byte{buff.at(buff.start)})
+			buff.start++
+		}
+	}
+}
+
+func (buff *Buffer) at(offset int64) byte {
+	return buff.data[offset%int64(len(buff.data))]
+}
+
+func (buff *Buffer) copyIn(offset int64, data []byte) {
+	size := int64(len(buff.data))
+	for len(data) > 0 {
+		pos := offset % size
+		n := copy(buff.data[pos:], data)
+		data = data[n:]
+		offset += int64(n)
+	}
+}
+
+func (buff *Buffer) slice(from, to int64) []byte {
+	result := make([]byte, 0, to-from)
+	size := int64(len(buff.data))
+	for from < to {
+		pos := from % size
+		chunkEnd := size
+		if pos+(to-from) < chunkEnd {
+			chunkEnd = pos + (to - from)
+		}
+		result = append(result, buff.data[pos:chunkEnd]...)
+		from += chunkEnd - pos
+	}
+	return result
+}
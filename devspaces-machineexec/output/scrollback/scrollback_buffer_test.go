@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package scrollback
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func replay(buff *Buffer, offset int64) (string, int64) {
+	var result string
+	var next int64
+	_ = buff.Replay(offset, func(content []byte, nextOffset int64) error {
+		result = string(content)
+		next = nextOffset
+		return nil
+	})
+	return result, next
+}
+
+func TestShouldReplayWholeOutputIfItFitsBuffer(t *testing.T) {
+	buff := New(64)
+	buff.Write([]byte("go on\n and more \n"), nil)
+	buff.Write([]byte(" foo \ntest"), nil)
+
+	content, next := replay(buff, 0)
+
+	assert.Equal(t, "go on\n and more \n foo \ntest", content)
+	assert.Equal(t, int64(27), next)
+}
+
+func TestShouldReplayOutputFromOffset(t *testing.T) {
+	buff := New(64)
+	buff.Write([]byte("first\nsecond\n"), nil)
+
+	content, next := replay(buff, 6)
+
+	assert.Equal(t, "second\n", content)
+	assert.Equal(t, int64(13), next)
+}
+
+func TestShouldReplayNothingIfOffsetIsUpToDate(t *testing.T) {
+	buff := New(64)
+	buff.Write([]byte("first\n"), nil)
+
+	content, next := replay(buff, 6)
+
+	assert.Equal(t, "", content)
+	assert.Equal(t, int64(6), next)
+}
+
+func TestShouldKeepOnlyLastBytes(t *testing.T) {
+	buff := New(8)
+	buff.Write([]byte("0123456789"), nil)
+	buff.Write([]byte("abc"), nil)
+
+	content, _ := replay(buff, 0)
+	start, end := buff.Offsets()
+
+	assert.Equal(t, "56789abc", content)
+	assert.Equal(t, int64(5), start)
+	assert.Equal(t, int64(13), end)
+}
+
+func TestShouldNotSplitUtf8CharacterOnDiscard(t *testing.T) {
+	buff := New(4)
+	buff.Write([]byte("aé€"), nil)
+
+	content, _ := replay(buff, 0)
+
+	assert.Equal(t, "€", content)
+}
+
+func TestShouldRestoreTerminalModesFromDiscardedOutput(t *testing.T) {
+	buff := New(16)
+	buff.Write([]byte("\x1b[?1049h\x1b[?25l\x1b[1;31m"), nil)
+	buff.Write([]byte(strings.Repeat("x", 16)), nil)
+
+	content, _ := replay(buff, 0)
+
+	assert.Equal(t, "\x1b[?25l\x1b[?1049h\x1b[1;31m"+strings.Repeat("x", 16), content)
+}
+
+func TestShouldNotStartReplayInTheMiddleOfEscapeSequence(t *testing.T) {
+	buff := New(8)
+	buff.Write([]byte("abc\x1b[?2004hxyz"), nil)
+
+	content, _ := replay(buff, 0)
+
+	assert.Equal(t, "\x1b[?2004hxyz", content)
+}
+
+func TestShouldForgetTextAttributesAfterReset(t *testing.T) {
+	buff := New(4)
+	buff.Write([]byte("\x1b[32mgreen\x1b[0mplain"), nil)
+
+	content, _ := replay(buff, 0)
+
+	assert.Equal(t, "lain", content)
+}
+
+func TestShouldPublishWrittenData(t *testing.T) {
+	buff := New(8)
+	var published []byte
+
+	buff.Write([]byte("data"), func(data []byte) {
+		published = data
+	})
+
+	assert.Equal(t, "data", string(published))
+}
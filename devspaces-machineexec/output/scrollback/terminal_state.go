@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package scrollback
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	esc = 0x1b
+
+	// max amount of text attribute sequences remembered since the last reset.
+	maxSgrSequences = 32
+)
+
+type parserState int
+
+const (
+	ground parserState = iota
+	escape
+	csi
+)
+
+// DEC private modes which affect how the replayed output is rendered, with their default values.
+var trackedModes = map[int]bool{
+	1:    false, // application cursor keys
+	25:   true,  // cursor is visible
+	47:   false, // alternate screen buffer
+	1000: false, // mouse tracking
+	1002: false, // mouse button event tracking
+	1003: false, // mouse any event tracking
+	1006: false, // SGR mouse mode
+	1047: false, // alternate screen buffer
+	1049: false, // alternate screen buffer with saved cursor
+	2004: false, // bracketed paste mode
+}
+
+// terminalState follows escape sequences of the terminal output
+// to remember terminal modes and text attributes.
+type terminalState struct {
+	parser parserState
+	params []byte
+
+	modes map[int]bool
+	sgr   []string
+}
+
+func newTerminalState() *terminalState {
+	return &terminalState{modes: make(map[int]bool)}
+}
+
+// Feed processes the next part of the terminal output.
+func (ts *terminalState) Feed(data []byte) {
+	for _, b := range data {
+		ts.feedByte(b)
+	}
+}
+
+// IsGround returns true if the parser is not inside of an escape sequence.
+func (ts *terminalState) IsGround() bool {
+	return ts.parser == ground
+}
+
+func (ts *terminalState) feedByte(b byte) {
+	switch ts.parser {
+	case ground:
+		if b == esc {
+			ts.parser = escape
+		}
+	case escape:
+		if b == '[' {
+			ts.parser = csi
+			ts.params = ts.params[:0]
+		} else {
+			// other escape sequences are not tracked
+			ts.parser = ground
+		}
+	case csi:
+		if b >= 0x40 && b <= 0x7e {
+			ts.applyCsi(string(ts.params), b)
+			ts.parser = ground
+		} else if len(ts.params) < 64 {
+			ts.params = append(ts.params, b)
+		}
+	}
+}
+
+func (ts *terminalState) applyCsi(params string, final byte) {
+	switch {
+	case strings.HasPrefix(params, "?") && (final == 'h' || final == 'l'):
+		for _, param := range strings.Split(params[1:], ";") {
+			mode, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if _, ok := trackedModes[mode]; ok {
+				ts.modes[mode] = final == 'h'
+			}
+		}
+	case final == 'm':
+		if params == "" || params == "0" {
+			ts.sgr = ts.sgr[:0]
+			return
+		}
+		if len(ts.sgr) == maxSgrSequences {
+			ts.sgr = ts.sgr[1:]
+		}
+		ts.sgr = append(ts.sgr, params)
+	}
+}
+
+// RestoreSequence returns escape sequences which bring a terminal
+// from the default state to the state followed so far.
+func (ts *terminalState) RestoreSequence() []byte {
+	var buffer bytes.Buffer
+
+	modes := make([]int, 0, len(ts.modes))
+	for mode := range ts.modes {
+		modes = append(modes, mode)
+	}
+	sort.Ints(modes)
+
+	for _, mode := range modes {
+		enabled := ts.modes[mode]
+		if enabled == trackedModes[mode] {
+			continue
+		}
+		final := 'l'
+		if enabled {
+			final = 'h'
+		}
+		buffer.WriteString("\x1b[?" + strconv.Itoa(mode) + string(final))
+	}
+
+	for _, params := range ts.sgr {
+		buffer.WriteString("\x1b[" + params + "m")
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,18 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package process
+
+// This package defines how exec manager talks to the exec process
+// regardless of the environment where the process is running.
+// Exec backends provide Executor to stream standard input/output of the process
+// and to deliver terminal size changes to it.
@@ -0,0 +1,52 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package process
+
+import (
+	"io"
+	"os"
+)
+
+// TerminalSize is the size of the exec terminal.
+type TerminalSize struct {
+	Width  uint16
+	Height uint16
+}
+
+// TerminalSizeQueue delivers terminal size changes to the exec process.
+type TerminalSizeQueue interface {
+	// Next returns the new terminal size, nil when there are no more changes.
+	Next() *TerminalSize
+}
+
+// StreamOptions defines streams of the exec process.
+type StreamOptions struct {
+	Stdin             io.Reader
+	Stdout            io.Writer
+	Stderr            io.Writer
+	Tty               bool
+	TerminalSizeQueue TerminalSizeQueue
+}
+
+// Executor runs the exec process.
+type Executor interface {
+	// Stream transports standard streams of the exec process until the process exits.
+	// If tty is set, stderr stream is not used and error output is sent with stdout.
+	Stream(options StreamOptions) error
+}
+
+// Signaler is implemented by the executors which are able to deliver signal to the exec process.
+type Signaler interface {
+	// Signal sends signal to the exec process and its children. Fails if process is not started.
+	Signal(sig os.Signal) error
+}
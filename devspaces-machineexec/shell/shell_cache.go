@@ -0,0 +1,82 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package shell
+
+import (
+	"sync"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/sirupsen/logrus"
+)
+
+// ShellCache keeps the shells detected in the containers until the container is restarted.
+type ShellCache struct {
+	lock   sync.RWMutex
+	shells map[model.ContainerInfo]string
+}
+
+// NewShellCache creates new empty shell cache.
+func NewShellCache() *ShellCache {
+	return &ShellCache{shells: make(map[model.ContainerInfo]string)}
+}
+
+// Get returns cached shell of the container.
+func (shellCache *ShellCache) Get(containerInfo *model.ContainerInfo) (shell string, ok bool) {
+	shellCache.lock.RLock()
+	defer shellCache.lock.RUnlock()
+
+	shell, ok = shellCache.shells[*containerInfo]
+	return shell, ok
+}
+
+// Put caches the shell detected in the container.
+func (shellCache *ShellCache) Put(containerInfo *model.ContainerInfo, shell string) {
+	shellCache.lock.Lock()
+	defer shellCache.lock.Unlock()
+
+	shellCache.shells[*containerInfo] = shell
+}
+
+// Invalidate removes cached shell of the container, for example when the container is restarted.
+func (shellCache *ShellCache) Invalidate(podName string, containerName string) {
+	shellCache.lock.Lock()
+	defer shellCache.lock.Unlock()
+
+	delete(shellCache.shells, model.ContainerInfo{PodName: podName, ContainerName: containerName})
+}
+
+// CachedShellDetector detects shell with the underlying detector only when it is not cached yet.
+type CachedShellDetector struct {
+	detector ContainerShellDetector
+	cache    *ShellCache
+}
+
+// NewCachedShellDetector creates shell detector which caches the detected shells.
+func NewCachedShellDetector(detector ContainerShellDetector, cache *ShellCache) *CachedShellDetector {
+	return &CachedShellDetector{detector: detector, cache: cache}
+}
+
+// Detect default shell inside container by container info. Failed detections are not cached.
+func (cachedDetector *CachedShellDetector) DetectShell(containerInfo *model.ContainerInfo) (shell string, err error) {
+	if shell, ok := cachedDetector.cache.Get(containerInfo); ok {
+		logrus.Debugf("Shell %s for %s/%s is found in cache", shell, containerInfo.PodName, containerInfo.ContainerName)
+		return shell, nil
+	}
+
+	shell, err = cachedDetector.detector.DetectShell(containerInfo)
+	if err != nil {
+		return "", err
+	}
+	cachedDetector.cache.Put(containerInfo, shell)
+	return shell, nil
+}
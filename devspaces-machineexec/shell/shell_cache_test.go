@@ -0,0 +1,56 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package shell
+
+import (
+	"testing"
+
+	"github.com/eclipse-che/che-machine-exec/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldDetectShellOnceUntilContainerIsRestarted(t *testing.T) {
+	shellDetector := &mocks.ContainerShellDetector{}
+	shellDetector.On("DetectShell", containerInfo).Return("/bin/bash", nil).Twice()
+
+	shellCache := NewShellCache()
+	cachedDetector := NewCachedShellDetector(shellDetector, shellCache)
+
+	for i := 0; i < 2; i++ {
+		shell, err := cachedDetector.DetectShell(containerInfo)
+		assert.Nil(t, err)
+		assert.Equal(t, "/bin/bash", shell)
+	}
+	shellDetector.AssertNumberOfCalls(t, "DetectShell", 1)
+
+	shellCache.Invalidate(containerInfo.PodName, containerInfo.ContainerName)
+	shell, err := cachedDetector.DetectShell(containerInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, "/bin/bash", shell)
+	shellDetector.AssertNumberOfCalls(t, "DetectShell", 2)
+}
+
+func TestShouldNotCacheFailedShellDetection(t *testing.T) {
+	shellDetector := &mocks.ContainerShellDetector{}
+	shellDetector.On("DetectShell", containerInfo).Return("", testErr).Once()
+	shellDetector.On("DetectShell", containerInfo).Return("/bin/bash", nil).Once()
+
+	cachedDetector := NewCachedShellDetector(shellDetector, NewShellCache())
+
+	_, err := cachedDetector.DetectShell(containerInfo)
+	assert.Equal(t, testErr, err)
+
+	shell, err := cachedDetector.DetectShell(containerInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, "/bin/bash", shell)
+}
@@ -19,7 +19,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/eclipse-che/che-machine-exec/cfg"
 	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,6 +32,10 @@ type InactivityIdleManager interface {
 
 	// Tick registers users activity and postpones workspace stopping by inactivity
 	Tick()
+
+	// Postpone postpones workspace stopping by inactivity once, until the next activity is registered.
+	// Returns the time when workspace is going to be stopped.
+	Postpone(duration time.Duration) (time.Time, error)
 }
 
 func NewInactivityIdleManager(idleTimeout, stopRetryPeriod time.Duration) (InactivityIdleManager, error) {
@@ -60,6 +66,8 @@ func NewInactivityIdleManager(idleTimeout, stopRetryPeriod time.Duration) (Inact
 		idleTimeout:     idleTimeout,
 		stopRetryPeriod: stopRetryPeriod,
 		activityC:       make(chan bool),
+		postponeC:       make(chan postponeRequest),
+		done:            make(chan struct{}),
 	}, nil
 }
 
@@ -69,6 +77,9 @@ type noOpInactivityIdleManager struct{}
 
 func (m noOpInactivityIdleManager) Tick()  {}
 func (m noOpInactivityIdleManager) Start() {}
+func (m noOpInactivityIdleManager) Postpone(time.Duration) (time.Time, error) {
+	return time.Time{}, errors.New("workspace is not stopped by inactivity")
+}
 
 type inactivityIdleManagerImpl struct {
 	namespace     string
@@ -78,6 +89,9 @@ type inactivityIdleManagerImpl struct {
 	stopRetryPeriod time.Duration
 
 	activityC chan bool
+	postponeC chan postponeRequest
+	// done is closed when workspace is not going to be stopped by inactivity anymore
+	done chan struct{}
 }
 
 func (m inactivityIdleManagerImpl) Tick() {
@@ -89,18 +103,26 @@ func (m inactivityIdleManagerImpl) Tick() {
 	}
 }
 
+func (m inactivityIdleManagerImpl) Postpone(duration time.Duration) (time.Time, error) {
+	return requestPostpone(m.postponeC, m.done, duration)
+}
+
 func (m inactivityIdleManagerImpl) Start() {
 	logrus.Infof("Activity tracker is run and workspace will be stopped in %s if there is no activity", m.idleTimeout)
-	timer := time.NewTimer(m.idleTimeout)
+	schedule := newStopSchedule(metrics.IdleTimeout, m.idleTimeout, cfg.StopWarnings, cfg.MaxStopPostpone)
 	var shutdownChan = make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, syscall.SIGTERM)
 
 	go func() {
+		defer close(m.done)
+		defer schedule.stop()
+
 		for {
 			select {
-			case <-timer.C:
+			case <-schedule.C():
 				if err := stopWorkspace(m.namespace, m.workspaceName, stoppedByInactivity); err != nil {
-					timer.Reset(m.stopRetryPeriod)
+					schedule.retry(m.stopRetryPeriod)
+					metrics.StopRetried(metrics.IdleTimeout)
 					logrus.Errorf("Failed to stop workspace. Will retry in %s. Cause: %s", m.stopRetryPeriod, err)
 				} else {
 					logrus.Info("Workspace is successfully stopped by inactivity. Bye")
@@ -108,10 +130,13 @@ func (m inactivityIdleManagerImpl) Start() {
 				}
 			case <-m.activityC:
 				logrus.Debug("Activity is reported. Resetting timer")
-				if !timer.Stop() {
-					<-timer.C
+				schedule.reset(m.idleTimeout)
+			case request := <-m.postponeC:
+				stopAt, err := schedule.postpone(request.duration)
+				if err == nil {
+					logrus.Infof("Workspace stop by inactivity is postponed until %s", stopAt.Format(time.RFC3339))
 				}
-				timer.Reset(m.idleTimeout)
+				request.result <- postponeResult{stopAt: stopAt, err: err}
 			case <-shutdownChan:
 				logrus.Info("Received SIGTERM: shutting down activity manager")
 				return
@@ -0,0 +1,100 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package timeout
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/sirupsen/logrus"
+)
+
+// preStopOutcomeAnnotation records outcome of the pre-stop phase on the stopped workspace.
+const preStopOutcomeAnnotation = "che.eclipse.org/pre-stop-outcome"
+
+var (
+	// pre-stop phase is run once even if workspace stop is retried or both timeouts are reached
+	preStopOnce    sync.Once
+	preStopOutcome string
+)
+
+type preStopResult struct {
+	// Amount of the live execs which were asked to exit.
+	Execs int `json:"execs"`
+	// Amount of the execs which were signalled, input of the others is closed.
+	SignalledExecs int `json:"signalledExecs"`
+	// Amount of the execs which exited within pre-stop timeout.
+	ExitedExecs int                    `json:"exitedExecs"`
+	Commands    []preStopCommandResult `json:"commands,omitempty"`
+}
+
+type preStopCommandResult struct {
+	Container string `json:"container,omitempty"`
+	Command   string `json:"command"`
+	Error     string `json:"error,omitempty"`
+}
+
+// preStop runs pre-stop phase if it is enabled and returns its outcome, empty if it is disabled.
+func preStop() string {
+	if cfg.PreStopTimeout <= 0 {
+		return ""
+	}
+	preStopOnce.Do(func() {
+		preStopOutcome = runPreStop(exec.GetExecManager(), cfg.PreStopTimeout, cfg.PreStopCommands)
+	})
+	return preStopOutcome
+}
+
+// runPreStop asks shells of the live execs to exit and runs pre-stop commands concurrently,
+// waits for them until timeout and returns the outcome encoded in JSON.
+func runPreStop(manager exec.ExecManager, timeout time.Duration, commands []cfg.PreStopCommand) string {
+	logrus.Infof("Running pre-stop phase. Workspace will be stopped in %s at most", timeout)
+	result := &preStopResult{Commands: make([]preStopCommandResult, len(commands))}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result.Execs, result.SignalledExecs, result.ExitedExecs = manager.Terminate(timeout)
+	}()
+
+	for i, command := range commands {
+		wg.Add(1)
+		go func(commandResult *preStopCommandResult, command cfg.PreStopCommand) {
+			defer wg.Done()
+			commandResult.Container = command.Container
+			commandResult.Command = strings.Join(command.Command, " ")
+
+			output, err := manager.RunCommand(command.Container, command.Command, timeout)
+			if err != nil {
+				commandResult.Error = err.Error()
+				logrus.Warnf("Pre-stop command '%s' failed. Cause: %s. Output: %s", commandResult.Command, err.Error(), output)
+				return
+			}
+			logrus.Debugf("Pre-stop command '%s' is completed. Output: %s", commandResult.Command, output)
+		}(&result.Commands[i], command)
+	}
+	wg.Wait()
+
+	logrus.Infof("Pre-stop phase is completed. %d of %d execs were signalled, %d exited", result.SignalledExecs, result.Execs, result.ExitedExecs)
+	outcome, err := json.Marshal(result)
+	if err != nil {
+		logrus.Errorf("Unable to encode pre-stop outcome. Cause: %s", err.Error())
+		return ""
+	}
+	return string(outcome)
+}
@@ -0,0 +1,49 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package timeout
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldRecordPreStopOutcome(t *testing.T) {
+	manager := &mocks.ExecManager{}
+	manager.On("Terminate", time.Second).Return(3, 1, 2)
+	manager.On("RunCommand", "tools", []string{"git", "stash"}, time.Second).Return("Saved working directory", nil)
+	manager.On("RunCommand", "", []string{"make", "flush"}, time.Second).Return("", errors.New("command exited with code 2"))
+
+	outcome := runPreStop(manager, time.Second, []cfg.PreStopCommand{
+		{Container: "tools", Command: []string{"git", "stash"}},
+		{Command: []string{"make", "flush"}},
+	})
+
+	result := &preStopResult{}
+	assert.Nil(t, json.Unmarshal([]byte(outcome), result))
+	assert.Equal(t, &preStopResult{
+		Execs:          3,
+		SignalledExecs: 1,
+		ExitedExecs:    2,
+		Commands: []preStopCommandResult{
+			{Container: "tools", Command: "git stash"},
+			{Command: "make flush", Error: "command exited with code 2"},
+		},
+	}, result)
+	manager.AssertExpectations(t)
+}
@@ -19,7 +19,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/eclipse-che/che-machine-exec/cfg"
 	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -27,6 +29,10 @@ type RunIdleManager interface {
 	// Start schedules workspace to stop after run timeout
 	// Should be called once
 	Start()
+
+	// Postpone postpones workspace stopping by run timeout once.
+	// Returns the time when workspace is going to be stopped.
+	Postpone(duration time.Duration) (time.Time, error)
 }
 
 func NewRunIdleManager(runTimeout, stopRetryPeriod time.Duration) (RunIdleManager, error) {
@@ -56,6 +62,8 @@ func NewRunIdleManager(runTimeout, stopRetryPeriod time.Duration) (RunIdleManage
 		workspaceName:   workspaceName,
 		runTimeout:      runTimeout,
 		stopRetryPeriod: stopRetryPeriod,
+		postponeC:       make(chan postponeRequest),
+		done:            make(chan struct{}),
 	}, nil
 }
 
@@ -64,6 +72,9 @@ func NewRunIdleManager(runTimeout, stopRetryPeriod time.Duration) (RunIdleManage
 type noOpRunIdleManager struct{}
 
 func (m noOpRunIdleManager) Start() {}
+func (m noOpRunIdleManager) Postpone(time.Duration) (time.Time, error) {
+	return time.Time{}, errors.New("workspace is not stopped by run timeout")
+}
 
 type runIdleManagerImpl struct {
 	namespace     string
@@ -71,25 +82,43 @@ type runIdleManagerImpl struct {
 
 	runTimeout      time.Duration
 	stopRetryPeriod time.Duration
+
+	postponeC chan postponeRequest
+	// done is closed when workspace is not going to be stopped by run timeout anymore
+	done chan struct{}
+}
+
+func (m runIdleManagerImpl) Postpone(duration time.Duration) (time.Time, error) {
+	return requestPostpone(m.postponeC, m.done, duration)
 }
 
 func (m runIdleManagerImpl) Start() {
 	logrus.Infof("Run idle manager is running. The workspace will be stopped in %s", m.runTimeout)
-	timer := time.NewTimer(m.runTimeout)
+	schedule := newStopSchedule(metrics.RunTimeout, m.runTimeout, cfg.StopWarnings, cfg.MaxStopPostpone)
 	var shutdownChan = make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, syscall.SIGTERM)
 
 	go func() {
+		defer close(m.done)
+		defer schedule.stop()
+
 		for {
 			select {
-			case <-timer.C:
+			case <-schedule.C():
 				if err := stopWorkspace(m.namespace, m.workspaceName, stoppedByRunTimeout); err != nil {
-					timer.Reset(m.stopRetryPeriod)
+					schedule.retry(m.stopRetryPeriod)
+					metrics.StopRetried(metrics.RunTimeout)
 					logrus.Errorf("Failed to stop workspace. Will retry in %s. Cause: %s", m.stopRetryPeriod, err)
 				} else {
 					logrus.Info("Workspace has reached its run timeout. Bye")
 					return
 				}
+			case request := <-m.postponeC:
+				stopAt, err := schedule.postpone(request.duration)
+				if err == nil {
+					logrus.Infof("Workspace stop by run timeout is postponed until %s", stopAt.Format(time.RFC3339))
+				}
+				request.result <- postponeResult{stopAt: stopAt, err: err}
 			case <-shutdownChan:
 				logrus.Info("Received SIGTERM: shutting down run timeout manager")
 				return
@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package timeout
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/events"
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+)
+
+// postponeRequest asks the manager goroutine to postpone workspace stop.
+type postponeRequest struct {
+	duration time.Duration
+	result   chan postponeResult
+}
+
+type postponeResult struct {
+	stopAt time.Time
+	err    error
+}
+
+// stopSchedule tracks when workspace is stopped by the timeout, warns clients before it
+// and lets the user postpone the stop once. It must be used from the single manager goroutine.
+type stopSchedule struct {
+	timeout string
+
+	timer    *time.Timer
+	deadline time.Time
+
+	warnings      []time.Duration
+	warningTimers []*time.Timer
+
+	maxPostpone time.Duration
+	postponed   bool
+}
+
+func newStopSchedule(timeout string, after time.Duration, warnings []time.Duration, maxPostpone time.Duration) *stopSchedule {
+	schedule := &stopSchedule{
+		timeout:     timeout,
+		timer:       time.NewTimer(after),
+		warnings:    warnings,
+		maxPostpone: maxPostpone,
+	}
+	schedule.setDeadline(time.Now().Add(after))
+	return schedule
+}
+
+// C is notified when workspace should be stopped.
+func (s *stopSchedule) C() <-chan time.Time {
+	return s.timer.C
+}
+
+// reset schedules workspace stop after the period, the user is able to postpone it again.
+func (s *stopSchedule) reset(after time.Duration) {
+	s.resetTimer(after)
+	s.postponed = false
+	s.setDeadline(time.Now().Add(after))
+}
+
+// retry schedules one more attempt to stop workspace after previous one failed.
+func (s *stopSchedule) retry(after time.Duration) {
+	s.resetTimer(after)
+}
+
+// postpone moves workspace stop forward by the duration, the maximum allowed duration is used if it is not positive.
+func (s *stopSchedule) postpone(duration time.Duration) (time.Time, error) {
+	if s.maxPostpone <= 0 {
+		return time.Time{}, errors.New("postponing workspace stop is disabled")
+	}
+	if s.postponed {
+		return time.Time{}, errors.New("workspace stop is already postponed")
+	}
+	if duration <= 0 {
+		duration = s.maxPostpone
+	}
+	if duration > s.maxPostpone {
+		return time.Time{}, fmt.Errorf("workspace stop can't be postponed for more than %s", s.maxPostpone)
+	}
+
+	// stop might be retried already, so it is postponed from now
+	deadline := s.deadline
+	if now := time.Now(); deadline.Before(now) {
+		deadline = now
+	}
+	deadline = deadline.Add(duration)
+
+	s.resetTimer(time.Until(deadline))
+	s.postponed = true
+	s.setDeadline(deadline)
+	return deadline, nil
+}
+
+// stop cancels workspace stop and the warnings.
+func (s *stopSchedule) stop() {
+	s.timer.Stop()
+	s.stopWarnings()
+}
+
+func (s *stopSchedule) resetTimer(after time.Duration) {
+	if !s.timer.Stop() {
+		// timer might be fired and drained already
+		select {
+		case <-s.timer.C:
+		default:
+		}
+	}
+	s.timer.Reset(after)
+}
+
+// setDeadline schedules warnings before the new deadline.
+func (s *stopSchedule) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+	metrics.SetTimeoutDeadline(s.timeout, deadline)
+
+	s.stopWarnings()
+	postponable := s.maxPostpone > 0 && !s.postponed
+	for _, warning := range s.warnings {
+		warnIn := time.Until(deadline) - warning
+		if warnIn <= 0 {
+			continue
+		}
+		warningEvent := &model.WorkspaceStopWarningEvent{
+			Timeout:     s.timeout,
+			StopsIn:     int(warning.Seconds()),
+			StopAt:      deadline,
+			Postponable: postponable,
+		}
+		s.warningTimers = append(s.warningTimers, time.AfterFunc(warnIn, func() {
+			events.EventBus.Pub(warningEvent)
+		}))
+	}
+}
+
+func (s *stopSchedule) stopWarnings() {
+	for _, timer := range s.warningTimers {
+		timer.Stop()
+	}
+	s.warningTimers = nil
+}
+
+// requestPostpone asks the manager goroutine to postpone workspace stop and waits for the result.
+func requestPostpone(postponeC chan postponeRequest, done chan struct{}, duration time.Duration) (time.Time, error) {
+	request := postponeRequest{duration: duration, result: make(chan postponeResult, 1)}
+	select {
+	case postponeC <- request:
+	case <-done:
+		return time.Time{}, errors.New("workspace is not going to be stopped by the timeout anymore")
+	}
+	result := <-request.result
+	return result.stopAt, result.err
+}
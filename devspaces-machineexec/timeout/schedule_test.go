@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package timeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/events"
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/metrics"
+	"github.com/eclipse/che-go-jsonrpc/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type warningConsumer struct {
+	warnings chan *model.WorkspaceStopWarningEvent
+}
+
+func (consumer *warningConsumer) Accept(e event.E) {
+	if warning, ok := e.(*model.WorkspaceStopWarningEvent); ok {
+		consumer.warnings <- warning
+	}
+}
+
+func TestShouldWarnBeforeWorkspaceStop(t *testing.T) {
+	consumer := &warningConsumer{warnings: make(chan *model.WorkspaceStopWarningEvent, 1)}
+	events.EventBus.Sub(consumer, model.OnWorkspaceStopWarning)
+	defer events.EventBus.Rm(consumer)
+
+	schedule := newStopSchedule(metrics.IdleTimeout, 2*time.Second, []time.Duration{time.Second, 5 * time.Second}, time.Minute)
+	defer schedule.stop()
+
+	select {
+	case warning := <-consumer.warnings:
+		assert.Equal(t, metrics.IdleTimeout, warning.Timeout)
+		assert.Equal(t, 1, warning.StopsIn)
+		assert.True(t, warning.Postponable)
+	case <-time.After(2 * time.Second):
+		t.Fatal("warning is not sent")
+	}
+}
+
+func TestShouldPostponeWorkspaceStopOnce(t *testing.T) {
+	schedule := newStopSchedule(metrics.RunTimeout, time.Minute, nil, 30*time.Minute)
+	defer schedule.stop()
+	deadline := schedule.deadline
+
+	stopAt, err := schedule.postpone(10 * time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, deadline.Add(10*time.Minute), stopAt)
+
+	_, err = schedule.postpone(10 * time.Minute)
+	assert.NotNil(t, err)
+}
+
+func TestShouldNotPostponeWorkspaceStopLongerThanMaximum(t *testing.T) {
+	schedule := newStopSchedule(metrics.RunTimeout, time.Minute, nil, 30*time.Minute)
+	defer schedule.stop()
+
+	_, err := schedule.postpone(time.Hour)
+	assert.NotNil(t, err)
+	assert.False(t, schedule.postponed)
+}
+
+func TestShouldAllowPostponeAgainAfterReset(t *testing.T) {
+	schedule := newStopSchedule(metrics.IdleTimeout, time.Minute, nil, 30*time.Minute)
+	defer schedule.stop()
+
+	_, err := schedule.postpone(0)
+	assert.Nil(t, err)
+
+	schedule.reset(time.Minute)
+	_, err = schedule.postpone(0)
+	assert.Nil(t, err)
+}
@@ -36,6 +36,13 @@ const (
 )
 
 func stopWorkspace(namespace string, workspaceName string, reason string) error {
+	annotations := map[string]interface{}{
+		"controller.devfile.io/stopped-by": reason,
+	}
+	if outcome := preStop(); outcome != "" {
+		annotations[preStopOutcomeAnnotation] = outcome
+	}
+
 	c, err := newWorkspaceClientInCluster()
 	if err != nil {
 		return err
@@ -44,9 +51,7 @@ func stopWorkspace(namespace string, workspaceName string, reason string) error
 	stopWorkspacePath := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"metadata": map[string]interface{}{
-				"annotations": map[string]interface{}{
-					"controller.devfile.io/stopped-by": reason,
-				},
+				"annotations": annotations,
 			},
 			"spec": map[string]interface{}{
 				"started": false,
@@ -0,0 +1,18 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package transfer
+
+// This package transfers files and tar archives to and from the workspace containers.
+// Archives are created and extracted by tar in the container, which is run through the exec subresource
+// like kubectl cp does, so tar has to be available in the container.
+// Progress of the transfers is published to the event bus, so it is reported to the connected clients.
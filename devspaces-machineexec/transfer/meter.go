@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package transfer
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/events"
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/cfg"
+)
+
+// minimal interval between the progress events of the transfer
+const progressInterval = 500 * time.Millisecond
+
+var lastTransferID int64
+
+// meter counts transferred bytes, enforces the transfer size limit and publishes progress events.
+// Transferred content could be read by the stream goroutine which outlives the command, so meter is synchronized.
+type meter struct {
+	lock       sync.Mutex
+	progress   model.FileTransferProgressEvent
+	exceeded   bool
+	reportedAt time.Time
+}
+
+func newMeter(direction, container, filePath string, total int64) *meter {
+	progress := model.FileTransferProgressEvent{
+		ID:        int(atomic.AddInt64(&lastTransferID, 1)),
+		Direction: direction,
+		Container: container,
+		Path:      filePath,
+	}
+	if total > 0 {
+		progress.Total = total
+	}
+	return &meter{progress: progress, reportedAt: time.Now()}
+}
+
+// checkLimit fails if size exceeds the transfer size limit.
+func (meter *meter) checkLimit(size int64) error {
+	meter.lock.Lock()
+	defer meter.lock.Unlock()
+
+	return meter.checkLimitLocked(size)
+}
+
+func (meter *meter) checkLimitLocked(size int64) error {
+	if cfg.MaxTransferSize > 0 && size > cfg.MaxTransferSize {
+		meter.exceeded = true
+		return limitError()
+	}
+	return nil
+}
+
+// add counts transferred bytes and publishes progress not more often than progressInterval.
+func (meter *meter) add(n int) error {
+	meter.lock.Lock()
+	defer meter.lock.Unlock()
+
+	meter.progress.Bytes += int64(n)
+	if err := meter.checkLimitLocked(meter.progress.Bytes); err != nil {
+		return err
+	}
+	if time.Since(meter.reportedAt) >= progressInterval {
+		meter.reportedAt = time.Now()
+		meter.publish()
+	}
+	return nil
+}
+
+// finish publishes the last progress event of the transfer and returns the transfer result.
+// Exceeded size limit is reported instead of the error it causes.
+func (meter *meter) finish(err error) error {
+	meter.lock.Lock()
+	defer meter.lock.Unlock()
+
+	if meter.exceeded {
+		err = limitError()
+	}
+	meter.progress.Done = true
+	if err != nil {
+		meter.progress.Error = err.Error()
+	}
+	meter.publish()
+	return err
+}
+
+func (meter *meter) publish() {
+	progress := meter.progress
+	events.EventBus.Pub(&progress)
+}
+
+type meteredReader struct {
+	io.Reader
+	meter *meter
+}
+
+func (reader *meteredReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if meterErr := reader.meter.add(n); meterErr != nil {
+		return n, meterErr
+	}
+	return n, err
+}
+
+type meteredWriter struct {
+	io.Writer
+	meter *meter
+}
+
+func (writer *meteredWriter) Write(p []byte) (int, error) {
+	if err := writer.meter.add(len(p)); err != nil {
+		return 0, err
+	}
+	return writer.Writer.Write(p)
+}
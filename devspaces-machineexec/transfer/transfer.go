@@ -0,0 +1,201 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/eclipse-che/che-machine-exec/api/model"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/process"
+)
+
+// maximum amount of tar error output included into the transfer error
+const maxErrorOutput = 1024
+
+// CommandStreamer runs command in the workspace container and streams its input and output.
+type CommandStreamer interface {
+	StreamCommand(token string, container string, command []string, options process.StreamOptions) error
+}
+
+// DownloadArchive writes tar archive of the file or directory at the path in the container to w.
+func DownloadArchive(streamer CommandStreamer, token, container, filePath string, w io.Writer) error {
+	if err := validatePath(filePath); err != nil {
+		return err
+	}
+
+	meter := newMeter(model.DownloadDirection, container, filePath, 0)
+	err := runTar(streamer, token, container, createArchiveCommand(filePath), nil, &meteredWriter{Writer: w, meter: meter})
+	return meter.finish(err)
+}
+
+// UploadArchive extracts tar archive read from r into the directory at the path in the container.
+// Size of the archive is used to report progress, it is -1 if unknown.
+func UploadArchive(streamer CommandStreamer, token, container, dirPath string, r io.Reader, size int64) error {
+	if err := validatePath(dirPath); err != nil {
+		return err
+	}
+
+	meter := newMeter(model.UploadDirection, container, dirPath, size)
+	if err := meter.checkLimit(size); err != nil {
+		return err
+	}
+	err := runTar(streamer, token, container, extractArchiveCommand(dirPath), &meteredReader{Reader: r, meter: meter}, nil)
+	return meter.finish(err)
+}
+
+// DownloadFile writes content of the regular file at the path in the container to w.
+func DownloadFile(streamer CommandStreamer, token, container, filePath string, w io.Writer) error {
+	if err := validatePath(filePath); err != nil {
+		return err
+	}
+
+	meter := newMeter(model.DownloadDirection, container, filePath, 0)
+	archiveReader, archiveWriter := io.Pipe()
+	tarResult := make(chan error, 1)
+	go func() {
+		err := runTar(streamer, token, container, createArchiveCommand(filePath), nil, archiveWriter)
+		archiveWriter.CloseWithError(err)
+		tarResult <- err
+	}()
+
+	err := copyFirstFile(tar.NewReader(archiveReader), &meteredWriter{Writer: w, meter: meter})
+	if err != nil {
+		// fail tar output, so tar doesn't archive the rest of the directory
+		archiveReader.CloseWithError(err)
+	} else {
+		// drain the end of the archive, so tar is able to complete
+		_, _ = io.Copy(ioutil.Discard, archiveReader)
+	}
+	if tarErr := <-tarResult; err == nil {
+		err = tarErr
+	}
+	return meter.finish(err)
+}
+
+// UploadFile writes content read from r into the file at the path in the container.
+// Size of the content has to be known to put it into the archive which is extracted in the container.
+func UploadFile(streamer CommandStreamer, token, container, filePath string, r io.Reader, size int64) error {
+	if err := validatePath(filePath); err != nil {
+		return err
+	}
+	if size < 0 {
+		return rest.NewError(http.StatusLengthRequired, "Size of the uploaded file is required")
+	}
+
+	meter := newMeter(model.UploadDirection, container, filePath, size)
+	if err := meter.checkLimit(size); err != nil {
+		return err
+	}
+
+	archiveReader, archiveWriter := io.Pipe()
+	archiveResult := make(chan error, 1)
+	go func() {
+		err := writeSingleFileArchive(archiveWriter, path.Base(filePath), &meteredReader{Reader: r, meter: meter}, size)
+		archiveWriter.CloseWithError(err)
+		archiveResult <- err
+	}()
+
+	err := runTar(streamer, token, container, extractArchiveCommand(path.Dir(filePath)), archiveReader, nil)
+	// unblock archive writer if tar exits before reading the whole archive
+	archiveReader.Close()
+	if archiveErr := <-archiveResult; err == nil && archiveErr != nil && archiveErr != io.ErrClosedPipe {
+		err = archiveErr
+	}
+	return meter.finish(err)
+}
+
+// runTar runs tar command in the container. Tar error output is included into the returned error.
+func runTar(streamer CommandStreamer, token, container string, command []string, stdin io.Reader, stdout io.Writer) error {
+	stderr := &bytes.Buffer{}
+	options := process.StreamOptions{Stdout: stdout, Stderr: stderr}
+	if stdin != nil {
+		options.Stdin = stdin
+	}
+	if stdout == nil {
+		options.Stdout = ioutil.Discard
+	}
+
+	err := streamer.StreamCommand(token, container, command, options)
+	if err != nil {
+		output := strings.TrimSpace(stderr.String())
+		if len(output) > maxErrorOutput {
+			output = output[:maxErrorOutput]
+		}
+		if output != "" {
+			return fmt.Errorf("%s: %s", err.Error(), output)
+		}
+		return err
+	}
+	return nil
+}
+
+// createArchiveCommand archives the last path element only, so archive entries are relative to its parent.
+// Path element is prefixed with ./ to be never treated as tar option.
+func createArchiveCommand(filePath string) []string {
+	return []string{"tar", "cf", "-", "-C", path.Dir(filePath), "./" + path.Base(filePath)}
+}
+
+func extractArchiveCommand(dirPath string) []string {
+	return []string{"tar", "xf", "-", "-C", dirPath}
+}
+
+// copyFirstFile copies content of the first archive entry, which has to be a regular file.
+func copyFirstFile(archive *tar.Reader, w io.Writer) error {
+	header, err := archive.Next()
+	if err != nil {
+		return err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return rest.NewError(http.StatusBadRequest, "'"+header.Name+"' is not a regular file")
+	}
+	_, err = io.Copy(w, archive)
+	return err
+}
+
+func writeSingleFileArchive(w io.Writer, name string, r io.Reader, size int64) error {
+	archive := tar.NewWriter(w)
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(archive, r, size); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func validatePath(filePath string) error {
+	if filePath == "" {
+		return rest.NewError(http.StatusBadRequest, "Path is required")
+	}
+	return nil
+}
+
+func limitError() error {
+	return rest.NewError(http.StatusRequestEntityTooLarge, "Transfer size limit is exceeded")
+}
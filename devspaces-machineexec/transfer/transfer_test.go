@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-che/che-machine-exec/cfg"
+	"github.com/eclipse-che/che-machine-exec/common/rest"
+	"github.com/eclipse-che/che-machine-exec/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldUploadAndDownloadFile(t *testing.T) {
+	backend := exec.NewLocalBackend()
+	filePath := filepath.Join(t.TempDir(), "-file with spaces.txt")
+	content := "hello\n"
+
+	err := UploadFile(backend, "", "", filePath, strings.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+	written, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(written))
+
+	downloaded := &bytes.Buffer{}
+	err = DownloadFile(backend, "", "", filePath, downloaded)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded.String())
+}
+
+func TestShouldUploadAndDownloadArchive(t *testing.T) {
+	backend := exec.NewLocalBackend()
+	source := filepath.Join(t.TempDir(), "project")
+	assert.Nil(t, os.MkdirAll(filepath.Join(source, "src"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "src", "main.go"), []byte("package main\n"), 0644))
+
+	archive := &bytes.Buffer{}
+	err := DownloadArchive(backend, "", "", source, archive)
+	assert.Nil(t, err)
+
+	target := t.TempDir()
+	err = UploadArchive(backend, "", "", target, bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	assert.Nil(t, err)
+	extracted, err := ioutil.ReadFile(filepath.Join(target, "project", "src", "main.go"))
+	assert.Nil(t, err)
+	assert.Equal(t, "package main\n", string(extracted))
+}
+
+func TestShouldFailToDownloadDirectoryAsFile(t *testing.T) {
+	err := DownloadFile(exec.NewLocalBackend(), "", "", t.TempDir(), ioutil.Discard)
+
+	httpErr, ok := err.(rest.HttpError)
+	assert.True(t, ok)
+	assert.Contains(t, httpErr.Error(), "is not a regular file")
+}
+
+func TestShouldFailToDownloadMissingFile(t *testing.T) {
+	err := DownloadFile(exec.NewLocalBackend(), "", "", filepath.Join(t.TempDir(), "missing"), ioutil.Discard)
+
+	assert.NotNil(t, err)
+}
+
+func TestShouldRejectTransferExceedingSizeLimit(t *testing.T) {
+	defer func(limit int64) { cfg.MaxTransferSize = limit }(cfg.MaxTransferSize)
+	cfg.MaxTransferSize = 1024
+	backend := exec.NewLocalBackend()
+	dir := t.TempDir()
+
+	err := UploadFile(backend, "", "", filepath.Join(dir, "big"), strings.NewReader(strings.Repeat("a", 2048)), 2048)
+	assert.Equal(t, rest.NewError(http.StatusRequestEntityTooLarge, "Transfer size limit is exceeded"), err)
+
+	// size of the archive is unknown, so it is limited while it is read
+	archive := &bytes.Buffer{}
+	archiveWriter := tar.NewWriter(archive)
+	assert.Nil(t, archiveWriter.WriteHeader(&tar.Header{Name: "big", Mode: 0644, Size: 2048}))
+	_, _ = archiveWriter.Write(bytes.Repeat([]byte("a"), 2048))
+	assert.Nil(t, archiveWriter.Close())
+	err = UploadArchive(backend, "", "", dir, archive, -1)
+	assert.Equal(t, rest.NewError(http.StatusRequestEntityTooLarge, "Transfer size limit is exceeded"), err)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "big"), bytes.Repeat([]byte("a"), 2048), 0644))
+	err = DownloadFile(backend, "", "", filepath.Join(dir, "big"), ioutil.Discard)
+	assert.Equal(t, rest.NewError(http.StatusRequestEntityTooLarge, "Transfer size limit is exceeded"), err)
+}
@@ -0,0 +1,43 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package ws_conn
+
+import (
+	"errors"
+)
+
+// AccessMode defines what the client connection is allowed to do with the exec.
+type AccessMode string
+
+const (
+	// FullAccess allows to send input to the exec and to control it.
+	FullAccess AccessMode = "full"
+	// ReadOnlyAccess allows only to attach to the exec and to view its output.
+	ReadOnlyAccess AccessMode = "read-only"
+)
+
+// ErrReadOnlyAccess is returned when client with read-only access tries to send input or to control exec.
+var ErrReadOnlyAccess = errors.New("access is read-only, exec input and control are not allowed")
+
+// IsValid returns true if the access mode is known.
+func (mode AccessMode) IsValid() bool {
+	return mode == FullAccess || mode == ReadOnlyAccess
+}
+
+// AllowControl returns ErrReadOnlyAccess unless the access mode is full.
+func AllowControl(mode AccessMode) error {
+	if mode != FullAccess {
+		return ErrReadOnlyAccess
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package ws_conn
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+)
+
+// OnTerminalResized is the event viewers of the dedicated websocket connection receive when terminal is resized.
+const OnTerminalResized = "onTerminalResized"
+
+// ResizeEvent is sent to the viewers of the dedicated websocket connection as binary message,
+// so that it is not mixed up with the exec output sent as text messages.
+type ResizeEvent struct {
+	Event string `json:"event"`
+	Cols  uint   `json:"cols"`
+	Rows  uint   `json:"rows"`
+}
+
+// Connection is a client connection which receives exec output.
+type Connection interface {
+	// Write exec output to the client.
+	Write(data []byte) error
+	// Write exec error output to the client. It is used only for execs without tty.
+	WriteStderr(data []byte) error
+	// Close connection.
+	Close() error
+}
+
+// Connection which sends exec output as text messages of the dedicated websocket connection.
+type websocketConnection struct {
+	wsConn *websocket.Conn
+}
+
+func (conn *websocketConnection) Write(data []byte) error {
+	return conn.wsConn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Dedicated websocket connection does not distinguish output streams, so stderr is sent as regular output.
+func (conn *websocketConnection) WriteStderr(data []byte) error {
+	return conn.Write(data)
+}
+
+func (conn *websocketConnection) WriteResize(cols uint, rows uint) error {
+	payload, err := json.Marshal(&ResizeEvent{Event: OnTerminalResized, Cols: cols, Rows: rows})
+	if err != nil {
+		return err
+	}
+	return conn.wsConn.WriteMessage(websocket.BinaryMessage, payload)
+}
+
+func (conn *websocketConnection) Close() error {
+	return conn.wsConn.Close()
+}
@@ -13,6 +13,8 @@
 package ws_conn
 
 import (
+	"net"
+
 	ws "github.com/gorilla/websocket"
 )
 
@@ -29,3 +31,11 @@ func IsClosedByClientError(err error) bool {
 	}
 	return false
 }
+
+// IsPongTimeoutError returns true if connection is closed since client did not respond to ping in time.
+func IsPongTimeoutError(err error) bool {
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout()
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+//
+// Copyright (c) 2019-2021 Red Hat, Inc.
+// This program and the accompanying materials are made
+// available under the terms of the Eclipse Public License 2.0
+// which is available at https://www.eclipse.org/legal/epl-2.0/
+//
+// SPDX-License-Identifier: EPL-2.0
+//
+// Contributors:
+//   Red Hat, Inc. - initial API and implementation
+//
+
+package ws_conn
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrViewerRevoked is the cause viewer connection is detached with when its access is revoked.
+var ErrViewerRevoked = errors.New("viewer access is revoked")
+
+// Client is the user the connection is attached on behalf of.
+type Client struct {
+	// User name, it is empty if authentication is disabled.
+	User string
+	// UserID is empty if authentication is disabled.
+	UserID string
+	Mode   AccessMode
+}
+
+// Viewer is the connection attached to the exec in read-only mode.
+// Viewer receives exec output and terminal size, but its input is dropped.
+type Viewer struct {
+	ID    int       `json:"id"`
+	User  string    `json:"user,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// ResizeListener is the connection which is notified when exec terminal is resized.
+// Viewer connections implement it to render output with the size of the terminal.
+type ResizeListener interface {
+	WriteResize(cols uint, rows uint) error
+}
+
+// Revocable is the connection which is detached by its owner when viewer access is revoked,
+// instead of being closed.
+type Revocable interface {
+	Revoke()
+}
@@ -13,97 +13,282 @@
 package ws_conn
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/eclipse-che/che-machine-exec/metrics"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
+	PongWait = 60 * time.Second
 
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = (pongWait * 9) / 10
+	// Send pings to peer with this period. Must be less than PongWait.
+	PingPeriod = (PongWait * 9) / 10
 )
 
 // Websocket connection handler is connection storage.
 // For che-machine-exec it used to manage connections with exec input/output.
 type ConnectionHandler interface {
-	// Add new websocket connection.
-	ReadConnection(wsConn *websocket.Conn, inputChan chan []byte)
+	// Add new websocket connection. Input is read from the connection only if access mode of the client is full,
+	// otherwise connection is attached as viewer.
+	ReadConnection(wsConn *websocket.Conn, inputChan chan []byte, client Client)
+	// Add new connection. Input from the connection should be delivered by the caller
+	// if access mode of the client is full, otherwise connection is attached as viewer.
+	AddConnection(conn Connection, client Client)
+	// Remove connection from handler without closing it.
+	RemoveConnection(conn Connection)
 	// Send data to the client websocket connections.
 	WriteDataToWsConnections(data []byte)
+	// Send error output to the client websocket connections.
+	WriteStderrToWsConnections(data []byte)
+	// Send terminal size to the viewer connections. Size is sent to the viewers attached later as well.
+	WriteResizeToViewers(cols uint, rows uint)
+
+	// Return viewer connections.
+	ListViewers() []*Viewer
+	// Detach viewer connection by id. User of the viewer can't attach as viewer anymore.
+	RevokeViewer(id int) error
+	// Return ErrViewerRevoked if the client is attached as viewer, but viewer access of the user is revoked.
+	CheckRevoked(client Client) error
 
 	// Close all connection.
 	CloseConnections()
+
+	// Return amount of the active connections.
+	ConnectionsCount() int
+	// Set callback which is invoked when the last active connection is lost.
+	OnConnectionsLost(callback func())
 }
 
 // Connection handler implementation.
 type ConnectionHandlerImpl struct {
 	wsConnsLock *sync.Mutex
-	wsConns     []*websocket.Conn
+	wsConns     []Connection
+
+	viewers      map[Connection]*Viewer
+	lastViewerID int
+	// users whose viewer connections are revoked
+	revokedUsers map[string]bool
+	// last terminal size, zero until terminal size is known
+	cols, rows uint
+
+	onConnectionsLost func()
 }
 
 // Create new implementation connection handler.
 func NewConnHandler() *ConnectionHandlerImpl {
 	return &ConnectionHandlerImpl{
-		wsConnsLock: &sync.Mutex{},
-		wsConns:     make([]*websocket.Conn, 0),
+		wsConnsLock:  &sync.Mutex{},
+		wsConns:      make([]Connection, 0),
+		viewers:      make(map[Connection]*Viewer),
+		revokedUsers: make(map[string]bool),
 	}
 }
 
 // Add new connection to handler.
-func (handler *ConnectionHandlerImpl) ReadConnection(wsConn *websocket.Conn, inputChan chan []byte) {
+// Input of the connection with read-only access is dropped.
+func (handler *ConnectionHandlerImpl) ReadConnection(wsConn *websocket.Conn, inputChan chan []byte, client Client) {
 	defer handler.wsConnsLock.Unlock()
 	handler.wsConnsLock.Lock()
 
-	handler.wsConns = append(handler.wsConns, wsConn)
+	conn := &websocketConnection{wsConn}
+	handler.addConnection(conn, client)
+	metrics.WebsocketConnected(metrics.AttachEndpoint)
 
-	go handler.readDataFromConnection(inputChan, wsConn)
+	go handler.readDataFromConnection(inputChan, conn, client.Mode)
 	go handler.sendPingMessage(wsConn)
 }
 
+// Add new connection to handler. Input from the connection should be delivered by the caller.
+func (handler *ConnectionHandlerImpl) AddConnection(conn Connection, client Client) {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	handler.addConnection(conn, client)
+}
+
+// Should be called with acquired lock.
+func (handler *ConnectionHandlerImpl) addConnection(conn Connection, client Client) {
+	handler.wsConns = append(handler.wsConns, conn)
+	if client.Mode != FullAccess {
+		handler.lastViewerID++
+		handler.viewers[conn] = &Viewer{ID: handler.lastViewerID, User: client.User, Since: time.Now()}
+		handler.writeResize(conn)
+	}
+}
+
+// Write the last terminal size to the viewer connection. Should be called with acquired lock.
+func (handler *ConnectionHandlerImpl) writeResize(conn Connection) {
+	listener, ok := conn.(ResizeListener)
+	if !ok || handler.cols == 0 || handler.rows == 0 {
+		return
+	}
+	if err := listener.WriteResize(handler.cols, handler.rows); err != nil {
+		logrus.Debugf("failed to write terminal size to the viewer. Cause: %v", err)
+	}
+}
+
+// Return viewer connections ordered by id.
+func (handler *ConnectionHandlerImpl) ListViewers() []*Viewer {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	viewers := make([]*Viewer, 0, len(handler.viewers))
+	for _, conn := range handler.wsConns {
+		if viewer, ok := handler.viewers[conn]; ok {
+			copied := *viewer
+			viewers = append(viewers, &copied)
+		}
+	}
+	return viewers
+}
+
+// Detach viewer connection by id. Connection is closed unless it is Revocable.
+func (handler *ConnectionHandlerImpl) RevokeViewer(id int) error {
+	handler.wsConnsLock.Lock()
+	var revoked Connection
+	for conn, viewer := range handler.viewers {
+		if viewer.ID == id {
+			revoked = conn
+			// viewers are not distinguished if authentication is disabled
+			if viewer.User != "" {
+				handler.revokedUsers[viewer.User] = true
+			}
+		}
+	}
+	handler.wsConnsLock.Unlock()
+
+	if revoked == nil {
+		return fmt.Errorf("viewer %d is not found", id)
+	}
+	handler.removeConnection(revoked)
+
+	if revocable, ok := revoked.(Revocable); ok {
+		revocable.Revoke()
+		return nil
+	}
+	return revoked.Close()
+}
+
+// Return ErrViewerRevoked if the client is attached as viewer, but viewer access of the user is revoked.
+func (handler *ConnectionHandlerImpl) CheckRevoked(client Client) error {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	if client.Mode != FullAccess && handler.revokedUsers[client.User] {
+		return ErrViewerRevoked
+	}
+	return nil
+}
+
+// Write terminal size to the viewer connections which listen to it.
+// Size is remembered to be sent to the viewers attached later.
+func (handler *ConnectionHandlerImpl) WriteResizeToViewers(cols uint, rows uint) {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	handler.cols, handler.rows = cols, rows
+	for conn := range handler.viewers {
+		handler.writeResize(conn)
+	}
+}
+
+// Remove connection from handler without closing it.
+func (handler *ConnectionHandlerImpl) RemoveConnection(conn Connection) {
+	handler.removeConnection(conn)
+}
+
 // Remove connection form handler.
-func (handler *ConnectionHandlerImpl) removeConnection(wsConn *websocket.Conn) {
+func (handler *ConnectionHandlerImpl) removeConnection(wsConn Connection) {
 	defer handler.wsConnsLock.Unlock()
 	handler.wsConnsLock.Lock()
 
 	for index, wsConnElem := range handler.wsConns {
 		if wsConnElem == wsConn {
 			handler.wsConns = append(handler.wsConns[:index], handler.wsConns[index+1:]...)
+			delete(handler.viewers, wsConn)
+			if len(handler.wsConns) == 0 {
+				handler.notifyConnectionsLost()
+			}
+			return
 		}
 	}
 }
 
+// Return amount of the active connections.
+func (handler *ConnectionHandlerImpl) ConnectionsCount() int {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	return len(handler.wsConns)
+}
+
+// Set callback which is invoked when the last active connection is lost.
+// Callback is not invoked when connections are closed with CloseConnections.
+func (handler *ConnectionHandlerImpl) OnConnectionsLost(callback func()) {
+	defer handler.wsConnsLock.Unlock()
+	handler.wsConnsLock.Lock()
+
+	handler.onConnectionsLost = callback
+}
+
+// Should be called with acquired lock.
+func (handler *ConnectionHandlerImpl) notifyConnectionsLost() {
+	if handler.onConnectionsLost != nil {
+		go handler.onConnectionsLost()
+	}
+}
+
 // Write data to the all connections managed by handler.
 func (handler *ConnectionHandlerImpl) WriteDataToWsConnections(data []byte) {
+	handler.writeToConnections(data, Connection.Write)
+}
+
+// Write error output to the all connections managed by handler.
+func (handler *ConnectionHandlerImpl) WriteStderrToWsConnections(data []byte) {
+	handler.writeToConnections(data, Connection.WriteStderr)
+}
+
+func (handler *ConnectionHandlerImpl) writeToConnections(data []byte, write func(conn Connection, data []byte) error) {
 	defer handler.wsConnsLock.Unlock()
 	handler.wsConnsLock.Lock()
 
-	workingConns := make([]*websocket.Conn, 0)
+	workingConns := make([]Connection, 0)
 
 	for _, wsConn := range handler.wsConns {
-		if err := wsConn.WriteMessage(websocket.TextMessage, data); err != nil {
+		if err := write(wsConn, data); err != nil {
 			if !IsClosedByClientError(err) {
 				logrus.Errorf("failed to write to ws-conn message. Cause: %v", err)
 			}
+			delete(handler.viewers, wsConn)
 		} else {
 			workingConns = append(workingConns, wsConn)
 		}
 	}
+	lost := len(handler.wsConns) > 0 && len(workingConns) == 0
 	handler.wsConns = workingConns
+	if lost {
+		handler.notifyConnectionsLost()
+	}
 }
 
 // Read data from connection.
-func (handler *ConnectionHandlerImpl) readDataFromConnection(inputChan chan []byte, wsConn *websocket.Conn) {
-	defer handler.removeConnection(wsConn)
+func (handler *ConnectionHandlerImpl) readDataFromConnection(inputChan chan []byte, conn *websocketConnection, mode AccessMode) {
+	defer metrics.WebsocketDisconnected(metrics.AttachEndpoint)
+	defer handler.removeConnection(conn)
 
+	wsConn := conn.wsConn
 	for {
-		wsConn.SetPongHandler(func(string) error { return wsConn.SetReadDeadline(time.Now().Add(pongWait)) })
+		wsConn.SetPongHandler(func(string) error { return wsConn.SetReadDeadline(time.Now().Add(PongWait)) })
 		msgType, wsBytes, err := wsConn.ReadMessage()
 		if err != nil {
+			if IsPongTimeoutError(err) {
+				metrics.KeepAliveFailed(metrics.PongFailure)
+			}
 			if !IsClosedByClientError(err) {
 				logrus.Errorf("failed to read ws-conn message. Cause: %v", err)
 			}
@@ -114,17 +299,24 @@ func (handler *ConnectionHandlerImpl) readDataFromConnection(inputChan chan []by
 			continue
 		}
 
+		// connection is still read to handle pong and close messages
+		if err := AllowControl(mode); err != nil {
+			logrus.Debugf("Dropping input of the connection. Cause: %s", err.Error())
+			continue
+		}
+
 		inputChan <- wsBytes
 	}
 }
 
 // Send ping message to the connection client.
 func (*ConnectionHandlerImpl) sendPingMessage(wsConn *websocket.Conn) {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(PingPeriod)
 	defer ticker.Stop()
 
 	for range ticker.C {
 		if err := wsConn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			metrics.KeepAliveFailed(metrics.PingFailure)
 			// stop sending ping messages if one failed
 			break
 		}
@@ -140,5 +332,6 @@ func (handler *ConnectionHandlerImpl) CloseConnections() {
 			logrus.Errorf("failed to close connection. Cause: %v", err)
 		}
 	}
-	handler.wsConns = make([]*websocket.Conn, 0)
+	handler.wsConns = make([]Connection, 0)
+	handler.viewers = make(map[Connection]*Viewer)
 }